@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/backtest"
+	"github.com/qqqq/eth-trading-system/internal/models"
+	"github.com/qqqq/eth-trading-system/internal/storage"
+)
+
+// backtestFlags 注册回测参数相关的命令行选项
+func backtestFlags() *backtest.Config {
+	cfg := backtest.DefaultConfig()
+	flag.Float64Var(&cfg.InitialCapital, "capital", cfg.InitialCapital, "初始资金")
+	flag.Float64Var(&cfg.FeeRate, "fee", cfg.FeeRate, "手续费率")
+	flag.Float64Var(&cfg.Slippage, "slippage", cfg.Slippage, "滑点比例")
+	flag.Float64Var(&cfg.PositionFraction, "position-fraction", cfg.PositionFraction, "每次开仓使用的权益比例")
+	flag.BoolVar(&cfg.UseSignalSize, "signal-size", cfg.UseSignalSize, "使用策略建议的交易数量")
+	flag.BoolVar(&cfg.AllowShort, "allow-short", cfg.AllowShort, "允许做空")
+	flag.IntVar(&cfg.Lookback, "lookback", cfg.Lookback, "每次评估传给策略的最多K线数量，0 表示全部历史")
+	return &cfg
+}
+
+func loadBars(dbPath, timeframe, start, end string) ([]models.Bar, error) {
+	if !models.IsValidTimeframe(timeframe) {
+		return nil, fmt.Errorf("不支持的时间框架 %q", timeframe)
+	}
+	from, to := time.Unix(0, 0), time.Now()
+	var err error
+	if start != "" {
+		if from, err = time.Parse("2006-01-02", start); err != nil {
+			return nil, fmt.Errorf("开始日期格式错误: %v", err)
+		}
+	}
+	if end != "" {
+		if to, err = time.Parse("2006-01-02", end); err != nil {
+			return nil, fmt.Errorf("结束日期格式错误: %v", err)
+		}
+	}
+
+	db, err := storage.NewSQLiteDB(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	bars, err := storage.NewDataRepository(db.DB).GetHistoricalData(timeframe, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("读取历史数据失败: %v", err)
+	}
+	if len(bars) < 2 {
+		return nil, fmt.Errorf("%s 在指定时间范围内只有 %d 根K线", timeframe, len(bars))
+	}
+	return bars, nil
+}
@@ -0,0 +1,173 @@
+// cmd/optimize 在数据库中的历史K线上搜索策略参数
+//
+//	go run ./cmd/optimize -type simple_ma -timeframe 1Hour \
+//	  -param shortPeriod=5:20:5 -param longPeriod=20:60:10 -objective sharpe -heatmap heatmap.json
+//
+// 指定 -monte-carlo N 时对排名第一的参数组合（或向前滚动分析拼接后的样本外结果）进行 N 次蒙特卡洛模拟。
+// 指定 -walk-forward 时改为向前滚动分析：
+//
+//	go run ./cmd/optimize -type simple_ma -timeframe 1Hour -walk-forward \
+//	  -train-bars 500 -test-bars 100 -param shortPeriod=5:20:5 -param longPeriod=20:60:10 -equity oos.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/backtest"
+	"github.com/qqqq/eth-trading-system/internal/config"
+	"github.com/qqqq/eth-trading-system/internal/strategy"
+	"github.com/qqqq/eth-trading-system/internal/utils"
+)
+
+// multiFlag 支持重复指定的字符串参数
+type multiFlag []string
+
+func (m *multiFlag) String() string     { return strings.Join(*m, ",") }
+func (m *multiFlag) Set(v string) error { *m = append(*m, v); return nil }
+
+func main() {
+	var params, fixed multiFlag
+	dbPath := flag.String("db", "./data/eth_trading.db", "SQLite 数据库路径")
+	strategyType := flag.String("type", "simple_ma", "策略类型")
+	timeframe := flag.String("timeframe", "1Hour", "K线时间框架")
+	start := flag.String("start", "", "开始日期（2006-01-02），默认为最早的数据")
+	end := flag.String("end", "", "结束日期（2006-01-02），默认为当前时间")
+	flag.Var(&params, "param", "待优化参数 name=min:max:step 或 name=a|b|c，可重复")
+	flag.Var(&fixed, "fixed", "固定参数 name=value，可重复")
+	method := flag.String("method", "grid", "搜索方式: grid 或 random")
+	samples := flag.Int("samples", 100, "随机搜索的参数组合数量")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "随机搜索的随机种子")
+	objective := flag.String("objective", backtest.ObjectiveSharpe, "优化目标: sharpe, return_drawdown, profit_factor")
+	minTrades := flag.Int("min-trades", 5, "有效结果的最少交易次数")
+	top := flag.Int("top", 20, "输出前 N 个结果，0 表示全部")
+	heatmapPath := flag.String("heatmap", "", "热力图 JSON 输出路径")
+	heatmapX := flag.String("heatmap-x", "", "热力图横轴参数，默认为第一个 -param")
+	heatmapY := flag.String("heatmap-y", "", "热力图纵轴参数，默认为第二个 -param")
+	workers := flag.Int("workers", 0, "并行回测数量，0 表示使用全部 CPU 核心")
+	btCfg := backtestFlags()
+	walkForward := flag.Bool("walk-forward", false, "进行向前滚动分析")
+	trainBars := flag.Int("train-bars", 500, "向前滚动分析的样本内K线数量")
+	testBars := flag.Int("test-bars", 100, "向前滚动分析的样本外K线数量")
+	anchored := flag.Bool("anchored", false, "样本内窗口起点固定在第一根K线")
+	equityPath := flag.String("equity", "", "向前滚动分析拼接后的样本外权益曲线 JSON 输出路径")
+	mcCfg := monteCarloFlags()
+	flag.Parse()
+
+	if len(params) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := utils.InitLogger(os.TempDir(), "error"); err != nil {
+		log.Fatalf("初始化日志记录器失败: %v", err)
+	}
+
+	var ranges []backtest.ParamRange
+	var names []string
+	for _, spec := range params {
+		r, err := backtest.ParseParamRange(spec)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ranges = append(ranges, r)
+		names = append(names, r.Name)
+	}
+	base := config.StrategyConfig{Name: *strategyType, Type: *strategyType, Params: map[string]interface{}{}}
+	for _, spec := range fixed {
+		r, err := backtest.ParseParamRange(spec)
+		if err != nil || len(r.Values) != 1 {
+			log.Fatalf("固定参数 %q 格式应为 name=value", spec)
+		}
+		base.Params[r.Name] = r.Values[0]
+	}
+
+	bars, err := loadBars(*dbPath, *timeframe, *start, *end)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opt := &backtest.Optimizer{
+		Registry:  strategy.NewRegistry(),
+		Base:      base,
+		Bars:      bars,
+		Timeframe: *timeframe,
+		Backtest:  *btCfg,
+		Objective: *objective,
+		MinTrades: *minTrades,
+		Workers:   *workers,
+	}
+
+	if *walkForward {
+		runWalkForward(opt, ranges, backtest.WalkForwardConfig{
+			TrainBars: *trainBars,
+			TestBars:  *testBars,
+			Anchored:  *anchored,
+			Method:    *method,
+			Samples:   *samples,
+			Seed:      *seed,
+		}, *equityPath, mcCfg)
+		return
+	}
+
+	began := time.Now()
+	var results []backtest.OptimizationResult
+	switch *method {
+	case backtest.SearchGrid:
+		results, err = opt.GridSearch(ranges)
+	case backtest.SearchRandom:
+		results, err = opt.RandomSearch(ranges, *samples, *seed)
+	default:
+		log.Fatalf("不支持的搜索方式 %q，可选值: grid, random", *method)
+	}
+	if err != nil {
+		log.Fatalf("参数优化失败: %v", err)
+	}
+
+	fmt.Printf("%s %s：%d 根K线，%d 组参数，耗时 %s，目标 %s\n\n",
+		*strategyType, *timeframe, len(bars), len(results), time.Since(began).Round(time.Millisecond), *objective)
+	if err := backtest.WriteTable(os.Stdout, results, names, *top); err != nil {
+		log.Fatal(err)
+	}
+
+	if mcCfg.Iterations > 0 {
+		if len(results) == 0 || !results[0].Valid {
+			log.Fatal("没有有效的参数组合可供蒙特卡洛分析")
+		}
+		mcCfg.Seed = *seed
+		best, err := opt.RunParams(results[0].Params)
+		if err != nil {
+			log.Fatalf("回测最优参数失败: %v", err)
+		}
+		runMonteCarlo(best, btCfg.InitialCapital, *mcCfg)
+	}
+
+	if *heatmapPath != "" {
+		x, y := *heatmapX, *heatmapY
+		if x == "" {
+			x = names[0]
+		}
+		if y == "" && len(names) > 1 {
+			y = names[1]
+		}
+		if y == "" {
+			log.Fatal("生成热力图需要两个参数")
+		}
+		heatmap, err := backtest.BuildHeatmap(results, x, y, *objective)
+		if err != nil {
+			log.Fatal(err)
+		}
+		data, err := json.MarshalIndent(heatmap, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*heatmapPath, data, 0644); err != nil {
+			log.Fatalf("写入热力图失败: %v", err)
+		}
+		fmt.Printf("\n热力图已写入 %s\n", *heatmapPath)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/qqqq/eth-trading-system/internal/backtest"
+)
+
+// monteCarloFlags 注册蒙特卡洛分析相关的命令行选项
+func monteCarloFlags() *backtest.MonteCarloConfig {
+	cfg := backtest.DefaultMonteCarloConfig()
+	cfg.Iterations = 0
+	flag.IntVar(&cfg.Iterations, "monte-carlo", cfg.Iterations, "对最优结果进行蒙特卡洛分析的模拟次数，0 表示不分析")
+	flag.StringVar(&cfg.Method, "mc-method", cfg.Method, "蒙特卡洛抽样方式: bootstrap 或 shuffle")
+	flag.StringVar(&cfg.Source, "mc-source", cfg.Source, "蒙特卡洛抽样对象: trades 或 returns")
+	flag.Float64Var(&cfg.Slippage, "mc-slippage", cfg.Slippage, "每次成交额外的最大随机滑点比例")
+	flag.Float64Var(&cfg.SkipProbability, "mc-skip", cfg.SkipProbability, "每笔交易被跳过的概率")
+	flag.Float64Var(&cfg.RuinDrawdown, "mc-ruin", cfg.RuinDrawdown, "视为破产的最大回撤百分比")
+	flag.Float64Var(&cfg.Confidence, "mc-confidence", cfg.Confidence, "置信区间的置信水平")
+	return &cfg
+}
+
+// runMonteCarlo 对回测结果进行蒙特卡洛分析并输出分布
+func runMonteCarlo(result *backtest.Result, initialCapital float64, cfg backtest.MonteCarloConfig) {
+	mc, err := backtest.MonteCarlo(result, initialCapital, cfg)
+	if err != nil {
+		log.Fatalf("蒙特卡洛分析失败: %v", err)
+	}
+
+	fmt.Printf("\n蒙特卡洛分析（%s %s，%d 次模拟，每次 %d 个样本）\n\n", cfg.Source, cfg.Method, mc.Iterations, mc.Samples)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "指标\t实际\t均值\t标准差\t最小\tP5\tP50\tP95\t最大\t%.0f%% 置信区间\n", mc.Confidence*100)
+	for _, row := range []struct {
+		name   string
+		actual float64
+		d      backtest.Distribution
+	}{
+		{"最终收益%", result.Metrics.TotalReturn, mc.FinalReturn},
+		{"最大回撤%", result.Metrics.MaxDrawdown, mc.MaxDrawdown},
+	} {
+		d := row.d
+		fmt.Fprintf(tw, "%s\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t[%.2f, %.2f]\n",
+			row.name, row.actual, d.Mean, d.Std, d.Min, d.P5, d.P50, d.P95, d.Max, d.Lower, d.Upper)
+	}
+	if err := tw.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("\n亏损概率 %.1f%%，破产风险（回撤 ≥ %.0f%%）%.1f%%\n", mc.ProbabilityOfLoss, cfg.RuinDrawdown, mc.RiskOfRuin)
+}
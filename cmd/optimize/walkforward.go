@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/backtest"
+)
+
+// runWalkForward 执行向前滚动分析并输出每个窗口的结果和拼接后的样本外绩效
+func runWalkForward(opt *backtest.Optimizer, ranges []backtest.ParamRange, wf backtest.WalkForwardConfig, equityPath string, mcCfg *backtest.MonteCarloConfig) {
+	began := time.Now()
+	result, err := opt.WalkForward(ranges, wf)
+	if err != nil {
+		log.Fatalf("向前滚动分析失败: %v", err)
+	}
+
+	mode := "滚动"
+	if wf.Anchored {
+		mode = "锚定"
+	}
+	fmt.Printf("向前滚动分析（%s窗口，样本内 %d 根，样本外 %d 根）：%d 个窗口，耗时 %s，目标 %s\n\n",
+		mode, wf.TrainBars, wf.TestBars, len(result.Windows), time.Since(began).Round(time.Millisecond), opt.Objective)
+
+	const layout = "2006-01-02 15:04"
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\t样本内\t样本外\t参数\t样本内收益%\t样本外收益%\t样本外回撤%\t样本外交易\t备注")
+	for i, w := range result.Windows {
+		fmt.Fprintf(tw, "%d\t%s ~ %s\t%s ~ %s\t%s\t%.2f\t%.2f\t%.2f\t%d\t%s\n",
+			i+1, w.TrainStart.Format(layout), w.TrainEnd.Format(layout), w.TestStart.Format(layout), w.TestEnd.Format(layout),
+			formatParams(w.Params), w.TrainMetrics.TotalReturn, w.TestMetrics.TotalReturn, w.TestMetrics.MaxDrawdown,
+			w.TestMetrics.Trades, w.Error)
+	}
+	if err := tw.Flush(); err != nil {
+		log.Fatal(err)
+	}
+
+	m := result.Metrics
+	fmt.Printf("\n样本外合计：收益 %.2f%%，最大回撤 %.2f%%，夏普 %.2f，交易 %d 次，胜率 %.1f%%，盈亏比 %.2f\n",
+		m.TotalReturn, m.MaxDrawdown, m.Sharpe, m.Trades, m.WinRate, m.ProfitFactor)
+	fmt.Printf("向前滚动效率 (WFE)：%.2f\n", result.Efficiency)
+
+	if equityPath != "" {
+		data, err := json.MarshalIndent(result.Equity, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(equityPath, data, 0644); err != nil {
+			log.Fatalf("写入权益曲线失败: %v", err)
+		}
+		fmt.Printf("\n样本外权益曲线已写入 %s\n", equityPath)
+	}
+
+	if mcCfg.Iterations > 0 {
+		mcCfg.Seed = wf.Seed
+		stitched := &backtest.Result{Timeframe: opt.Timeframe, Trades: result.Trades, Equity: result.Equity, Metrics: result.Metrics}
+		runMonteCarlo(stitched, opt.Backtest.InitialCapital, *mcCfg)
+	}
+}
+
+func formatParams(params map[string]interface{}) string {
+	if params == nil {
+		return "-"
+	}
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%v", k, params[k])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,44 @@
+// cmd/secrets 用于生成 config.secretsFile 所需的加密密钥文件
+//
+//	ETHBOT_SECRETS_PASSPHRASE=... go run ./cmd/secrets -in secrets.yaml -out secrets.enc
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/qqqq/eth-trading-system/internal/config"
+)
+
+func main() {
+	in := flag.String("in", "", "明文 YAML 密钥文件路径")
+	out := flag.String("out", "secrets.enc", "加密后输出文件路径")
+	flag.Parse()
+
+	if *in == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	passphrase, err := config.SecretsPassphrase()
+	if err != nil {
+		log.Fatalf("获取口令失败: %v", err)
+	}
+
+	plaintext, err := os.ReadFile(*in)
+	if err != nil {
+		log.Fatalf("读取明文文件失败: %v", err)
+	}
+
+	data, err := config.EncryptSecrets(plaintext, passphrase)
+	if err != nil {
+		log.Fatalf("加密失败: %v", err)
+	}
+
+	if err := os.WriteFile(*out, data, 0600); err != nil {
+		log.Fatalf("写入加密文件失败: %v", err)
+	}
+
+	log.Printf("已生成加密密钥文件: %s", *out)
+}
@@ -3,17 +3,28 @@ package main
 import (
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/qqqq/eth-trading-system/internal/alerts"
 	"github.com/qqqq/eth-trading-system/internal/analysis"
+	"github.com/qqqq/eth-trading-system/internal/analysis/indicators"
 	"github.com/qqqq/eth-trading-system/internal/api"
 	"github.com/qqqq/eth-trading-system/internal/config"
 	"github.com/qqqq/eth-trading-system/internal/datamanager"
+	"github.com/qqqq/eth-trading-system/internal/models"
+	"github.com/qqqq/eth-trading-system/internal/notify"
+	"github.com/qqqq/eth-trading-system/internal/portfolio"
+	"github.com/qqqq/eth-trading-system/internal/risk"
+	"github.com/qqqq/eth-trading-system/internal/scripting"
 	"github.com/qqqq/eth-trading-system/internal/services"
 	"github.com/qqqq/eth-trading-system/internal/storage"
 	"github.com/qqqq/eth-trading-system/internal/strategy"
 	"github.com/qqqq/eth-trading-system/internal/utils"
 )
 
+// tradingLookback 模拟交易评估策略时加载的K线时间范围，与 /api/analysis 一致
+const tradingLookback = 30 * 24 * time.Hour
+
 func main() {
 	cfg, err := config.LoadConfig()
 	if err != nil {
@@ -23,6 +34,8 @@ func main() {
 	if err := utils.InitLogger(cfg.LogDir, cfg.LogLevel); err != nil {
 		log.Fatalf("初始化日志记录器失败: %v", err)
 	}
+	redactHook := utils.NewRedactHook(cfg.SecretValues()...)
+	utils.Log.AddHook(redactHook)
 
 	utils.Log.Info("启动ETH交易系统")
 
@@ -34,12 +47,35 @@ func main() {
 
 	dataRepo := storage.NewDataRepository(db.DB)
 
-	analysisEngine := analysis.NewAnalysisEngine()
+	analysisEngine, err := analysis.NewAnalysisEngine(indicators.MovingAverageType(cfg.Analysis.TrendMAType), cfg.Analysis.TrendShortPeriod, cfg.Analysis.TrendLongPeriod)
+	if err != nil {
+		utils.Log.Fatalf("初始化分析引擎失败: %v", err)
+	}
+
+	// 模拟交易：新K线采集完成后评估策略，信号经风控检查后下单
+	riskManager := risk.NewManager(cfg.Risk, cfg.Trading.InitialCapital)
+	tradingService := services.NewTradingService(services.NewPaperBroker(cfg.Trading.FeeRate), riskManager, storage.NewProtectionRepository(db.DB))
+	tradingService.SetConfig(cfg.Trading)
 
-	simpleMAStrategy := strategy.NewSimpleMAStrategy(10, 30)
-	macdStrategy := strategy.NewMACDStrategy()
+	strategyRegistry := strategy.NewRegistry()
+	strategyRegistry.SetSignalHistory(storage.NewSignalRepository(db.DB))
+	strategyRegistry.SetPositions(tradingService)
+	strategyRegistry.SetStateStore(storage.NewStrategyStateRepository(db.DB))
+	strategies, err := strategyRegistry.BuildAll(cfg.Strategies)
+	if err != nil {
+		utils.Log.Fatalf("构建策略失败: %v", err)
+	}
+	strategyService := services.NewStrategyService(strategies...)
 
-	strategyService := services.NewStrategyService(simpleMAStrategy, macdStrategy)
+	// 脚本策略：脚本目录中的文件变化后自动重新加载，与配置中的策略一起评估
+	scriptManager := scripting.NewManager()
+	scriptManager.OnChange(func(strategies []strategy.Strategy) {
+		strategyService.SetScriptStrategies(strategies...)
+	})
+	if err := scriptManager.SetConfig(cfg.Scripts); err != nil {
+		utils.Log.WithError(err).Error("加载脚本策略失败")
+	}
+	defer scriptManager.Stop()
 
 	// Create analysis service
 	analysisService := services.NewAnalysisService(analysisEngine, dataRepo, strategyService)
@@ -51,18 +87,178 @@ func main() {
 	dataManager := datamanager.NewDataManager(alpacaService, dataRepo)
 
 	// 使用 DataManager 创建 DataCollectionService
-	dataCollectionService := services.NewDataCollectionService(dataManager)
+	dataCollectionService := services.NewDataCollectionService(dataManager, cfg.Timeframes...)
+	dataCollectionService.SetPriceMoveAlert(cfg.Alerts.PriceMovePercent)
+
+	notifier, err := notify.NewNotifier(cfg.Notifications)
+	if err != nil {
+		utils.Log.Fatalf("初始化通知渠道失败: %v", err)
+	}
+	dataCollectionService.OnPriceAlert(func(bar models.Bar, previous, changePercent float64) {
+		notifier.Notify(notify.PriceAlertEvent(strategy.DefaultSymbol, bar, previous, changePercent))
+	})
+
+	// 用户告警规则：K线类规则在新K线采集后评估，价格类规则在每次采集最新价格后评估
+	alertEngine, err := alerts.NewEngine(storage.NewAlertRepository(db.DB), dataRepo)
+	if err != nil {
+		utils.Log.Fatalf("加载告警规则失败: %v", err)
+	}
+	alertEngine.OnTrigger(func(rule models.AlertRule, trigger models.AlertTrigger) {
+		notifier.Notify(notify.AlertEvent(trigger))
+	})
+	dataCollectionService.OnBars(alertEngine.OnBars)
+	dataCollectionService.OnPrice(func(bar models.Bar) {
+		alertEngine.OnPrice(strategy.DefaultSymbol, bar)
+	})
+
+	// 账本从数据库恢复持仓，并据此恢复风控、策略持仓和保护性订单
+	ledger, err := portfolio.NewLedger(cfg.Trading.InitialCapital, storage.NewPortfolioRepository(db.DB), dataRepo)
+	if err != nil {
+		utils.Log.Fatalf("初始化账本失败: %v", err)
+	}
+	if fills, err := ledger.Fills(); err != nil {
+		utils.Log.Fatalf("读取成交记录失败: %v", err)
+	} else if err := tradingService.Restore(fills); err != nil {
+		utils.Log.Fatalf("恢复保护性订单失败: %v", err)
+	}
+	tradingService.OnFill(func(fill models.Fill) {
+		if err := ledger.RecordFill(fill); err != nil {
+			utils.Log.WithError(err).WithField("order", fill.OrderID).Error("记录成交失败")
+		}
+		strategyService.OnFill(fill)
+		notifier.Notify(notify.FillEvent(fill))
+	})
+	tradingService.OnRejection(func(order models.Order, rejection *risk.Rejection) {
+		notifier.Notify(notify.RejectionEvent(order, rejection.Rule, rejection.Reason))
+	})
+	tradingService.OnKill(func(reason string) {
+		notifier.Notify(notify.KillEvent(reason))
+	})
+	ledger.StartSnapshots(cfg.Trading.SnapshotInterval)
+	// seedSignals 以已保存的最新K线作为信号基准，交易开始前已存在的K线不再下单
+	seedSignals := func(timeframes []string) {
+		end := time.Now()
+		for _, timeframe := range timeframes {
+			bars, err := dataRepo.GetHistoricalData(timeframe, end.Add(-tradingLookback), end)
+			if err != nil {
+				utils.Log.WithError(err).Errorf("读取K线失败，时间框架: %s", timeframe)
+				continue
+			}
+			if len(bars) > 0 {
+				tradingService.SeedSignals(timeframe, bars[len(bars)-1].Timestamp)
+			}
+		}
+	}
+	if cfg.Trading.Enabled {
+		seedSignals(cfg.Timeframes)
+	}
+	// 新K线采集完成后评估策略：非 HOLD 信号发送通知，开启模拟交易时下单
+	dataCollectionService.OnBars(func(timeframe string) {
+		trading := tradingService.Enabled()
+		if !trading && !notifier.Enabled() {
+			return
+		}
+		end := time.Now()
+		bars, err := dataRepo.GetHistoricalData(timeframe, end.Add(-tradingLookback), end)
+		if err != nil {
+			utils.Log.WithError(err).Errorf("读取K线失败，时间框架: %s", timeframe)
+			return
+		}
+		if trading && len(bars) > 0 {
+			tradingService.EvaluateBar(strategy.DefaultSymbol, bars[len(bars)-1])
+		}
+		result, err := analysisService.AnalyzeMarket(timeframe, bars)
+		if err != nil {
+			utils.Log.WithError(err).Errorf("评估策略失败，时间框架: %s", timeframe)
+			return
+		}
+		for _, signal := range result.StrategySignals {
+			notifier.NotifySignal(signal)
+		}
+		if trading {
+			tradingService.HandleSignals(result.StrategySignals, bars)
+		}
+	})
+	dataCollectionService.OnPrice(func(bar models.Bar) {
+		tradingService.UpdatePrice(strategy.DefaultSymbol, bar.Close, bar.Timestamp)
+	})
 	dataCollectionService.Start()
 
+	// 配置热更新
+	watcher := config.NewWatcher(cfg)
+	watcher.OnReload(func(newCfg *config.Config, changes []config.Change) {
+		redactHook.SetSecrets(newCfg.SecretValues()...)
+		if err := utils.SetLogLevel(newCfg.LogLevel); err != nil {
+			utils.Log.WithError(err).Error("更新日志级别失败")
+		}
+		if strategies, err := strategyRegistry.BuildAll(newCfg.Strategies); err != nil {
+			utils.Log.WithError(err).Error("重新构建策略失败，继续使用原策略")
+		} else {
+			strategyService.SetStrategies(strategies...)
+		}
+		// 交易重新开启时所有时间框架重新设置基准，交易中新增的时间框架在开始采集前设置基准
+		if !tradingService.Enabled() && newCfg.Trading.Enabled {
+			seedSignals(newCfg.Timeframes)
+		} else if newCfg.Trading.Enabled {
+			seedSignals(addedTimeframes(dataCollectionService.Timeframes(), newCfg.Timeframes))
+		}
+		dataCollectionService.SetTimeframes(newCfg.Timeframes)
+		dataCollectionService.SetPriceMoveAlert(newCfg.Alerts.PriceMovePercent)
+		tradingService.SetConfig(newCfg.Trading)
+		tradingService.SetRiskConfig(newCfg.Risk)
+		if err := notifier.SetConfig(newCfg.Notifications); err != nil {
+			utils.Log.WithError(err).Error("更新通知渠道失败，继续使用原渠道")
+		}
+		if err := scriptManager.SetConfig(newCfg.Scripts); err != nil {
+			utils.Log.WithError(err).Error("更新脚本策略失败")
+		}
+	})
+	watcher.Start()
+
 	handler := api.NewHandler(alpacaService, dataCollectionService, analysisService)
+	tradingHandler := api.NewTradingHandler(tradingService)
+	portfolioHandler := api.NewPortfolioHandler(ledger)
+	notifyHandler := api.NewNotifyHandler(notifier)
+	alertHandler := api.NewAlertHandler(alertEngine)
+	expressionHandler := api.NewExpressionHandler()
+	scriptHandler := api.NewScriptHandler(scriptManager)
 
 	http.HandleFunc("/", handler.IndexHandler)
 	http.HandleFunc("/api/price", handler.GetLatestPrice)
 	http.HandleFunc("/api/historical", handler.GetHistoricalData)
 	http.HandleFunc("/api/analysis", handler.GetMarketAnalysis)
+	http.HandleFunc("/api/risk", tradingHandler.GetRiskStatus)
+	http.HandleFunc("/api/risk/kill", tradingHandler.Kill)
+	http.HandleFunc("/api/risk/resume", tradingHandler.Resume)
+	http.HandleFunc("/api/protection", tradingHandler.GetProtectiveOrders)
+	http.HandleFunc("/api/portfolio", portfolioHandler.GetPortfolio)
+	http.HandleFunc("/api/portfolio/equity", portfolioHandler.GetEquityHistory)
+	http.HandleFunc("/api/portfolio/gains", portfolioHandler.GetGains)
+	http.HandleFunc("/api/notifications/test", notifyHandler.Test)
+	http.HandleFunc("/api/alerts", alertHandler.Rules)
+	http.HandleFunc("/api/alerts/", alertHandler.Rule)
+	http.HandleFunc("/api/alerts/history", alertHandler.History)
+	http.HandleFunc("/api/expressions/validate", expressionHandler.Validate)
+	http.HandleFunc("/api/expressions/functions", expressionHandler.Functions)
+	http.HandleFunc("/api/scripts", scriptHandler.GetScripts)
 
 	utils.Log.Infof("服务器启动在端口 %s", cfg.ServerPort)
 	if err := http.ListenAndServe(cfg.ServerPort, nil); err != nil {
 		utils.Log.Fatalf("启动服务器失败: %v", err)
 	}
 }
+
+// addedTimeframes 返回 next 中有而 current 中没有的时间框架
+func addedTimeframes(current, next []string) []string {
+	existing := make(map[string]bool, len(current))
+	for _, timeframe := range current {
+		existing[timeframe] = true
+	}
+	var added []string
+	for _, timeframe := range next {
+		if !existing[timeframe] {
+			added = append(added, timeframe)
+		}
+	}
+	return added
+}
@@ -0,0 +1,109 @@
+// cmd/taxreport 按税务批次计算数据库中成交记录的年度已实现损益，并输出 Form 8949 格式的 CSV
+//
+//	go run ./cmd/taxreport -year 2024 -method hifo -o form8949_2024.csv
+//
+// 指定批次方式需要提供批次选择文件，每行为 sale_order_id,lot_order_id,quantity，
+// 未指定的卖出数量按先进先出处理：
+//
+//	go run ./cmd/taxreport -year 2024 -method specific -lots selections.csv -o form8949_2024.csv
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/portfolio"
+	"github.com/qqqq/eth-trading-system/internal/storage"
+)
+
+func main() {
+	dbPath := flag.String("db", "./data/eth_trading.db", "SQLite 数据库路径")
+	year := flag.Int("year", time.Now().UTC().Year(), "报告年度（UTC）")
+	method := flag.String("method", portfolio.LotFIFO, "批次方式: fifo, lifo, hifo, specific")
+	lotsPath := flag.String("lots", "", "指定批次方式的批次选择 CSV")
+	output := flag.String("o", "", "Form 8949 CSV 输出路径，默认输出到标准输出")
+	flag.Parse()
+
+	var selections []portfolio.Selection
+	if *lotsPath != "" {
+		var err error
+		if selections, err = readSelections(*lotsPath); err != nil {
+			log.Fatal(err)
+		}
+	} else if *method == portfolio.LotSpecific {
+		log.Fatal("指定批次方式需要 -lots 批次选择文件")
+	}
+
+	db, err := storage.NewSQLiteDB(*dbPath)
+	if err != nil {
+		log.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	fills, err := storage.NewPortfolioRepository(db.DB).Fills()
+	if err != nil {
+		log.Fatalf("读取成交记录失败: %v", err)
+	}
+	result, err := portfolio.MatchLots(fills, *method, selections)
+	if err != nil {
+		log.Fatal(err)
+	}
+	report := portfolio.NewGainsReport(result, *year)
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("创建输出文件失败: %v", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := report.WriteForm8949CSV(out); err != nil {
+		log.Fatalf("写入 CSV 失败: %v", err)
+	}
+
+	if *output != "" {
+		fmt.Printf("%d 年（%s）：%d 笔处置\n", report.Year, report.Method, len(report.Disposals))
+		fmt.Printf("短期  收入 %.2f  成本 %.2f  损益 %.2f\n", report.ShortTerm.Proceeds, report.ShortTerm.CostBasis, report.ShortTerm.Gain)
+		fmt.Printf("长期  收入 %.2f  成本 %.2f  损益 %.2f\n", report.LongTerm.Proceeds, report.LongTerm.CostBasis, report.LongTerm.Gain)
+		fmt.Printf("未平仓批次 %d 个，Form 8949 已写入 %s\n", len(result.Open), *output)
+	}
+}
+
+// readSelections 读取批次选择文件，首行以 sale 开头时视为表头
+func readSelections(path string) ([]portfolio.Selection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开批次选择文件失败: %v", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("读取批次选择文件失败: %v", err)
+	}
+
+	var selections []portfolio.Selection
+	for i, record := range records {
+		if i == 0 && strings.HasPrefix(strings.ToLower(record[0]), "sale") {
+			continue
+		}
+		quantity, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("批次选择文件第 %d 行数量格式错误: %v", i+1, err)
+		}
+		selections = append(selections, portfolio.Selection{SaleID: record[0], LotID: record[1], Quantity: quantity})
+	}
+	return selections, nil
+}
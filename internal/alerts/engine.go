@@ -0,0 +1,407 @@
+package alerts
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+	"github.com/qqqq/eth-trading-system/internal/storage"
+	"github.com/qqqq/eth-trading-system/internal/utils"
+)
+
+// lookbackBars 评估K线类规则时加载的K线数量，足以计算默认周期的指标
+const lookbackBars = 300
+
+// BarSource 提供评估K线类规则所需的历史K线
+type BarSource interface {
+	GetHistoricalData(timeframe string, start, end time.Time) ([]models.Bar, error)
+}
+
+type pricePoint struct {
+	at    time.Time
+	price float64
+}
+
+// result 一次规则评估的结果
+type result struct {
+	matched bool
+	price   float64
+	value   float64
+	message string
+}
+
+// Engine 管理告警规则并在新K线和最新价格到达时评估。规则缓存在内存中，增删改时同步更新
+type Engine struct {
+	repo storage.AlertRepository
+	bars BarSource
+
+	mu    sync.Mutex
+	rules []models.AlertRule
+	// lastBar 每条K线类规则已评估的最新K线，同一根K线只评估一次
+	lastBar map[int64]time.Time
+	// matched 每条规则上一次评估时条件是否满足，用于 recurring 模式
+	matched map[int64]bool
+	// prices 最新价格历史，用于价格穿越和时间窗口内的涨跌幅
+	prices    map[string][]pricePoint
+	listeners []func(rule models.AlertRule, trigger models.AlertTrigger)
+}
+
+func NewEngine(repo storage.AlertRepository, bars BarSource) (*Engine, error) {
+	rules, err := repo.ListRules()
+	if err != nil {
+		return nil, err
+	}
+	return &Engine{
+		repo:    repo,
+		bars:    bars,
+		rules:   rules,
+		lastBar: make(map[int64]time.Time),
+		matched: make(map[int64]bool),
+		prices:  make(map[string][]pricePoint),
+	}, nil
+}
+
+// OnTrigger 注册回调，规则触发并保存记录后调用
+func (e *Engine) OnTrigger(fn func(rule models.AlertRule, trigger models.AlertTrigger)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.listeners = append(e.listeners, fn)
+}
+
+// Rules 返回全部规则
+func (e *Engine) Rules() []models.AlertRule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]models.AlertRule{}, e.rules...)
+}
+
+// Rule 返回指定规则，不存在时返回 nil
+func (e *Engine) Rule(id int64) *models.AlertRule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if i := e.index(id); i >= 0 {
+		rule := e.rules[i]
+		return &rule
+	}
+	return nil
+}
+
+// index 返回规则在缓存中的位置，调用方需持有 e.mu
+func (e *Engine) index(id int64) int {
+	for i := range e.rules {
+		if e.rules[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// CreateRule 校验并保存新规则
+func (e *Engine) CreateRule(rule models.AlertRule) (*models.AlertRule, error) {
+	if err := Normalize(&rule); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	rule.ID, rule.LastTriggeredAt, rule.CreatedAt, rule.UpdatedAt = 0, nil, now, now
+	if err := e.repo.CreateRule(&rule); err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	e.rules = append(e.rules, rule)
+	e.mu.Unlock()
+	return &rule, nil
+}
+
+// UpdateRule 用 rule 替换指定规则并清除其评估状态，规则不存在时返回 nil
+func (e *Engine) UpdateRule(id int64, rule models.AlertRule) (*models.AlertRule, error) {
+	if err := Normalize(&rule); err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	i := e.index(id)
+	if i < 0 {
+		return nil, nil
+	}
+	rule.ID = id
+	rule.CreatedAt = e.rules[i].CreatedAt
+	rule.LastTriggeredAt = e.rules[i].LastTriggeredAt
+	rule.UpdatedAt = time.Now().UTC()
+	if err := e.repo.UpdateRule(&rule); err != nil {
+		return nil, err
+	}
+	e.rules[i] = rule
+	delete(e.lastBar, id)
+	delete(e.matched, id)
+	return &rule, nil
+}
+
+// DeleteRule 删除规则，返回规则是否存在
+func (e *Engine) DeleteRule(id int64) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	i := e.index(id)
+	if i < 0 {
+		return false, nil
+	}
+	if err := e.repo.DeleteRule(id); err != nil {
+		return false, err
+	}
+	e.rules = append(e.rules[:i], e.rules[i+1:]...)
+	delete(e.lastBar, id)
+	delete(e.matched, id)
+	return true, nil
+}
+
+// History 返回最近的触发记录，ruleID 为 0 时返回全部规则的记录
+func (e *Engine) History(ruleID int64, limit int) ([]models.AlertTrigger, error) {
+	return e.repo.Triggers(ruleID, limit)
+}
+
+// OnBars 在时间框架 timeframe 采集到新K线后评估该时间框架上的规则。
+// 规则只在已收盘的K线上评估，每根K线评估一次；尚未收盘的最新K线留到收盘后评估
+func (e *Engine) OnBars(timeframe string) {
+	e.mu.Lock()
+	var ids []int64
+	for _, rule := range e.rules {
+		if rule.Enabled && rule.Timeframe == timeframe {
+			ids = append(ids, rule.ID)
+		}
+	}
+	e.mu.Unlock()
+	if len(ids) == 0 {
+		return
+	}
+
+	duration, _ := models.TimeframeDuration(timeframe)
+	end := time.Now()
+	bars, err := e.bars.GetHistoricalData(timeframe, end.Add(-duration*lookbackBars), end)
+	if err != nil {
+		utils.Log.WithError(err).Errorf("读取K线失败，无法评估告警规则，时间框架: %s", timeframe)
+		return
+	}
+	// 采集到当前时刻的最后一根K线可能尚未收盘，成交量和收盘价都不完整
+	if n := len(bars); n > 0 && bars[n-1].Timestamp.Add(duration).After(end) {
+		bars = bars[:n-1]
+	}
+	if len(bars) == 0 {
+		return
+	}
+	last := bars[len(bars)-1].Timestamp
+
+	e.evaluate(func(rule *models.AlertRule) (result, bool) {
+		if rule.Timeframe != timeframe {
+			return result{}, false
+		}
+		if seen, ok := e.lastBar[rule.ID]; ok && !last.After(seen) {
+			return result{}, false
+		}
+		e.lastBar[rule.ID] = last
+		return evaluateBars(rule, bars), true
+	})
+}
+
+// OnPrice 用最新价格评估未设置时间框架的价格类规则
+func (e *Engine) OnPrice(symbol string, bar models.Bar) {
+	e.mu.Lock()
+	history := append(e.prices[symbol], pricePoint{at: bar.Timestamp, price: bar.Close})
+	e.prices[symbol] = e.trim(history, bar.Timestamp)
+	e.mu.Unlock()
+
+	e.evaluate(func(rule *models.AlertRule) (result, bool) {
+		if rule.Timeframe != "" || rule.Symbol != symbol {
+			return result{}, false
+		}
+		return evaluatePrice(rule, history), true
+	})
+}
+
+// trim 只保留最长时间窗口内的价格，且至少保留两个点用于判断穿越。调用方需持有 e.mu
+func (e *Engine) trim(history []pricePoint, now time.Time) []pricePoint {
+	var window time.Duration
+	for _, rule := range e.rules {
+		if rule.Type == models.AlertPercentMove && rule.Timeframe == "" {
+			if w, err := time.ParseDuration(rule.Window); err == nil && w > window {
+				window = w
+			}
+		}
+	}
+	start := 0
+	for start < len(history)-2 && now.Sub(history[start+1].at) >= window {
+		start++
+	}
+	return append([]pricePoint{}, history[start:]...)
+}
+
+// evaluate 对每条启用的规则调用 check，按模式决定是否触发，保存触发记录后通知监听者
+func (e *Engine) evaluate(check func(rule *models.AlertRule) (result, bool)) {
+	type fired struct {
+		rule    models.AlertRule
+		trigger models.AlertTrigger
+	}
+	var triggered []fired
+
+	e.mu.Lock()
+	now := time.Now().UTC()
+	for i := range e.rules {
+		rule := &e.rules[i]
+		if !rule.Enabled {
+			continue
+		}
+		res, ok := check(rule)
+		if !ok || !e.shouldFire(rule, res.matched, now) {
+			continue
+		}
+
+		trigger := models.AlertTrigger{
+			RuleID:    rule.ID,
+			RuleName:  rule.Name,
+			Symbol:    rule.Symbol,
+			Price:     res.price,
+			Value:     res.value,
+			Message:   res.message,
+			Timestamp: now,
+		}
+		if err := e.repo.RecordTrigger(&trigger); err != nil {
+			utils.Log.WithError(err).WithField("rule", rule.Name).Error("保存告警触发记录失败")
+		}
+		disable := rule.Mode == models.AlertModeOnce
+		if err := e.repo.MarkTriggered(rule.ID, now, disable); err != nil {
+			utils.Log.WithError(err).WithField("rule", rule.Name).Error("更新告警规则失败")
+		}
+		triggeredAt := now
+		rule.LastTriggeredAt = &triggeredAt
+		if disable {
+			rule.Enabled = false
+		}
+		utils.Log.WithFields(map[string]interface{}{
+			"rule":  rule.Name,
+			"price": res.price,
+			"value": res.value,
+		}).Warnf("告警触发: %s", res.message)
+		triggered = append(triggered, fired{rule: *rule, trigger: trigger})
+	}
+	listeners := append([]func(models.AlertRule, models.AlertTrigger){}, e.listeners...)
+	e.mu.Unlock()
+
+	for _, f := range triggered {
+		for _, fn := range listeners {
+			fn(f.rule, f.trigger)
+		}
+	}
+}
+
+// shouldFire 按规则的模式判断条件满足时是否触发。调用方需持有 e.mu
+func (e *Engine) shouldFire(rule *models.AlertRule, matched bool, now time.Time) bool {
+	previous := e.matched[rule.ID]
+	e.matched[rule.ID] = matched
+	if !matched {
+		return false
+	}
+	switch rule.Mode {
+	case models.AlertModeRecur:
+		return !previous
+	case models.AlertModeCooldown:
+		cooldown, _ := time.ParseDuration(rule.Cooldown)
+		return rule.LastTriggeredAt == nil || now.Sub(*rule.LastTriggeredAt) >= cooldown
+	}
+	return true
+}
+
+// evaluateBars 用最新的两根K线评估K线类规则
+func evaluateBars(rule *models.AlertRule, bars []models.Bar) result {
+	n := len(bars)
+	last := bars[n-1]
+	res := result{price: last.Close}
+
+	switch rule.Type {
+	case models.AlertPriceCross:
+		prev := math.NaN()
+		if n > 1 {
+			prev = bars[n-2].Close
+		}
+		res.value = last.Close
+		res.matched = compare(rule.Condition, prev, rule.Value, last.Close, rule.Value)
+		res.message = fmt.Sprintf("%s %s 收盘价%s %.2f（当前 %.2f）", rule.Symbol, rule.Timeframe, conditionNames[rule.Condition], rule.Value, last.Close)
+
+	case models.AlertPercentMove:
+		window, _ := time.ParseDuration(rule.Window)
+		for i := n - 2; i >= 0; i-- {
+			if !bars[i].Timestamp.After(last.Timestamp.Add(-window)) {
+				res.value = (last.Close - bars[i].Close) / bars[i].Close * 100
+				res.matched = moved(rule.Condition, res.value, rule.Value)
+				break
+			}
+		}
+		res.message = fmt.Sprintf("%s %s 内涨跌幅 %+.2f%%，达到 %.2f%%（当前 %.2f）", rule.Symbol, rule.Window, res.value, rule.Value, last.Close)
+
+	case models.AlertIndicator:
+		a := series(rule.Indicator, rule.Period, bars)
+		label := indicatorLabel(rule.Indicator, rule.Period)
+		target := fmt.Sprintf("%.2f", rule.Value)
+		b := make([]float64, n)
+		if rule.CompareIndicator != "" {
+			b = series(rule.CompareIndicator, rule.ComparePeriod, bars)
+			target = indicatorLabel(rule.CompareIndicator, rule.ComparePeriod)
+		} else {
+			for i := range b {
+				b[i] = rule.Value
+			}
+		}
+		prevA, prevB := math.NaN(), math.NaN()
+		if n > 1 {
+			prevA, prevB = a[n-2], b[n-2]
+		}
+		res.value = a[n-1]
+		res.matched = compare(rule.Condition, prevA, prevB, a[n-1], b[n-1])
+		res.message = fmt.Sprintf("%s %s %s%s %s（当前 %.2f）", rule.Symbol, rule.Timeframe, label, conditionNames[rule.Condition], target, a[n-1])
+
+	case models.AlertVolumeSpike:
+		if n <= rule.Period {
+			break
+		}
+		var sum float64
+		for _, bar := range bars[n-1-rule.Period : n-1] {
+			sum += bar.Volume
+		}
+		if average := sum / float64(rule.Period); average > 0 {
+			res.value = last.Volume / average
+			res.matched = res.value >= rule.Value
+		}
+		res.message = fmt.Sprintf("%s %s 成交量为前 %d 根K线平均值的 %.2f 倍，达到 %.2f 倍", rule.Symbol, rule.Timeframe, rule.Period, res.value, rule.Value)
+	}
+	return res
+}
+
+// evaluatePrice 用最新价格历史评估价格类规则，history 的最后一个点为当前价格
+func evaluatePrice(rule *models.AlertRule, history []pricePoint) result {
+	n := len(history)
+	current := history[n-1]
+	res := result{price: current.price}
+
+	switch rule.Type {
+	case models.AlertPriceCross:
+		prev := math.NaN()
+		if n > 1 {
+			prev = history[n-2].price
+		}
+		res.value = current.price
+		res.matched = compare(rule.Condition, prev, rule.Value, current.price, rule.Value)
+		res.message = fmt.Sprintf("%s 价格%s %.2f（当前 %.2f）", rule.Symbol, conditionNames[rule.Condition], rule.Value, current.price)
+
+	case models.AlertPercentMove:
+		window, _ := time.ParseDuration(rule.Window)
+		// 与窗口内最早的价格比较，价格历史不足一个窗口时也会评估
+		for _, p := range history[:n-1] {
+			if current.at.Sub(p.at) <= window {
+				res.value = (current.price - p.price) / p.price * 100
+				res.matched = moved(rule.Condition, res.value, rule.Value)
+				break
+			}
+		}
+		res.message = fmt.Sprintf("%s %s 内涨跌幅 %+.2f%%，达到 %.2f%%（当前 %.2f）", rule.Symbol, rule.Window, res.value, rule.Value, current.price)
+	}
+	return res
+}
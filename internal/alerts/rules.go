@@ -0,0 +1,259 @@
+// Package alerts 评估用户定义的价格和指标告警规则
+package alerts
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/analysis/indicators"
+	"github.com/qqqq/eth-trading-system/internal/models"
+	"github.com/qqqq/eth-trading-system/internal/strategy"
+)
+
+// 比较条件
+const (
+	CondAbove        = "above"
+	CondBelow        = "below"
+	CondCrossesAbove = "crosses_above"
+	CondCrossesBelow = "crosses_below"
+	CondUp           = "up"
+	CondDown         = "down"
+	CondAny          = "any"
+)
+
+// defaultVolumePeriod volume_spike 未设置 period 时计算平均成交量的K线数量
+const defaultVolumePeriod = 20
+
+// 指标及其默认周期，macd 系列固定使用 (12, 26, 9)，布林带使用 2 倍标准差
+var indicatorPeriods = map[string]int{
+	"price":          0,
+	"volume":         0,
+	"sma":            20,
+	"rsi":            14,
+	"atr":            14,
+	"macd":           0,
+	"macd_signal":    0,
+	"macd_histogram": 0,
+	"bb_upper":       20,
+	"bb_middle":      20,
+	"bb_lower":       20,
+}
+
+// InvalidRuleError 规则参数不合法
+type InvalidRuleError struct {
+	Message string
+}
+
+func (e *InvalidRuleError) Error() string {
+	return e.Message
+}
+
+func invalid(format string, args ...interface{}) error {
+	return &InvalidRuleError{Message: fmt.Sprintf(format, args...)}
+}
+
+// Normalize 填充默认值并校验规则
+func Normalize(rule *models.AlertRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return invalid("name 不能为空")
+	}
+	if rule.Symbol == "" || strings.EqualFold(rule.Symbol, strategy.DefaultSymbol) {
+		rule.Symbol = strategy.DefaultSymbol
+	} else {
+		return invalid("当前只采集 %s 的数据，不支持标的 %q", strategy.DefaultSymbol, rule.Symbol)
+	}
+	if rule.Timeframe != "" && (!models.IsValidTimeframe(rule.Timeframe) || rule.Timeframe == "1Min") {
+		return invalid("不支持的时间框架 %q，可选值: 5Min, 15Min, 1Hour, 4Hour, 1Day", rule.Timeframe)
+	}
+
+	switch rule.Type {
+	case models.AlertPriceCross:
+		if err := checkCondition(rule.Condition, CondAbove, CondBelow, CondCrossesAbove, CondCrossesBelow); err != nil {
+			return err
+		}
+		if rule.Value <= 0 {
+			return invalid("price_cross 的 value 必须大于 0")
+		}
+	case models.AlertPercentMove:
+		if rule.Condition == "" {
+			rule.Condition = CondAny
+		}
+		if err := checkCondition(rule.Condition, CondUp, CondDown, CondAny); err != nil {
+			return err
+		}
+		if rule.Value <= 0 {
+			return invalid("percent_move 的 value（百分比）必须大于 0")
+		}
+		if window, err := time.ParseDuration(rule.Window); err != nil || window <= 0 {
+			return invalid("percent_move 需要设置大于 0 的 window，如 \"1h\"")
+		}
+	case models.AlertIndicator:
+		if rule.Timeframe == "" {
+			return invalid("indicator 规则需要设置 timeframe")
+		}
+		if err := checkCondition(rule.Condition, CondAbove, CondBelow, CondCrossesAbove, CondCrossesBelow); err != nil {
+			return err
+		}
+		if err := normalizeIndicator(&rule.Indicator, &rule.Period, "indicator"); err != nil {
+			return err
+		}
+		if rule.CompareIndicator != "" {
+			if err := normalizeIndicator(&rule.CompareIndicator, &rule.ComparePeriod, "compare_indicator"); err != nil {
+				return err
+			}
+		}
+	case models.AlertVolumeSpike:
+		if rule.Timeframe == "" {
+			return invalid("volume_spike 规则需要设置 timeframe")
+		}
+		if rule.Value <= 0 {
+			return invalid("volume_spike 的 value（平均成交量的倍数）必须大于 0")
+		}
+		if rule.Period == 0 {
+			rule.Period = defaultVolumePeriod
+		}
+		if rule.Period < 1 {
+			return invalid("period 必须大于 0")
+		}
+	default:
+		return invalid("不支持的规则类型 %q，可选值: price_cross, percent_move, indicator, volume_spike", rule.Type)
+	}
+
+	if rule.Mode == "" {
+		rule.Mode = models.AlertModeOnce
+	}
+	switch rule.Mode {
+	case models.AlertModeOnce, models.AlertModeRecur:
+	case models.AlertModeCooldown:
+		if cooldown, err := time.ParseDuration(rule.Cooldown); err != nil || cooldown <= 0 {
+			return invalid("cooldown 模式需要设置大于 0 的 cooldown，如 \"30m\"")
+		}
+	default:
+		return invalid("不支持的模式 %q，可选值: once, recurring, cooldown", rule.Mode)
+	}
+	return nil
+}
+
+func checkCondition(condition string, allowed ...string) error {
+	for _, c := range allowed {
+		if condition == c {
+			return nil
+		}
+	}
+	return invalid("不支持的条件 %q，可选值: %s", condition, strings.Join(allowed, ", "))
+}
+
+func normalizeIndicator(name *string, period *int, field string) error {
+	*name = strings.ToLower(*name)
+	def, ok := indicatorPeriods[*name]
+	if !ok {
+		return invalid("%s 不支持指标 %q，可选值: price, volume, sma, rsi, atr, macd, macd_signal, macd_histogram, bb_upper, bb_middle, bb_lower", field, *name)
+	}
+	if def == 0 {
+		*period = 0
+		return nil
+	}
+	if *period == 0 {
+		*period = def
+	}
+	if *period < 1 {
+		return invalid("%s 的周期必须大于 0", field)
+	}
+	return nil
+}
+
+// series 返回与 bars 对齐的指标序列，数据不足的位置为 NaN
+func series(name string, period int, bars []models.Bar) []float64 {
+	switch name {
+	case "sma":
+		return indicators.NewSimpleMovingAverage(period).Series(bars)
+	case "rsi":
+		return indicators.NewRelativeStrengthIndex(period).Series(bars)
+	case "atr":
+		return indicators.NewAverageTrueRange(period).Series(bars)
+	case "macd", "macd_signal", "macd_histogram":
+		macd, signal := indicators.NewMACD(12, 26, 9).Series(bars)
+		switch name {
+		case "macd":
+			return macd
+		case "macd_signal":
+			return signal
+		}
+		histogram := make([]float64, len(bars))
+		for i := range histogram {
+			histogram[i] = macd[i] - signal[i]
+		}
+		return histogram
+	case "bb_upper", "bb_middle", "bb_lower":
+		upper, middle, lower := indicators.NewBollingerBands(period, 2).Series(bars)
+		switch name {
+		case "bb_upper":
+			return upper
+		case "bb_middle":
+			return middle
+		}
+		return lower
+	}
+	values := make([]float64, len(bars))
+	for i, bar := range bars {
+		if name == "volume" {
+			values[i] = bar.Volume
+		} else {
+			values[i] = bar.Close
+		}
+	}
+	return values
+}
+
+// compare 按条件比较 a 与 b，prevA 和 prevB 为上一次的值，用于判断穿越
+func compare(condition string, prevA, prevB, a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return false
+	}
+	switch condition {
+	case CondAbove:
+		return a > b
+	case CondBelow:
+		return a < b
+	}
+	if math.IsNaN(prevA) || math.IsNaN(prevB) {
+		return false
+	}
+	switch condition {
+	case CondCrossesAbove:
+		return prevA <= prevB && a > b
+	case CondCrossesBelow:
+		return prevA >= prevB && a < b
+	}
+	return false
+}
+
+// moved 按方向判断涨跌幅 change（百分比）是否达到阈值
+func moved(condition string, change, threshold float64) bool {
+	switch condition {
+	case CondUp:
+		return change >= threshold
+	case CondDown:
+		return change <= -threshold
+	}
+	return math.Abs(change) >= threshold
+}
+
+var conditionNames = map[string]string{
+	CondAbove:        "高于",
+	CondBelow:        "低于",
+	CondCrossesAbove: "上穿",
+	CondCrossesBelow: "下穿",
+}
+
+// indicatorLabel 返回 "RSI(14)" 形式的指标名
+func indicatorLabel(name string, period int) string {
+	label := strings.ToUpper(name)
+	if period > 0 {
+		label = fmt.Sprintf("%s(%d)", label, period)
+	}
+	return label
+}
@@ -4,6 +4,7 @@ package analysis
 import (
 	"time"
 
+	"github.com/qqqq/eth-trading-system/internal/analysis/indicators"
 	"github.com/qqqq/eth-trading-system/internal/analysis/support_resistance"
 	"github.com/qqqq/eth-trading-system/internal/analysis/trend"
 	"github.com/qqqq/eth-trading-system/internal/models"
@@ -16,12 +17,17 @@ type AnalysisEngine struct {
 	supportResistanceAnalyzer *support_resistance.SupportResistanceAnalyzer
 }
 
-func NewAnalysisEngine() Engine {
+// NewAnalysisEngine 创建分析引擎，趋势判断使用 maType 类型的短期与长期均线
+func NewAnalysisEngine(maType indicators.MovingAverageType, shortPeriod, longPeriod int) (Engine, error) {
+	trendAnalyzer, err := trend.NewTrendAnalyzerWithType(maType, shortPeriod, longPeriod)
+	if err != nil {
+		return nil, err
+	}
 	return &AnalysisEngine{
 		indicators:                make([]Indicator, 0),
-		trendAnalyzer:             trend.NewTrendAnalyzer(10, 30),
+		trendAnalyzer:             trendAnalyzer,
 		supportResistanceAnalyzer: support_resistance.NewSupportResistanceAnalyzer(20),
-	}
+	}, nil
 }
 
 func (e *AnalysisEngine) AddIndicator(indicator Indicator) {
@@ -20,6 +20,20 @@ func (atr *AverageTrueRange) Calculate(bars []models.Bar) (interface{}, error) {
 		return nil, fmt.Errorf("not enough data for ATR calculation")
 	}
 
+	series := atr.Series(bars)
+	return series[len(series)-1], nil
+}
+
+// Series 返回与 bars 对齐的 ATR 序列（Wilder 平滑），前 Period 个值为 NaN
+func (atr *AverageTrueRange) Series(bars []models.Bar) []float64 {
+	series := make([]float64, len(bars))
+	for i := range series {
+		series[i] = math.NaN()
+	}
+	if len(bars) < atr.Period+1 {
+		return series
+	}
+
 	trueRanges := make([]float64, len(bars)-1)
 	for i := 1; i < len(bars); i++ {
 		high := bars[i].High
@@ -38,12 +52,14 @@ func (atr *AverageTrueRange) Calculate(bars []models.Bar) (interface{}, error) {
 		atrValue += trueRanges[i]
 	}
 	atrValue /= float64(atr.Period)
+	series[atr.Period] = atrValue
 
 	for i := atr.Period; i < len(trueRanges); i++ {
 		atrValue = (atrValue*(float64(atr.Period)-1) + trueRanges[i]) / float64(atr.Period)
+		series[i+1] = atrValue
 	}
 
-	return atrValue, nil
+	return series
 }
 
 func (atr *AverageTrueRange) Name() string {
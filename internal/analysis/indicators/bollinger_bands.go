@@ -48,6 +48,38 @@ func (bb *BollingerBands) Calculate(bars []models.Bar) (interface{}, error) {
 	}, nil
 }
 
+// Series 返回与 bars 对齐的上轨、中轨、下轨序列，前 Period-1 个值为 NaN
+func (bb *BollingerBands) Series(bars []models.Bar) (upper, middle, lower []float64) {
+	upper = make([]float64, len(bars))
+	middle = make([]float64, len(bars))
+	lower = make([]float64, len(bars))
+
+	for i := range bars {
+		if i < bb.Period-1 {
+			upper[i], middle[i], lower[i] = math.NaN(), math.NaN(), math.NaN()
+			continue
+		}
+
+		sum := 0.0
+		for j := i - bb.Period + 1; j <= i; j++ {
+			sum += bars[j].Close
+		}
+		mean := sum / float64(bb.Period)
+
+		variance := 0.0
+		for j := i - bb.Period + 1; j <= i; j++ {
+			variance += math.Pow(bars[j].Close-mean, 2)
+		}
+		stdDev := math.Sqrt(variance / float64(bb.Period))
+
+		middle[i] = mean
+		upper[i] = mean + bb.StdDevs*stdDev
+		lower[i] = mean - bb.StdDevs*stdDev
+	}
+
+	return upper, middle, lower
+}
+
 func (bb *BollingerBands) Name() string {
 	return fmt.Sprintf("BB(%d,%.1f)", bb.Period, bb.StdDevs)
 }
@@ -0,0 +1,39 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// DoubleExponentialMovingAverage 双重指数移动平均：2*EMA - EMA(EMA)，比 EMA 滞后更小
+type DoubleExponentialMovingAverage struct {
+	Period int
+}
+
+func NewDoubleExponentialMovingAverage(period int) *DoubleExponentialMovingAverage {
+	return &DoubleExponentialMovingAverage{Period: period}
+}
+
+func (dema *DoubleExponentialMovingAverage) Calculate(bars []models.Bar) (interface{}, error) {
+	return lastValue(dema.Series(bars), "DEMA")
+}
+
+// Series 返回与 bars 对齐的 DEMA 序列，前 2*(Period-1) 个值为 NaN
+func (dema *DoubleExponentialMovingAverage) Series(bars []models.Bar) []float64 {
+	ema1 := emaOf(closes(bars), dema.Period)
+	ema2 := emaOf(ema1, dema.Period)
+	series := make([]float64, len(bars))
+	for i := range series {
+		series[i] = 2*ema1[i] - ema2[i]
+		if math.IsNaN(ema2[i]) {
+			series[i] = math.NaN()
+		}
+	}
+	return series
+}
+
+func (dema *DoubleExponentialMovingAverage) Name() string {
+	return fmt.Sprintf("DEMA%d", dema.Period)
+}
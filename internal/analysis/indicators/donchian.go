@@ -0,0 +1,57 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+type DonchianChannel struct {
+	Period int
+}
+
+func NewDonchianChannel(period int) *DonchianChannel {
+	return &DonchianChannel{Period: period}
+}
+
+func (dc *DonchianChannel) Calculate(bars []models.Bar) (interface{}, error) {
+	if len(bars) < dc.Period {
+		return nil, fmt.Errorf("not enough data for Donchian Channel calculation")
+	}
+
+	upper, lower := dc.Series(bars)
+	last := len(bars) - 1
+
+	return map[string]float64{
+		"Upper":  upper[last],
+		"Middle": (upper[last] + lower[last]) / 2,
+		"Lower":  lower[last],
+	}, nil
+}
+
+// Series 返回与 bars 对齐的通道上轨（最近 Period 根K线最高价）和下轨（最低价），
+// 前 Period-1 个值为 NaN
+func (dc *DonchianChannel) Series(bars []models.Bar) (upper, lower []float64) {
+	upper = make([]float64, len(bars))
+	lower = make([]float64, len(bars))
+
+	for i := range bars {
+		if i < dc.Period-1 {
+			upper[i], lower[i] = math.NaN(), math.NaN()
+			continue
+		}
+		high, low := bars[i].High, bars[i].Low
+		for j := i - dc.Period + 1; j < i; j++ {
+			high = math.Max(high, bars[j].High)
+			low = math.Min(low, bars[j].Low)
+		}
+		upper[i], lower[i] = high, low
+	}
+
+	return upper, lower
+}
+
+func (dc *DonchianChannel) Name() string {
+	return fmt.Sprintf("Donchian(%d)", dc.Period)
+}
@@ -0,0 +1,29 @@
+package indicators
+
+import (
+	"fmt"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// ExponentialMovingAverage 指数移动平均，以前 Period 根K线的 SMA 作为初始值
+type ExponentialMovingAverage struct {
+	Period int
+}
+
+func NewExponentialMovingAverage(period int) *ExponentialMovingAverage {
+	return &ExponentialMovingAverage{Period: period}
+}
+
+func (ema *ExponentialMovingAverage) Calculate(bars []models.Bar) (interface{}, error) {
+	return lastValue(ema.Series(bars), "EMA")
+}
+
+// Series 返回与 bars 对齐的 EMA 序列，前 Period-1 个值为 NaN
+func (ema *ExponentialMovingAverage) Series(bars []models.Bar) []float64 {
+	return emaOf(closes(bars), ema.Period)
+}
+
+func (ema *ExponentialMovingAverage) Name() string {
+	return fmt.Sprintf("EMA%d", ema.Period)
+}
@@ -0,0 +1,47 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// HullMovingAverage Hull 均线：WMA(2*WMA(n/2) - WMA(n), sqrt(n))，在保持平滑的同时几乎消除滞后
+type HullMovingAverage struct {
+	Period int
+}
+
+func NewHullMovingAverage(period int) *HullMovingAverage {
+	return &HullMovingAverage{Period: period}
+}
+
+func (hma *HullMovingAverage) Calculate(bars []models.Bar) (interface{}, error) {
+	return lastValue(hma.Series(bars), "HMA")
+}
+
+// Series 返回与 bars 对齐的 HMA 序列，前 Period+round(sqrt(Period))-2 个值为 NaN
+func (hma *HullMovingAverage) Series(bars []models.Bar) []float64 {
+	prices := closes(bars)
+	half := wmaOf(prices, max(hma.Period/2, 1))
+	full := wmaOf(prices, hma.Period)
+	diff := make([]float64, len(bars))
+	for i := range diff {
+		diff[i] = 2*half[i] - full[i]
+	}
+
+	// diff 在前 Period-1 个位置为 NaN，平滑时从第一个有效值开始
+	sqrtPeriod := max(int(math.Round(math.Sqrt(float64(hma.Period)))), 1)
+	series := make([]float64, len(bars))
+	for i := range series {
+		series[i] = math.NaN()
+	}
+	if start := hma.Period - 1; start < len(bars) {
+		copy(series[start:], wmaOf(diff[start:], sqrtPeriod))
+	}
+	return series
+}
+
+func (hma *HullMovingAverage) Name() string {
+	return fmt.Sprintf("HMA%d", hma.Period)
+}
@@ -0,0 +1,61 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// KaufmanAdaptiveMovingAverage Kaufman 自适应均线：按效率比（Period 根K线的净变化 / 逐根变化之和）
+// 在快慢两条 EMA 的平滑系数之间调整，趋势明确时跟随价格，震荡时趋于平缓
+type KaufmanAdaptiveMovingAverage struct {
+	Period     int
+	FastPeriod int
+	SlowPeriod int
+}
+
+func NewKaufmanAdaptiveMovingAverage(period, fastPeriod, slowPeriod int) *KaufmanAdaptiveMovingAverage {
+	return &KaufmanAdaptiveMovingAverage{
+		Period:     period,
+		FastPeriod: fastPeriod,
+		SlowPeriod: slowPeriod,
+	}
+}
+
+func (kama *KaufmanAdaptiveMovingAverage) Calculate(bars []models.Bar) (interface{}, error) {
+	return lastValue(kama.Series(bars), "KAMA")
+}
+
+// Series 返回与 bars 对齐的 KAMA 序列，以第 Period 根K线的收盘价为初始值，前 Period-1 个值为 NaN
+func (kama *KaufmanAdaptiveMovingAverage) Series(bars []models.Bar) []float64 {
+	series := make([]float64, len(bars))
+	fast := 2.0 / float64(kama.FastPeriod+1)
+	slow := 2.0 / float64(kama.SlowPeriod+1)
+
+	for i := range bars {
+		switch {
+		case i < kama.Period-1:
+			series[i] = math.NaN()
+		case i == kama.Period-1:
+			series[i] = bars[i].Close
+		default:
+			change := math.Abs(bars[i].Close - bars[i-kama.Period].Close)
+			volatility := 0.0
+			for j := i - kama.Period + 1; j <= i; j++ {
+				volatility += math.Abs(bars[j].Close - bars[j-1].Close)
+			}
+			er := 0.0
+			if volatility > 0 {
+				er = change / volatility
+			}
+			sc := math.Pow(er*(fast-slow)+slow, 2)
+			series[i] = series[i-1] + sc*(bars[i].Close-series[i-1])
+		}
+	}
+	return series
+}
+
+func (kama *KaufmanAdaptiveMovingAverage) Name() string {
+	return fmt.Sprintf("KAMA(%d,%d,%d)", kama.Period, kama.FastPeriod, kama.SlowPeriod)
+}
@@ -2,6 +2,7 @@ package indicators
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/qqqq/eth-trading-system/internal/models"
 )
@@ -20,40 +21,35 @@ func NewMACD(fastPeriod, slowPeriod, signalPeriod int) *MACD {
 	}
 }
 
+// Calculate 返回最后一根K线上的 MACD 线、信号线和柱，至少需要 SlowPeriod+SignalPeriod-1 根K线
 func (macd *MACD) Calculate(bars []models.Bar) (interface{}, error) {
-	if len(bars) < macd.SlowPeriod {
+	macdLine, signalLine := macd.Series(bars)
+	if len(bars) == 0 || math.IsNaN(signalLine[len(signalLine)-1]) {
 		return nil, fmt.Errorf("not enough data for MACD calculation")
 	}
 
-	fastEMA := calculateEMA(bars, macd.FastPeriod)
-	slowEMA := calculateEMA(bars, macd.SlowPeriod)
-
-	macdLine := fastEMA[len(fastEMA)-1] - slowEMA[len(slowEMA)-1]
-	signalLine := calculateEMA(bars[len(bars)-len(slowEMA):], macd.SignalPeriod)[macd.SignalPeriod-1]
-	histogram := macdLine - signalLine
-
+	last := len(bars) - 1
 	return map[string]float64{
-		"MACD":      macdLine,
-		"Signal":    signalLine,
-		"Histogram": histogram,
+		"MACD":      macdLine[last],
+		"Signal":    signalLine[last],
+		"Histogram": macdLine[last] - signalLine[last],
 	}, nil
 }
 
-func (macd *MACD) Name() string {
-	return fmt.Sprintf("MACD(%d,%d,%d)", macd.FastPeriod, macd.SlowPeriod, macd.SignalPeriod)
-}
-
-func calculateEMA(bars []models.Bar, period int) []float64 {
-	ema := make([]float64, len(bars))
-	k := 2.0 / float64(period+1)
+// Series 返回与 bars 对齐的 MACD 线和信号线，信号线为 MACD 线的 EMA。
+// EMA 与 EMA 指标一致以 SMA 作为初始值，预热期内为 NaN
+func (macd *MACD) Series(bars []models.Bar) ([]float64, []float64) {
+	values := closes(bars)
+	fastEMA := emaOf(values, macd.FastPeriod)
+	slowEMA := emaOf(values, macd.SlowPeriod)
 
+	macdLine := make([]float64, len(bars))
 	for i := range bars {
-		if i == 0 {
-			ema[i] = bars[i].Close
-		} else {
-			ema[i] = bars[i].Close*k + ema[i-1]*(1-k)
-		}
+		macdLine[i] = fastEMA[i] - slowEMA[i]
 	}
+	return macdLine, emaOf(macdLine, macd.SignalPeriod)
+}
 
-	return ema
+func (macd *MACD) Name() string {
+	return fmt.Sprintf("MACD(%d,%d,%d)", macd.FastPeriod, macd.SlowPeriod, macd.SignalPeriod)
 }
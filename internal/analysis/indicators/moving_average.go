@@ -0,0 +1,117 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// MovingAverageType 均线类型，用于在配置中按类型选择均线
+type MovingAverageType string
+
+const (
+	MovingAverageSMA  MovingAverageType = "sma"
+	MovingAverageEMA  MovingAverageType = "ema"
+	MovingAverageWMA  MovingAverageType = "wma"
+	MovingAverageDEMA MovingAverageType = "dema"
+	MovingAverageTEMA MovingAverageType = "tema"
+	MovingAverageHMA  MovingAverageType = "hma"
+	MovingAverageKAMA MovingAverageType = "kama"
+)
+
+// MovingAverageTypes 全部支持的均线类型
+var MovingAverageTypes = []MovingAverageType{
+	MovingAverageSMA, MovingAverageEMA, MovingAverageWMA, MovingAverageDEMA,
+	MovingAverageTEMA, MovingAverageHMA, MovingAverageKAMA,
+}
+
+// MovingAverage 基于收盘价的均线，Calculate 返回最后一根K线上的 float64 值，
+// Series 返回与 bars 对齐的序列，预热期内为 NaN
+type MovingAverage interface {
+	Calculate(bars []models.Bar) (interface{}, error)
+	Series(bars []models.Bar) []float64
+	Name() string
+}
+
+// NewMovingAverage 按类型创建周期为 period 的均线，KAMA 使用默认的快慢周期 2 和 30
+func NewMovingAverage(maType MovingAverageType, period int) (MovingAverage, error) {
+	if period <= 0 {
+		return nil, fmt.Errorf("均线周期必须大于 0")
+	}
+	switch maType {
+	case MovingAverageSMA:
+		return NewSimpleMovingAverage(period), nil
+	case MovingAverageEMA:
+		return NewExponentialMovingAverage(period), nil
+	case MovingAverageWMA:
+		return NewWeightedMovingAverage(period), nil
+	case MovingAverageDEMA:
+		return NewDoubleExponentialMovingAverage(period), nil
+	case MovingAverageTEMA:
+		return NewTripleExponentialMovingAverage(period), nil
+	case MovingAverageHMA:
+		return NewHullMovingAverage(period), nil
+	case MovingAverageKAMA:
+		return NewKaufmanAdaptiveMovingAverage(period, 2, 30), nil
+	}
+	return nil, fmt.Errorf("不支持的均线类型 %q，可选: %v", maType, MovingAverageTypes)
+}
+
+// lastValue 返回序列的最后一个值，数据不足（为空或仍在预热期）时返回错误
+func lastValue(series []float64, name string) (interface{}, error) {
+	if len(series) == 0 || math.IsNaN(series[len(series)-1]) {
+		return nil, fmt.Errorf("not enough data for %s calculation", name)
+	}
+	return series[len(series)-1], nil
+}
+
+func closes(bars []models.Bar) []float64 {
+	values := make([]float64, len(bars))
+	for i, bar := range bars {
+		values[i] = bar.Close
+	}
+	return values
+}
+
+// emaOf 计算 values 的 EMA，以前 period 个有效值的简单平均作为初始值。
+// values 开头的 NaN（上一级指标的预热期）会被跳过，结果在预热期内为 NaN
+func emaOf(values []float64, period int) []float64 {
+	ema := make([]float64, len(values))
+	k := 2.0 / float64(period+1)
+	sum, count := 0.0, 0
+	for i, v := range values {
+		switch {
+		case math.IsNaN(v):
+			ema[i] = math.NaN()
+		case count >= period:
+			ema[i] = v*k + ema[i-1]*(1-k)
+		default:
+			sum += v
+			count++
+			ema[i] = math.NaN()
+			if count == period {
+				ema[i] = sum / float64(period)
+			}
+		}
+	}
+	return ema
+}
+
+// wmaOf 计算 values 的线性加权均线，最近的值权重为 period，窗口未满或含 NaN 时为 NaN
+func wmaOf(values []float64, period int) []float64 {
+	wma := make([]float64, len(values))
+	weights := float64(period*(period+1)) / 2
+	for i := range values {
+		wma[i] = math.NaN()
+		if i < period-1 {
+			continue
+		}
+		sum := 0.0
+		for j := 0; j < period; j++ {
+			sum += values[i-j] * float64(period-j)
+		}
+		wma[i] = sum / weights
+	}
+	return wma
+}
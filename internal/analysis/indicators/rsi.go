@@ -2,6 +2,7 @@ package indicators
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/qqqq/eth-trading-system/internal/models"
 )
@@ -21,6 +22,20 @@ func (rsi *RelativeStrengthIndex) Calculate(bars []models.Bar) (interface{}, err
 		return nil, fmt.Errorf("not enough data for RSI calculation, need at least %d bars", rsi.Period+1)
 	}
 
+	series := rsi.Series(bars)
+	return series[len(series)-1], nil
+}
+
+// Series 返回与 bars 对齐的 RSI 序列，前 Period 个值为 NaN
+func (rsi *RelativeStrengthIndex) Series(bars []models.Bar) []float64 {
+	series := make([]float64, len(bars))
+	for i := range series {
+		series[i] = math.NaN()
+	}
+	if len(bars) < rsi.Period+1 {
+		return series
+	}
+
 	var gains, losses float64
 	for i := 1; i <= rsi.Period; i++ {
 		change := bars[i].Close - bars[i-1].Close
@@ -33,6 +48,7 @@ func (rsi *RelativeStrengthIndex) Calculate(bars []models.Bar) (interface{}, err
 
 	avgGain := gains / float64(rsi.Period)
 	avgLoss := losses / float64(rsi.Period)
+	series[rsi.Period] = rsiValue(avgGain, avgLoss)
 
 	for i := rsi.Period + 1; i < len(bars); i++ {
 		change := bars[i].Close - bars[i-1].Close
@@ -43,16 +59,19 @@ func (rsi *RelativeStrengthIndex) Calculate(bars []models.Bar) (interface{}, err
 			avgGain = (avgGain*float64(rsi.Period-1) + 0) / float64(rsi.Period)
 			avgLoss = (avgLoss*float64(rsi.Period-1) - change) / float64(rsi.Period)
 		}
+		series[i] = rsiValue(avgGain, avgLoss)
 	}
 
+	return series
+}
+
+func rsiValue(avgGain, avgLoss float64) float64 {
 	if avgLoss == 0 {
-		return 100.0, nil
+		return 100.0
 	}
 
 	rs := avgGain / avgLoss
-	rsiValue := 100 - (100 / (1 + rs))
-
-	return rsiValue, nil
+	return 100 - (100 / (1 + rs))
 }
 
 func (rsi *RelativeStrengthIndex) Name() string {
@@ -2,6 +2,7 @@ package indicators
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/qqqq/eth-trading-system/internal/models"
 )
@@ -25,6 +26,24 @@ func (sma *SimpleMovingAverage) Calculate(bars []models.Bar) (interface{}, error
 	return sum / float64(sma.Period), nil
 }
 
+// Series 返回与 bars 对齐的 SMA 序列，前 Period-1 个值为 NaN
+func (sma *SimpleMovingAverage) Series(bars []models.Bar) []float64 {
+	series := make([]float64, len(bars))
+	sum := 0.0
+	for i, bar := range bars {
+		sum += bar.Close
+		if i >= sma.Period {
+			sum -= bars[i-sma.Period].Close
+		}
+		if i < sma.Period-1 {
+			series[i] = math.NaN()
+		} else {
+			series[i] = sum / float64(sma.Period)
+		}
+	}
+	return series
+}
+
 func (sma *SimpleMovingAverage) Name() string {
 	return fmt.Sprintf("SMA%d", sma.Period)
 }
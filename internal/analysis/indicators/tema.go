@@ -0,0 +1,40 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// TripleExponentialMovingAverage 三重指数移动平均：3*EMA - 3*EMA(EMA) + EMA(EMA(EMA))
+type TripleExponentialMovingAverage struct {
+	Period int
+}
+
+func NewTripleExponentialMovingAverage(period int) *TripleExponentialMovingAverage {
+	return &TripleExponentialMovingAverage{Period: period}
+}
+
+func (tema *TripleExponentialMovingAverage) Calculate(bars []models.Bar) (interface{}, error) {
+	return lastValue(tema.Series(bars), "TEMA")
+}
+
+// Series 返回与 bars 对齐的 TEMA 序列，前 3*(Period-1) 个值为 NaN
+func (tema *TripleExponentialMovingAverage) Series(bars []models.Bar) []float64 {
+	ema1 := emaOf(closes(bars), tema.Period)
+	ema2 := emaOf(ema1, tema.Period)
+	ema3 := emaOf(ema2, tema.Period)
+	series := make([]float64, len(bars))
+	for i := range series {
+		series[i] = 3*ema1[i] - 3*ema2[i] + ema3[i]
+		if math.IsNaN(ema3[i]) {
+			series[i] = math.NaN()
+		}
+	}
+	return series
+}
+
+func (tema *TripleExponentialMovingAverage) Name() string {
+	return fmt.Sprintf("TEMA%d", tema.Period)
+}
@@ -0,0 +1,29 @@
+package indicators
+
+import (
+	"fmt"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// WeightedMovingAverage 线性加权移动平均，最近一根K线权重为 Period，依次递减到 1
+type WeightedMovingAverage struct {
+	Period int
+}
+
+func NewWeightedMovingAverage(period int) *WeightedMovingAverage {
+	return &WeightedMovingAverage{Period: period}
+}
+
+func (wma *WeightedMovingAverage) Calculate(bars []models.Bar) (interface{}, error) {
+	return lastValue(wma.Series(bars), "WMA")
+}
+
+// Series 返回与 bars 对齐的 WMA 序列，前 Period-1 个值为 NaN
+func (wma *WeightedMovingAverage) Series(bars []models.Bar) []float64 {
+	return wmaOf(closes(bars), wma.Period)
+}
+
+func (wma *WeightedMovingAverage) Name() string {
+	return fmt.Sprintf("WMA%d", wma.Period)
+}
@@ -1,38 +1,72 @@
 package trend
 
 import (
+	"fmt"
+
 	"github.com/qqqq/eth-trading-system/internal/analysis/indicators"
 	"github.com/qqqq/eth-trading-system/internal/models"
 )
 
+// TrendAnalyzer 比较短期与长期均线判断趋势，默认使用 SMA
 type TrendAnalyzer struct {
-	ShortSMA *indicators.SimpleMovingAverage
-	LongSMA  *indicators.SimpleMovingAverage
+	ShortMA indicators.MovingAverage
+	LongMA  indicators.MovingAverage
 }
 
 func NewTrendAnalyzer(shortPeriod, longPeriod int) *TrendAnalyzer {
 	return &TrendAnalyzer{
-		ShortSMA: indicators.NewSimpleMovingAverage(shortPeriod),
-		LongSMA:  indicators.NewSimpleMovingAverage(longPeriod),
+		ShortMA: indicators.NewSimpleMovingAverage(shortPeriod),
+		LongMA:  indicators.NewSimpleMovingAverage(longPeriod),
+	}
+}
+
+// NewTrendAnalyzerWithType 使用指定类型的均线创建趋势分析器，例如 EMA 或 HMA
+func NewTrendAnalyzerWithType(maType indicators.MovingAverageType, shortPeriod, longPeriod int) (*TrendAnalyzer, error) {
+	shortMA, err := indicators.NewMovingAverage(maType, shortPeriod)
+	if err != nil {
+		return nil, err
+	}
+	longMA, err := indicators.NewMovingAverage(maType, longPeriod)
+	if err != nil {
+		return nil, err
+	}
+	if shortPeriod >= longPeriod {
+		return nil, fmt.Errorf("短期均线周期(%d) 必须小于长期均线周期(%d)", shortPeriod, longPeriod)
 	}
+	return &TrendAnalyzer{ShortMA: shortMA, LongMA: longMA}, nil
 }
 
 func (ta *TrendAnalyzer) AnalyzeTrend(bars []models.Bar) (string, error) {
-	shortSMA, err := ta.ShortSMA.Calculate(bars)
+	shortMA, err := ta.ShortMA.Calculate(bars)
 	if err != nil {
 		return "", err
 	}
 
-	longSMA, err := ta.LongSMA.Calculate(bars)
+	longMA, err := ta.LongMA.Calculate(bars)
 	if err != nil {
 		return "", err
 	}
 
-	if shortSMA.(float64) > longSMA.(float64) {
+	if shortMA.(float64) > longMA.(float64) {
 		return "Uptrend", nil
-	} else if shortSMA.(float64) < longSMA.(float64) {
+	} else if shortMA.(float64) < longMA.(float64) {
 		return "Downtrend", nil
 	} else {
 		return "Neutral", nil
 	}
 }
+
+// Strength 返回短期均线相对长期均线的偏离百分比，正值表示上升趋势，绝对值越大趋势越强
+func (ta *TrendAnalyzer) Strength(bars []models.Bar) (float64, error) {
+	shortMA, err := ta.ShortMA.Calculate(bars)
+	if err != nil {
+		return 0, err
+	}
+
+	longMA, err := ta.LongMA.Calculate(bars)
+	if err != nil {
+		return 0, err
+	}
+
+	return (shortMA.(float64) - longMA.(float64)) / longMA.(float64) * 100, nil
+}
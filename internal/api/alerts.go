@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/qqqq/eth-trading-system/internal/alerts"
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// defaultAlertHistoryLimit 未指定 limit 时返回的触发记录数量
+const defaultAlertHistoryLimit = 100
+
+// AlertHandler 提供告警规则的增删改查和触发记录查询
+type AlertHandler struct {
+	engine *alerts.Engine
+}
+
+func NewAlertHandler(engine *alerts.Engine) *AlertHandler {
+	return &AlertHandler{engine: engine}
+}
+
+// Rules 处理 /api/alerts：GET 返回全部规则，POST 创建规则（enabled 默认为 true）
+func (h *AlertHandler) Rules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		rules := h.engine.Rules()
+		respondWithJSON(w, http.StatusOK, rules)
+	case http.MethodPost:
+		rule, ok := decodeRule(w, r)
+		if !ok {
+			return
+		}
+		created, err := h.engine.CreateRule(rule)
+		if err != nil {
+			respondWithRuleError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusCreated, created)
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "仅支持 GET 和 POST 请求")
+	}
+}
+
+// Rule 处理 /api/alerts/{id}：GET 返回规则，PUT 替换规则，DELETE 删除规则
+func (h *AlertHandler) Rule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/alerts/"), 10, 64)
+	if err != nil || id <= 0 {
+		respondWithError(w, http.StatusBadRequest, "无效的规则 ID")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		rule := h.engine.Rule(id)
+		if rule == nil {
+			respondWithError(w, http.StatusNotFound, "规则不存在")
+			return
+		}
+		respondWithJSON(w, http.StatusOK, rule)
+	case http.MethodPut:
+		rule, ok := decodeRule(w, r)
+		if !ok {
+			return
+		}
+		updated, err := h.engine.UpdateRule(id, rule)
+		if err != nil {
+			respondWithRuleError(w, err)
+			return
+		}
+		if updated == nil {
+			respondWithError(w, http.StatusNotFound, "规则不存在")
+			return
+		}
+		respondWithJSON(w, http.StatusOK, updated)
+	case http.MethodDelete:
+		found, err := h.engine.DeleteRule(id)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "删除规则失败")
+			return
+		}
+		if !found {
+			respondWithError(w, http.StatusNotFound, "规则不存在")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "仅支持 GET、PUT 和 DELETE 请求")
+	}
+}
+
+// History 返回触发记录，可选参数 rule_id 和 limit（默认 100）
+func (h *AlertHandler) History(w http.ResponseWriter, r *http.Request) {
+	var ruleID int64
+	if v := r.URL.Query().Get("rule_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			respondWithError(w, http.StatusBadRequest, "无效的 rule_id")
+			return
+		}
+		ruleID = id
+	}
+	limit := defaultAlertHistoryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			respondWithError(w, http.StatusBadRequest, "limit 必须为正整数")
+			return
+		}
+		limit = n
+	}
+
+	triggers, err := h.engine.History(ruleID, limit)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "获取告警记录失败")
+		return
+	}
+	if triggers == nil {
+		triggers = []models.AlertTrigger{}
+	}
+	respondWithJSON(w, http.StatusOK, triggers)
+}
+
+func decodeRule(w http.ResponseWriter, r *http.Request) (models.AlertRule, bool) {
+	rule := models.AlertRule{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		respondWithError(w, http.StatusBadRequest, "请求体格式错误")
+		return rule, false
+	}
+	return rule, true
+}
+
+func respondWithRuleError(w http.ResponseWriter, err error) {
+	var invalid *alerts.InvalidRuleError
+	if errors.As(err, &invalid) {
+		respondWithError(w, http.StatusBadRequest, invalid.Message)
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, "保存规则失败")
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/qqqq/eth-trading-system/internal/dsl"
+)
+
+// ExpressionHandler 校验策略规则表达式并列出可用函数
+type ExpressionHandler struct{}
+
+func NewExpressionHandler() *ExpressionHandler {
+	return &ExpressionHandler{}
+}
+
+// validateRequest 二选一：rules 为 expression 策略的规则集，expression 为单个表达式
+type validateRequest struct {
+	Rules      string `json:"rules"`
+	Expression string `json:"expression"`
+}
+
+type validatedRule struct {
+	Action    string  `json:"action"`
+	Condition string  `json:"condition"`
+	Pos       dsl.Pos `json:"pos"`
+}
+
+type validateResponse struct {
+	Valid bool       `json:"valid"`
+	Error *dsl.Error `json:"error,omitempty"`
+	// Type 单个表达式的结果类型
+	Type  string          `json:"type,omitempty"`
+	Rules []validatedRule `json:"rules,omitempty"`
+}
+
+// Validate 解析并检查表达式。表达式有误时仍返回 200，error 中给出行号、列号和字节偏移
+func (h *ExpressionHandler) Validate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "仅支持 POST 请求")
+		return
+	}
+	var req validateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "请求体不是有效的 JSON")
+		return
+	}
+	if (req.Rules == "") == (req.Expression == "") {
+		respondWithError(w, http.StatusBadRequest, "需要且只能提供 rules 或 expression 之一")
+		return
+	}
+
+	var resp validateResponse
+	var err error
+	if req.Rules != "" {
+		var rules []dsl.Rule
+		if rules, err = dsl.CompileRules(req.Rules); err == nil {
+			for _, rule := range rules {
+				resp.Rules = append(resp.Rules, validatedRule{Action: rule.Action, Condition: rule.Condition.Source(), Pos: rule.Pos})
+			}
+		}
+	} else {
+		var program *dsl.Program
+		if program, err = dsl.Compile(req.Expression); err == nil {
+			resp.Type = program.Type().String()
+		}
+	}
+
+	if err != nil {
+		var dslErr *dsl.Error
+		if !errors.As(err, &dslErr) {
+			respondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		resp.Error = dslErr
+	}
+	resp.Valid = err == nil
+	respondWithJSON(w, http.StatusOK, resp)
+}
+
+// Functions 返回表达式中可用的内置函数
+func (h *ExpressionHandler) Functions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "仅支持 GET 请求")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, dsl.Functions())
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/qqqq/eth-trading-system/internal/notify"
+)
+
+// NotifyHandler 提供通知渠道测试
+type NotifyHandler struct {
+	notifier *notify.Notifier
+}
+
+func NewNotifyHandler(notifier *notify.Notifier) *NotifyHandler {
+	return &NotifyHandler{notifier: notifier}
+}
+
+// Test 向所有启用的渠道发送测试通知，返回各渠道的发送结果
+func (h *NotifyHandler) Test(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "仅支持 POST 请求")
+		return
+	}
+	results := h.notifier.Test()
+	if results == nil {
+		results = []notify.TestResult{}
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+	"github.com/qqqq/eth-trading-system/internal/portfolio"
+	"github.com/qqqq/eth-trading-system/internal/utils"
+)
+
+// defaultEquityRange 未指定 start 时查询的权益历史范围
+const defaultEquityRange = 30 * 24 * time.Hour
+
+// PortfolioHandler 提供账户持仓和权益历史查询
+type PortfolioHandler struct {
+	ledger *portfolio.Ledger
+}
+
+func NewPortfolioHandler(ledger *portfolio.Ledger) *PortfolioHandler {
+	return &PortfolioHandler{ledger: ledger}
+}
+
+// GetPortfolio 返回按最新价格估值的现金、持仓和盈亏
+func (h *PortfolioHandler) GetPortfolio(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, h.ledger.Summary())
+}
+
+// GetEquityHistory 返回权益快照，start 和 end 为 RFC3339 格式，默认为最近 30 天
+func (h *PortfolioHandler) GetEquityHistory(w http.ResponseWriter, r *http.Request) {
+	end := time.Now().UTC()
+	if v := r.URL.Query().Get("end"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "end 格式应为 RFC3339")
+			return
+		}
+		end = t.UTC()
+	}
+	start := end.Add(-defaultEquityRange)
+	if v := r.URL.Query().Get("start"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "start 格式应为 RFC3339")
+			return
+		}
+		start = t.UTC()
+	}
+	if start.After(end) {
+		respondWithError(w, http.StatusBadRequest, "start 不能晚于 end")
+		return
+	}
+
+	snapshots, err := h.ledger.EquityHistory(start, end)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "获取权益历史失败")
+		return
+	}
+	if snapshots == nil {
+		snapshots = []models.EquitySnapshot{}
+	}
+	respondWithJSON(w, http.StatusOK, snapshots)
+}
+
+// GetGains 返回某一年度按批次计算的已实现损益。year 默认为当前年份，method 可选 fifo, lifo, hifo，
+// 默认 fifo；format=csv 时以 Form 8949 格式下载。指定批次方式需要逐笔选择，请使用 cmd/taxreport
+func (h *PortfolioHandler) GetGains(w http.ResponseWriter, r *http.Request) {
+	year := time.Now().UTC().Year()
+	if v := r.URL.Query().Get("year"); v != "" {
+		y, err := strconv.Atoi(v)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "year 必须为整数")
+			return
+		}
+		year = y
+	}
+	method := r.URL.Query().Get("method")
+	if method == "" {
+		method = portfolio.LotFIFO
+	}
+	if method == portfolio.LotSpecific {
+		respondWithError(w, http.StatusBadRequest, "指定批次方式请使用 cmd/taxreport 并提供批次选择文件")
+		return
+	}
+
+	fills, err := h.ledger.Fills()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "读取成交记录失败")
+		return
+	}
+	result, err := portfolio.MatchLots(fills, method, nil)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	report := portfolio.NewGainsReport(result, year)
+
+	if r.URL.Query().Get("format") != "csv" {
+		respondWithJSON(w, http.StatusOK, report)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=form8949_%d_%s.csv", year, method))
+	if err := report.WriteForm8949CSV(w); err != nil {
+		utils.Log.WithError(err).Error("输出 Form 8949 CSV 失败")
+	}
+}
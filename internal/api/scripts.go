@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/qqqq/eth-trading-system/internal/scripting"
+)
+
+// ScriptHandler 提供脚本策略的加载状态
+type ScriptHandler struct {
+	manager *scripting.Manager
+}
+
+func NewScriptHandler(manager *scripting.Manager) *ScriptHandler {
+	return &ScriptHandler{manager: manager}
+}
+
+// GetScripts 返回脚本目录中每个文件的加载状态和错误
+func (h *ScriptHandler) GetScripts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "仅支持 GET 请求")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, h.manager.Status())
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/qqqq/eth-trading-system/internal/services"
+)
+
+// TradingHandler 提供风控状态查询、熔断开关和保护性订单查询
+type TradingHandler struct {
+	tradingService *services.TradingService
+}
+
+func NewTradingHandler(tradingService *services.TradingService) *TradingHandler {
+	return &TradingHandler{tradingService: tradingService}
+}
+
+// GetRiskStatus 返回当前的风控状态
+func (h *TradingHandler) GetRiskStatus(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, h.tradingService.RiskStatus())
+}
+
+// Kill 触发熔断，请求体 {"reason": "...", "flatten": true} 中 flatten 表示是否平掉全部持仓
+func (h *TradingHandler) Kill(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "仅支持 POST 请求")
+		return
+	}
+	var req struct {
+		Reason  string `json:"reason"`
+		Flatten bool   `json:"flatten"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "请求体格式错误")
+			return
+		}
+	}
+	if req.Reason == "" {
+		req.Reason = "通过 API 触发熔断"
+	}
+	h.tradingService.Kill(req.Reason, req.Flatten)
+	respondWithJSON(w, http.StatusOK, h.tradingService.RiskStatus())
+}
+
+// Resume 解除通过 API 触发的熔断
+func (h *TradingHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "仅支持 POST 请求")
+		return
+	}
+	if err := h.tradingService.Resume(); err != nil {
+		respondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusOK, h.tradingService.RiskStatus())
+}
+
+// GetProtectiveOrders 返回当前持仓的保护性订单及其对应的券商原生订单
+func (h *TradingHandler) GetProtectiveOrders(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, h.tradingService.ProtectiveOrders())
+}
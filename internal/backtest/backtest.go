@@ -0,0 +1,263 @@
+// Package backtest 在历史K线上回放策略信号，模拟成交并统计绩效
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/analysis"
+	"github.com/qqqq/eth-trading-system/internal/models"
+	"github.com/qqqq/eth-trading-system/internal/strategy"
+)
+
+// Config 回测参数
+type Config struct {
+	InitialCapital float64
+	// FeeRate 按成交额收取的手续费率，例如 0.001 表示 0.1%
+	FeeRate float64
+	// Slippage 成交价相对开盘价的不利滑点比例
+	Slippage float64
+	// PositionFraction 策略未给出建议数量（或未启用 UseSignalSize）时每次开仓使用的权益比例
+	PositionFraction float64
+	UseSignalSize    bool
+	AllowShort       bool
+	// Lookback 每次评估传给策略的最多K线数量，0 表示使用截至当前的全部历史
+	Lookback int
+	// Engine 不为 nil 时每根K线都会重新分析，供依赖分析结果的策略使用
+	Engine analysis.Engine
+	// TradeFrom 之前的K线只作为指标预热数据，不评估信号也不计入权益曲线
+	TradeFrom time.Time
+}
+
+// DefaultConfig 返回默认回测参数：初始资金 10000，手续费 0.1%，全仓做多
+func DefaultConfig() Config {
+	return Config{
+		InitialCapital:   10000,
+		FeeRate:          0.001,
+		PositionFraction: 1,
+	}
+}
+
+// Trade 一笔完整的开平仓交易
+type Trade struct {
+	Direction  int // 1 多头，-1 空头
+	EntryTime  time.Time
+	ExitTime   time.Time
+	EntryPrice float64
+	ExitPrice  float64
+	Quantity   float64
+	Fees       float64
+	PnL        float64
+	// ReturnPct 扣除手续费后相对开仓成交额的收益率（百分比）
+	ReturnPct float64
+	// EntryEquity 开仓前的账户权益
+	EntryEquity float64
+	BarsHeld    int
+	ExitReason  string
+}
+
+// EquityPoint 权益曲线上的一个点，按K线收盘价计算
+type EquityPoint struct {
+	Timestamp time.Time
+	Equity    float64
+}
+
+// Result 回测结果
+type Result struct {
+	Timeframe string
+	Trades    []Trade
+	Equity    []EquityPoint
+	Metrics   Metrics
+}
+
+type position struct {
+	direction  int
+	quantity   float64
+	entryPrice float64
+	entryFee   float64
+	entryTime  time.Time
+	entryIndex int
+	stopLoss   float64
+	takeProfit float64
+	equity     float64
+}
+
+// Run 在 bars 上回测策略。第 i 根K线收盘时评估得到的信号在第 i+1 根K线开盘成交，
+// 持仓期间按信号给出的止损/止盈价在K线内检查，回测结束时以最后收盘价平仓
+func Run(s strategy.Strategy, bars []models.Bar, timeframe string, cfg Config) (*Result, error) {
+	if len(bars) < 2 {
+		return nil, fmt.Errorf("回测至少需要 2 根K线")
+	}
+	if cfg.InitialCapital <= 0 {
+		return nil, fmt.Errorf("初始资金必须大于 0")
+	}
+	if cfg.PositionFraction <= 0 || cfg.PositionFraction > 1 {
+		return nil, fmt.Errorf("positionFraction 必须在 (0, 1] 范围内")
+	}
+
+	r := &runner{cfg: cfg, cash: cfg.InitialCapital, result: &Result{Timeframe: timeframe}}
+	var pending *models.TradeSignal
+
+	for i, bar := range bars {
+		if bar.Timestamp.Before(cfg.TradeFrom) {
+			continue
+		}
+		if pending != nil {
+			r.execute(pending, bars, i)
+			pending = nil
+		}
+		r.checkProtective(bar, i)
+		r.result.Equity = append(r.result.Equity, EquityPoint{Timestamp: bar.Timestamp, Equity: r.equity(bar.Close)})
+
+		if i == len(bars)-1 {
+			break
+		}
+		window := bars[:i+1]
+		if cfg.Lookback > 0 && len(window) > cfg.Lookback {
+			window = window[len(window)-cfg.Lookback:]
+		}
+		signal := r.evaluate(s, window, timeframe)
+		if signal != nil && signal.Action.IsTrade() {
+			pending = signal
+		}
+	}
+
+	if len(r.result.Equity) == 0 {
+		return nil, fmt.Errorf("tradeFrom 之后没有K线")
+	}
+	last := bars[len(bars)-1]
+	if r.pos != nil {
+		r.close(last.Close, last.Timestamp, len(bars)-1, "回测结束")
+		r.result.Equity[len(r.result.Equity)-1].Equity = r.cash
+	}
+
+	r.result.Metrics = computeMetrics(r.result, cfg.InitialCapital)
+	return r.result, nil
+}
+
+type runner struct {
+	cfg    Config
+	cash   float64
+	pos    *position
+	result *Result
+}
+
+func (r *runner) evaluate(s strategy.Strategy, window []models.Bar, timeframe string) *models.TradeSignal {
+	var analysisResult *models.AnalysisResult
+	if r.cfg.Engine != nil {
+		if res, err := r.cfg.Engine.Analyze(window); err == nil {
+			analysisResult = res
+		}
+	}
+	return strategy.EvaluateInContext(s, strategy.NewMultiTimeframeContext(timeframe, window, analysisResult))
+}
+
+func (r *runner) equity(price float64) float64 {
+	if r.pos == nil {
+		return r.cash
+	}
+	return r.cash + float64(r.pos.direction)*r.pos.quantity*price
+}
+
+// execute 在第 i 根K线开盘执行信号：反向持仓时平仓，空仓时开仓，同向持仓时忽略
+func (r *runner) execute(signal *models.TradeSignal, bars []models.Bar, i int) {
+	bar := bars[i]
+	direction := 1
+	if signal.Action == models.ActionSell {
+		direction = -1
+	}
+
+	if r.pos != nil {
+		if r.pos.direction != direction {
+			r.close(r.fillPrice(bar.Open, direction), bar.Timestamp, i, "反向信号")
+		}
+		return
+	}
+	if direction == -1 && !r.cfg.AllowShort {
+		return
+	}
+
+	price := r.fillPrice(bar.Open, direction)
+	quantity := r.cash * r.cfg.PositionFraction / (price * (1 + r.cfg.FeeRate))
+	if r.cfg.UseSignalSize && signal.Size > 0 && signal.Size < quantity {
+		quantity = signal.Size
+	}
+	if quantity <= 0 {
+		return
+	}
+
+	// 止损/止盈必须位于成交价的正确一侧，否则忽略
+	stopLoss, takeProfit := signal.StopLoss, signal.TakeProfit
+	if stopLoss > 0 && float64(direction)*(price-stopLoss) <= 0 {
+		stopLoss = 0
+	}
+	if takeProfit > 0 && float64(direction)*(takeProfit-price) <= 0 {
+		takeProfit = 0
+	}
+
+	fee := quantity * price * r.cfg.FeeRate
+	equity := r.cash
+	r.cash -= float64(direction)*quantity*price + fee
+	r.pos = &position{
+		direction:  direction,
+		quantity:   quantity,
+		entryPrice: price,
+		entryFee:   fee,
+		entryTime:  bar.Timestamp,
+		entryIndex: i,
+		stopLoss:   stopLoss,
+		takeProfit: takeProfit,
+		equity:     equity,
+	}
+}
+
+// fillPrice 按滑点调整成交价，direction 为成交方向（1 买入，-1 卖出）
+func (r *runner) fillPrice(price float64, direction int) float64 {
+	return price * (1 + float64(direction)*r.cfg.Slippage)
+}
+
+// checkProtective 检查K线内是否触及止损或止盈，同时触及时按止损处理；跳空越过时以开盘价成交
+func (r *runner) checkProtective(bar models.Bar, i int) {
+	p := r.pos
+	if p == nil {
+		return
+	}
+
+	if p.direction == 1 {
+		if p.stopLoss > 0 && bar.Low <= p.stopLoss {
+			r.close(r.fillPrice(math.Min(bar.Open, p.stopLoss), -1), bar.Timestamp, i, "止损")
+		} else if p.takeProfit > 0 && bar.High >= p.takeProfit {
+			r.close(r.fillPrice(math.Max(bar.Open, p.takeProfit), -1), bar.Timestamp, i, "止盈")
+		}
+		return
+	}
+	if p.stopLoss > 0 && bar.High >= p.stopLoss {
+		r.close(r.fillPrice(math.Max(bar.Open, p.stopLoss), 1), bar.Timestamp, i, "止损")
+	} else if p.takeProfit > 0 && bar.Low <= p.takeProfit {
+		r.close(r.fillPrice(math.Min(bar.Open, p.takeProfit), 1), bar.Timestamp, i, "止盈")
+	}
+}
+
+func (r *runner) close(price float64, at time.Time, i int, reason string) {
+	p := r.pos
+	fee := p.quantity * price * r.cfg.FeeRate
+	r.cash += float64(p.direction)*p.quantity*price - fee
+
+	pnl := float64(p.direction)*(price-p.entryPrice)*p.quantity - p.entryFee - fee
+	r.result.Trades = append(r.result.Trades, Trade{
+		Direction:   p.direction,
+		EntryTime:   p.entryTime,
+		ExitTime:    at,
+		EntryPrice:  p.entryPrice,
+		ExitPrice:   price,
+		Quantity:    p.quantity,
+		Fees:        p.entryFee + fee,
+		PnL:         pnl,
+		ReturnPct:   pnl / (p.entryPrice * p.quantity) * 100,
+		EntryEquity: p.equity,
+		BarsHeld:    i - p.entryIndex,
+		ExitReason:  reason,
+	})
+	r.pos = nil
+}
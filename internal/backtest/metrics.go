@@ -0,0 +1,135 @@
+package backtest
+
+import (
+	"math"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// profitFactorCap 没有亏损交易时盈利因子记为该值，避免出现无穷大
+const profitFactorCap = 100
+
+// Metrics 回测绩效指标，收益率和回撤均为百分比
+type Metrics struct {
+	FinalEquity float64
+	TotalReturn float64
+	// MaxDrawdown 权益曲线自高点的最大回撤（正数）
+	MaxDrawdown float64
+	// Sharpe 按K线收益率计算并年化的夏普比率（无风险利率为 0）
+	Sharpe float64
+	// ReturnDrawdown 总收益率与最大回撤之比，最大回撤低于 1% 时按 1% 计算
+	ReturnDrawdown float64
+	// ProfitFactor 盈利交易总额与亏损交易总额之比
+	ProfitFactor float64
+	Trades       int
+	WinRate      float64
+	AvgTrade     float64
+}
+
+func computeMetrics(result *Result, initialCapital float64) Metrics {
+	m := Metrics{FinalEquity: initialCapital, Trades: len(result.Trades)}
+	if len(result.Equity) > 0 {
+		m.FinalEquity = result.Equity[len(result.Equity)-1].Equity
+	}
+	m.TotalReturn = (m.FinalEquity/initialCapital - 1) * 100
+	m.MaxDrawdown = MaxDrawdown(equityValues(result.Equity))
+	m.Sharpe = sharpe(result.Equity, result.Timeframe)
+	m.ReturnDrawdown = m.TotalReturn / math.Max(m.MaxDrawdown, 1)
+
+	var grossProfit, grossLoss, totalReturn float64
+	wins := 0
+	for _, t := range result.Trades {
+		if t.PnL > 0 {
+			grossProfit += t.PnL
+			wins++
+		} else {
+			grossLoss -= t.PnL
+		}
+		totalReturn += t.ReturnPct
+	}
+	if len(result.Trades) > 0 {
+		m.WinRate = float64(wins) / float64(len(result.Trades)) * 100
+		m.AvgTrade = totalReturn / float64(len(result.Trades))
+	}
+	switch {
+	case grossLoss > 0:
+		m.ProfitFactor = math.Min(grossProfit/grossLoss, profitFactorCap)
+	case grossProfit > 0:
+		m.ProfitFactor = profitFactorCap
+	}
+	return m
+}
+
+func equityValues(points []EquityPoint) []float64 {
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Equity
+	}
+	return values
+}
+
+// MaxDrawdown 返回权益序列自高点的最大回撤百分比
+func MaxDrawdown(equity []float64) float64 {
+	peak, maxDD := 0.0, 0.0
+	for _, e := range equity {
+		if e > peak {
+			peak = e
+		}
+		if peak > 0 {
+			maxDD = math.Max(maxDD, (peak-e)/peak*100)
+		}
+	}
+	return maxDD
+}
+
+// sharpe 计算年化夏普比率，年化系数由时间框架的K线时长决定
+func sharpe(points []EquityPoint, timeframe string) float64 {
+	if len(points) < 3 {
+		return 0
+	}
+	returns := make([]float64, 0, len(points)-1)
+	for i := 1; i < len(points); i++ {
+		if points[i-1].Equity > 0 {
+			returns = append(returns, points[i].Equity/points[i-1].Equity-1)
+		}
+	}
+
+	mean, std := meanStd(returns)
+	if std == 0 {
+		return 0
+	}
+	return mean / std * math.Sqrt(periodsPerYear(timeframe))
+}
+
+// periodsPerYear 返回一年内的K线数量，未知时间框架按日线计算
+func periodsPerYear(timeframe string) float64 {
+	if d, ok := models.TimeframeDuration(timeframe); ok {
+		return float64(365*24*time.Hour) / float64(d)
+	}
+	return 365
+}
+
+// annualizedReturn 将 bars 根K线上的总收益率按单利折算为年化收益率（百分比）
+func annualizedReturn(totalReturn float64, bars int, timeframe string) float64 {
+	if bars <= 0 {
+		return 0
+	}
+	return totalReturn * periodsPerYear(timeframe) / float64(bars)
+}
+
+func meanStd(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
@@ -0,0 +1,225 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// 蒙特卡洛抽样方式
+const (
+	// MonteCarloShuffle 随机打乱原有顺序，每个样本恰好使用一次
+	MonteCarloShuffle = "shuffle"
+	// MonteCarloBootstrap 有放回地随机抽样
+	MonteCarloBootstrap = "bootstrap"
+)
+
+// 蒙特卡洛抽样对象
+const (
+	// MonteCarloTrades 对逐笔交易收益抽样
+	MonteCarloTrades = "trades"
+	// MonteCarloReturns 对权益曲线的逐K线收益率抽样
+	MonteCarloReturns = "returns"
+)
+
+// MonteCarloConfig 蒙特卡洛分析参数
+type MonteCarloConfig struct {
+	Iterations int
+	Method     string
+	Source     string
+	// Slippage 每笔交易每次成交额外承受的最大随机滑点比例，实际滑点在 [0, Slippage] 内均匀分布，仅用于逐笔交易
+	Slippage float64
+	// SkipProbability 每笔交易被跳过（未成交）的概率，仅用于逐笔交易
+	SkipProbability float64
+	// RuinDrawdown 最大回撤达到该百分比即视为破产
+	RuinDrawdown float64
+	// Confidence 置信区间的置信水平，例如 0.95
+	Confidence float64
+	Seed       int64
+}
+
+// DefaultMonteCarloConfig 返回默认参数：对逐笔交易有放回抽样 5000 次，回撤 50% 视为破产，95% 置信区间
+func DefaultMonteCarloConfig() MonteCarloConfig {
+	return MonteCarloConfig{
+		Iterations:   5000,
+		Method:       MonteCarloBootstrap,
+		Source:       MonteCarloTrades,
+		RuinDrawdown: 50,
+		Confidence:   0.95,
+	}
+}
+
+// Distribution 模拟结果的分布，单位与原指标相同
+type Distribution struct {
+	Mean float64
+	Std  float64
+	Min  float64
+	Max  float64
+	P5   float64
+	P25  float64
+	P50  float64
+	P75  float64
+	P95  float64
+	// Lower 和 Upper 为置信区间的上下界
+	Lower float64
+	Upper float64
+}
+
+// MonteCarloResult 蒙特卡洛分析结果，收益率、回撤和概率均为百分比
+type MonteCarloResult struct {
+	Iterations int
+	// Samples 每次模拟使用的交易或K线数量
+	Samples     int
+	Confidence  float64
+	FinalReturn Distribution
+	MaxDrawdown Distribution
+	// RiskOfRuin 最大回撤达到 RuinDrawdown 的模拟占比
+	RiskOfRuin float64
+	// ProbabilityOfLoss 最终收益为负的模拟占比
+	ProbabilityOfLoss float64
+}
+
+// MonteCarlo 对回测结果的交易或收益率序列重复抽样，估计最终收益、最大回撤和破产风险的分布。
+// 逐笔交易按开仓时权益的收益率复利计算，回撤只在交易结束时计量，因此会低估持仓期间的浮亏
+func MonteCarlo(result *Result, initialCapital float64, cfg MonteCarloConfig) (*MonteCarloResult, error) {
+	if cfg.Iterations < 1 {
+		return nil, fmt.Errorf("iterations 必须大于 0")
+	}
+	if cfg.Method != MonteCarloShuffle && cfg.Method != MonteCarloBootstrap {
+		return nil, fmt.Errorf("不支持的抽样方式 %q，可选值: %s, %s", cfg.Method, MonteCarloShuffle, MonteCarloBootstrap)
+	}
+	if cfg.SkipProbability < 0 || cfg.SkipProbability >= 1 {
+		return nil, fmt.Errorf("skipProbability 必须在 [0, 1) 范围内")
+	}
+	if cfg.Slippage < 0 {
+		return nil, fmt.Errorf("slippage 不能为负数")
+	}
+	if cfg.Confidence <= 0 || cfg.Confidence >= 1 {
+		return nil, fmt.Errorf("confidence 必须在 (0, 1) 范围内")
+	}
+
+	var returns, exposures []float64
+	switch cfg.Source {
+	case MonteCarloTrades:
+		returns, exposures = tradeReturns(result.Trades)
+	case MonteCarloReturns:
+		if cfg.Slippage > 0 || cfg.SkipProbability > 0 {
+			return nil, fmt.Errorf("随机滑点和跳过交易仅适用于逐笔交易抽样")
+		}
+		returns = equityReturns(result.Equity, initialCapital)
+	default:
+		return nil, fmt.Errorf("不支持的抽样对象 %q，可选值: %s, %s", cfg.Source, MonteCarloTrades, MonteCarloReturns)
+	}
+	if len(returns) == 0 {
+		return nil, fmt.Errorf("没有可供抽样的%s", cfg.Source)
+	}
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	finals := make([]float64, cfg.Iterations)
+	drawdowns := make([]float64, cfg.Iterations)
+	order := make([]int, len(returns))
+	for i := range order {
+		order[i] = i
+	}
+	ruined, losses := 0, 0
+
+	for it := 0; it < cfg.Iterations; it++ {
+		if cfg.Method == MonteCarloShuffle {
+			rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		} else {
+			for i := range order {
+				order[i] = rng.Intn(len(returns))
+			}
+		}
+
+		equity, peak, maxDD := 1.0, 1.0, 0.0
+		for _, k := range order {
+			if cfg.SkipProbability > 0 && rng.Float64() < cfg.SkipProbability {
+				continue
+			}
+			r := returns[k]
+			if exposures != nil && cfg.Slippage > 0 {
+				// 开仓和平仓各承受一次随机滑点
+				r -= exposures[k] * (rng.Float64() + rng.Float64()) * cfg.Slippage
+			}
+			equity = math.Max(equity*(1+r), 0)
+			peak = math.Max(peak, equity)
+			maxDD = math.Max(maxDD, (peak-equity)/peak*100)
+		}
+
+		finals[it] = (equity - 1) * 100
+		drawdowns[it] = maxDD
+		if equity < 1 {
+			losses++
+		}
+		if cfg.RuinDrawdown > 0 && maxDD >= cfg.RuinDrawdown {
+			ruined++
+		}
+	}
+
+	n := float64(cfg.Iterations)
+	return &MonteCarloResult{
+		Iterations:        cfg.Iterations,
+		Samples:           len(returns),
+		Confidence:        cfg.Confidence,
+		FinalReturn:       newDistribution(finals, cfg.Confidence),
+		MaxDrawdown:       newDistribution(drawdowns, cfg.Confidence),
+		RiskOfRuin:        float64(ruined) / n * 100,
+		ProbabilityOfLoss: float64(losses) / n * 100,
+	}, nil
+}
+
+// tradeReturns 返回每笔交易相对开仓时权益的收益率，以及成交额占权益的比例（用于折算滑点）
+func tradeReturns(trades []Trade) ([]float64, []float64) {
+	var returns, exposures []float64
+	for _, t := range trades {
+		if t.EntryEquity <= 0 {
+			continue
+		}
+		returns = append(returns, t.PnL/t.EntryEquity)
+		exposures = append(exposures, t.EntryPrice*t.Quantity/t.EntryEquity)
+	}
+	return returns, exposures
+}
+
+// equityReturns 返回权益曲线的逐K线收益率，第一个点相对初始资金计算
+func equityReturns(points []EquityPoint, initialCapital float64) []float64 {
+	returns := make([]float64, 0, len(points))
+	prev := initialCapital
+	for _, p := range points {
+		if prev > 0 {
+			returns = append(returns, p.Equity/prev-1)
+		}
+		prev = p.Equity
+	}
+	return returns
+}
+
+func newDistribution(values []float64, confidence float64) Distribution {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mean, std := meanStd(sorted)
+	tail := (1 - confidence) / 2
+	return Distribution{
+		Mean:  mean,
+		Std:   std,
+		Min:   sorted[0],
+		Max:   sorted[len(sorted)-1],
+		P5:    percentile(sorted, 0.05),
+		P25:   percentile(sorted, 0.25),
+		P50:   percentile(sorted, 0.5),
+		P75:   percentile(sorted, 0.75),
+		P95:   percentile(sorted, 0.95),
+		Lower: percentile(sorted, tail),
+		Upper: percentile(sorted, 1-tail),
+	}
+}
+
+// percentile 在已排序的序列上按线性插值计算分位数，p 取值 [0, 1]
+func percentile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
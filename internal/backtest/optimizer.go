@@ -0,0 +1,413 @@
+package backtest
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/qqqq/eth-trading-system/internal/config"
+	"github.com/qqqq/eth-trading-system/internal/models"
+	"github.com/qqqq/eth-trading-system/internal/strategy"
+)
+
+// 优化目标
+const (
+	ObjectiveSharpe         = "sharpe"
+	ObjectiveReturnDrawdown = "return_drawdown"
+	ObjectiveProfitFactor   = "profit_factor"
+)
+
+// ParamRange 一个待优化参数的取值范围。
+// 设置 Values 时直接枚举这些取值；否则在 [Min, Max] 中按 Step 取值，
+// Min、Max、Step 均为整数时取整数值。Step 为 0 时仅能用于随机搜索，在区间内连续取值
+type ParamRange struct {
+	Name   string
+	Min    float64
+	Max    float64
+	Step   float64
+	Values []interface{}
+}
+
+// ParseParamRange 解析 "name=min:max:step"、"name=a|b|c" 或 "name=value" 形式的参数范围
+func ParseParamRange(spec string) (ParamRange, error) {
+	name, value, ok := strings.Cut(spec, "=")
+	if !ok || name == "" || value == "" {
+		return ParamRange{}, fmt.Errorf("参数范围 %q 格式应为 name=min:max:step 或 name=a|b|c", spec)
+	}
+	r := ParamRange{Name: name}
+
+	if parts := strings.Split(value, ":"); len(parts) == 3 {
+		nums := make([]float64, 3)
+		for i, p := range parts {
+			n, err := strconv.ParseFloat(p, 64)
+			if err != nil {
+				return ParamRange{}, fmt.Errorf("参数范围 %q: %q 不是数字", spec, p)
+			}
+			nums[i] = n
+		}
+		r.Min, r.Max, r.Step = nums[0], nums[1], nums[2]
+		if r.Min > r.Max || r.Step < 0 {
+			return ParamRange{}, fmt.Errorf("参数范围 %q 需要满足 min <= max 且 step >= 0", spec)
+		}
+		return r, nil
+	}
+
+	for _, v := range strings.Split(value, "|") {
+		r.Values = append(r.Values, parseScalar(v))
+	}
+	return r, nil
+}
+
+func parseScalar(v string) interface{} {
+	if n, err := strconv.Atoi(v); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	if b, err := strconv.ParseBool(v); err == nil {
+		return b
+	}
+	return v
+}
+
+func (r ParamRange) integral() bool {
+	return r.Min == math.Trunc(r.Min) && r.Max == math.Trunc(r.Max) && r.Step == math.Trunc(r.Step)
+}
+
+// grid 返回网格搜索的全部取值
+func (r ParamRange) grid() ([]interface{}, error) {
+	if len(r.Values) > 0 {
+		return r.Values, nil
+	}
+	if r.Step == 0 {
+		if r.Min == r.Max {
+			return []interface{}{r.value(r.Min)}, nil
+		}
+		return nil, fmt.Errorf("参数 %s 的 step 为 0，无法进行网格搜索", r.Name)
+	}
+	var values []interface{}
+	// 加上极小量避免浮点误差漏掉 Max
+	for v := r.Min; v <= r.Max+r.Step*1e-9; v += r.Step {
+		values = append(values, r.value(v))
+	}
+	return values, nil
+}
+
+// sample 随机抽取一个取值
+func (r ParamRange) sample(rng *rand.Rand) interface{} {
+	if len(r.Values) > 0 {
+		return r.Values[rng.Intn(len(r.Values))]
+	}
+	if r.Step == 0 {
+		v := r.Min + rng.Float64()*(r.Max-r.Min)
+		if r.integral() {
+			return int(math.Round(v))
+		}
+		return v
+	}
+	steps := int(math.Floor((r.Max-r.Min)/r.Step + 1e-9))
+	return r.value(r.Min + float64(rng.Intn(steps+1))*r.Step)
+}
+
+func (r ParamRange) value(v float64) interface{} {
+	if r.integral() {
+		return int(math.Round(v))
+	}
+	return v
+}
+
+// OptimizationResult 一组参数的回测结果
+type OptimizationResult struct {
+	Params  map[string]interface{}
+	Metrics Metrics
+	Score   float64
+	// Valid 为 false 表示参数无效、回测失败或交易次数不足，不参与排名
+	Valid bool
+	Error string
+}
+
+// Optimizer 在历史K线上搜索策略参数
+type Optimizer struct {
+	Registry *strategy.Registry
+	// Base 策略配置，Params 中的固定参数会与搜索参数合并
+	Base      config.StrategyConfig
+	Bars      []models.Bar
+	Timeframe string
+	Backtest  Config
+	Objective string
+	// MinTrades 交易次数少于该值的结果视为无效，避免少数交易造成的过拟合
+	MinTrades int
+	// Workers 并行回测数量，0 表示使用全部 CPU 核心
+	Workers int
+}
+
+// ValidObjective 判断优化目标是否受支持
+func ValidObjective(objective string) bool {
+	switch objective {
+	case ObjectiveSharpe, ObjectiveReturnDrawdown, ObjectiveProfitFactor:
+		return true
+	}
+	return false
+}
+
+// GridSearch 回测所有参数组合，返回按目标排序的结果
+func (o *Optimizer) GridSearch(ranges []ParamRange) ([]OptimizationResult, error) {
+	combos := []map[string]interface{}{{}}
+	for _, r := range ranges {
+		values, err := r.grid()
+		if err != nil {
+			return nil, err
+		}
+		next := make([]map[string]interface{}, 0, len(combos)*len(values))
+		for _, combo := range combos {
+			for _, v := range values {
+				c := copyParams(combo)
+				c[r.Name] = v
+				next = append(next, c)
+			}
+		}
+		combos = next
+	}
+	return o.run(combos)
+}
+
+// RandomSearch 随机抽取 samples 组不重复的参数组合进行回测
+func (o *Optimizer) RandomSearch(ranges []ParamRange, samples int, seed int64) ([]OptimizationResult, error) {
+	if samples < 1 {
+		return nil, fmt.Errorf("samples 必须大于 0")
+	}
+	rng := rand.New(rand.NewSource(seed))
+	seen := make(map[string]bool)
+	var combos []map[string]interface{}
+	// 参数空间可能小于 samples，限制尝试次数
+	for attempts := 0; len(combos) < samples && attempts < samples*20; attempts++ {
+		combo := make(map[string]interface{}, len(ranges))
+		for _, r := range ranges {
+			combo[r.Name] = r.sample(rng)
+		}
+		key := fmt.Sprint(combo)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		combos = append(combos, combo)
+	}
+	return o.run(combos)
+}
+
+func (o *Optimizer) run(combos []map[string]interface{}) ([]OptimizationResult, error) {
+	if !ValidObjective(o.Objective) {
+		return nil, fmt.Errorf("不支持的优化目标 %q，可选值: %s, %s, %s", o.Objective, ObjectiveSharpe, ObjectiveReturnDrawdown, ObjectiveProfitFactor)
+	}
+	workers := o.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	results := make([]OptimizationResult, len(combos))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = o.evaluate(combos[i])
+			}
+		}()
+	}
+	for i := range combos {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	Rank(results)
+	return results, nil
+}
+
+// evaluate 按参数构建策略并回测
+func (o *Optimizer) evaluate(params map[string]interface{}) OptimizationResult {
+	result := OptimizationResult{Params: params}
+
+	bt, err := o.RunParams(params)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Metrics = bt.Metrics
+	result.Score = Score(bt.Metrics, o.Objective)
+	if bt.Metrics.Trades < o.MinTrades {
+		result.Error = fmt.Sprintf("交易次数 %d 少于 %d", bt.Metrics.Trades, o.MinTrades)
+		return result
+	}
+	result.Valid = true
+	return result
+}
+
+// RunParams 使用给定参数在 o.Bars 上回测，返回完整的回测结果
+func (o *Optimizer) RunParams(params map[string]interface{}) (*Result, error) {
+	s, err := o.build(params)
+	if err != nil {
+		return nil, err
+	}
+	return Run(s, o.Bars, o.Timeframe, o.Backtest)
+}
+
+// build 将 params 覆盖到基础配置上并构建策略
+func (o *Optimizer) build(params map[string]interface{}) (strategy.Strategy, error) {
+	cfg := o.Base
+	cfg.Params = copyParams(o.Base.Params)
+	for k, v := range params {
+		cfg.Params[k] = v
+	}
+	strategies, err := o.Registry.BuildAll([]config.StrategyConfig{cfg})
+	if err != nil {
+		return nil, err
+	}
+	if len(strategies) != 1 {
+		return nil, fmt.Errorf("策略未启用")
+	}
+	return strategies[0], nil
+}
+
+// Score 返回指标在优化目标下的得分，越大越好
+func Score(m Metrics, objective string) float64 {
+	switch objective {
+	case ObjectiveReturnDrawdown:
+		return m.ReturnDrawdown
+	case ObjectiveProfitFactor:
+		return m.ProfitFactor
+	default:
+		return m.Sharpe
+	}
+}
+
+// Rank 将有效结果按得分降序排在前面，无效结果排在最后
+func Rank(results []OptimizationResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Valid != results[j].Valid {
+			return results[i].Valid
+		}
+		return results[i].Score > results[j].Score
+	})
+}
+
+// WriteTable 以表格形式输出前 top 个结果，top 为 0 时输出全部
+func WriteTable(w io.Writer, results []OptimizationResult, paramNames []string, top int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	header := append([]string{"#"}, paramNames...)
+	header = append(header, "得分", "收益%", "回撤%", "夏普", "收益/回撤", "盈利因子", "交易数", "胜率%", "")
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for i, r := range results {
+		if top > 0 && i >= top {
+			break
+		}
+		row := []string{strconv.Itoa(i + 1)}
+		for _, name := range paramNames {
+			row = append(row, fmt.Sprint(r.Params[name]))
+		}
+		status := ""
+		if !r.Valid {
+			status = "无效: " + r.Error
+		}
+		m := r.Metrics
+		row = append(row,
+			fmt.Sprintf("%.3f", r.Score), fmt.Sprintf("%.2f", m.TotalReturn), fmt.Sprintf("%.2f", m.MaxDrawdown),
+			fmt.Sprintf("%.2f", m.Sharpe), fmt.Sprintf("%.2f", m.ReturnDrawdown), fmt.Sprintf("%.2f", m.ProfitFactor),
+			strconv.Itoa(m.Trades), fmt.Sprintf("%.1f", m.WinRate), status)
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// Heatmap 两个参数网格上的得分，便于前端绘制热力图。
+// Scores[y][x] 为参数取 (XValues[x], YValues[y]) 时（其余参数取最优）的最高得分，无有效结果时为 nil
+type Heatmap struct {
+	XParam    string        `json:"xParam"`
+	YParam    string        `json:"yParam"`
+	Objective string        `json:"objective"`
+	XValues   []interface{} `json:"xValues"`
+	YValues   []interface{} `json:"yValues"`
+	Scores    [][]*float64  `json:"scores"`
+}
+
+// BuildHeatmap 根据优化结果生成 xParam × yParam 的热力图数据
+func BuildHeatmap(results []OptimizationResult, xParam, yParam, objective string) (*Heatmap, error) {
+	xValues, xIndex := distinctValues(results, xParam)
+	yValues, yIndex := distinctValues(results, yParam)
+	if len(xValues) == 0 || len(yValues) == 0 {
+		return nil, fmt.Errorf("结果中缺少参数 %s 或 %s", xParam, yParam)
+	}
+
+	h := &Heatmap{XParam: xParam, YParam: yParam, Objective: objective, XValues: xValues, YValues: yValues}
+	h.Scores = make([][]*float64, len(yValues))
+	for i := range h.Scores {
+		h.Scores[i] = make([]*float64, len(xValues))
+	}
+	for _, r := range results {
+		if !r.Valid {
+			continue
+		}
+		x, y := xIndex[fmt.Sprint(r.Params[xParam])], yIndex[fmt.Sprint(r.Params[yParam])]
+		if cell := h.Scores[y][x]; cell == nil || r.Score > *cell {
+			score := r.Score
+			h.Scores[y][x] = &score
+		}
+	}
+	return h, nil
+}
+
+// distinctValues 返回参数出现过的取值（数值按大小排序）及其下标
+func distinctValues(results []OptimizationResult, name string) ([]interface{}, map[string]int) {
+	seen := make(map[string]interface{})
+	for _, r := range results {
+		if v, ok := r.Params[name]; ok {
+			seen[fmt.Sprint(v)] = v
+		}
+	}
+	values := make([]interface{}, 0, len(seen))
+	for _, v := range seen {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		a, aok := toFloat(values[i])
+		b, bok := toFloat(values[j])
+		if aok && bok {
+			return a < b
+		}
+		return fmt.Sprint(values[i]) < fmt.Sprint(values[j])
+	})
+	index := make(map[string]int, len(values))
+	for i, v := range values {
+		index[fmt.Sprint(v)] = i
+	}
+	return values, index
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func copyParams(params map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		c[k] = v
+	}
+	return c
+}
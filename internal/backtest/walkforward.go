@@ -0,0 +1,161 @@
+package backtest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// 参数搜索方式
+const (
+	SearchGrid   = "grid"
+	SearchRandom = "random"
+)
+
+// WalkForwardConfig 向前滚动分析的窗口设置
+type WalkForwardConfig struct {
+	// TrainBars 样本内（优化）窗口的K线数量，Anchored 时为第一个窗口的长度
+	TrainBars int
+	// TestBars 样本外（验证）窗口的K线数量，也是窗口每次前移的距离
+	TestBars int
+	// Anchored 为 true 时样本内窗口起点固定在第一根K线，否则随窗口一起滚动
+	Anchored bool
+	Method   string
+	Samples  int
+	Seed     int64
+}
+
+// WalkForwardWindow 一个样本内/样本外窗口的结果
+type WalkForwardWindow struct {
+	TrainStart time.Time
+	TrainEnd   time.Time
+	TestStart  time.Time
+	TestEnd    time.Time
+	// Params 在样本内窗口上得分最高的参数，为 nil 表示没有有效结果，样本外窗口空仓
+	Params       map[string]interface{}
+	TrainMetrics Metrics
+	TestMetrics  Metrics
+	// TrainAnnualized 和 TestAnnualized 为样本内/外的年化收益率（单利，百分比）
+	TrainAnnualized float64
+	TestAnnualized  float64
+	Error           string
+}
+
+// WalkForwardResult 向前滚动分析的结果
+type WalkForwardResult struct {
+	Windows []WalkForwardWindow
+	// Equity 依次拼接各样本外窗口的权益曲线，每个窗口以上一窗口的期末权益开始
+	Equity  []EquityPoint
+	Trades  []Trade
+	Metrics Metrics
+	// Efficiency 向前滚动效率：样本外平均年化收益率 / 样本内平均年化收益率，
+	// 样本内平均年化收益率不为正时为 0
+	Efficiency float64
+}
+
+// WalkForward 将 o.Bars 切分为连续的样本内/样本外窗口，在每个样本内窗口上搜索参数，
+// 并用最优参数回测紧随其后的样本外窗口。样本外回测以样本内数据作为指标预热
+func (o *Optimizer) WalkForward(ranges []ParamRange, wf WalkForwardConfig) (*WalkForwardResult, error) {
+	if wf.TrainBars < 2 || wf.TestBars < 1 {
+		return nil, fmt.Errorf("trainBars 必须不小于 2，testBars 必须不小于 1")
+	}
+	if len(o.Bars) < wf.TrainBars+wf.TestBars {
+		return nil, fmt.Errorf("K线数量 %d 少于一个窗口所需的 %d 根", len(o.Bars), wf.TrainBars+wf.TestBars)
+	}
+	if wf.Method != SearchGrid && wf.Method != SearchRandom {
+		return nil, fmt.Errorf("不支持的搜索方式 %q，可选值: %s, %s", wf.Method, SearchGrid, SearchRandom)
+	}
+
+	result := &WalkForwardResult{}
+	capital := o.Backtest.InitialCapital
+	var trainSum, testSum float64
+
+	for testStart := wf.TrainBars; testStart < len(o.Bars); testStart += wf.TestBars {
+		trainStart := testStart - wf.TrainBars
+		if wf.Anchored {
+			trainStart = 0
+		}
+		testEnd := testStart + wf.TestBars
+		if testEnd > len(o.Bars) {
+			testEnd = len(o.Bars)
+		}
+
+		window, equity, trades := o.walkForwardWindow(ranges, wf, trainStart, testStart, testEnd, capital)
+		result.Windows = append(result.Windows, window)
+		result.Equity = append(result.Equity, equity...)
+		result.Trades = append(result.Trades, trades...)
+		if len(equity) > 0 {
+			capital = equity[len(equity)-1].Equity
+		}
+		trainSum += window.TrainAnnualized
+		testSum += window.TestAnnualized
+	}
+
+	stitched := &Result{Timeframe: o.Timeframe, Trades: result.Trades, Equity: result.Equity}
+	result.Metrics = computeMetrics(stitched, o.Backtest.InitialCapital)
+	if n := float64(len(result.Windows)); trainSum > 0 {
+		result.Efficiency = (testSum / n) / (trainSum / n)
+	}
+	return result, nil
+}
+
+// walkForwardWindow 优化并验证一个窗口，返回窗口结果及样本外的权益曲线和交易
+func (o *Optimizer) walkForwardWindow(ranges []ParamRange, wf WalkForwardConfig, trainStart, testStart, testEnd int, capital float64) (WalkForwardWindow, []EquityPoint, []Trade) {
+	bars := o.Bars
+	window := WalkForwardWindow{
+		TrainStart: bars[trainStart].Timestamp,
+		TrainEnd:   bars[testStart-1].Timestamp,
+		TestStart:  bars[testStart].Timestamp,
+		TestEnd:    bars[testEnd-1].Timestamp,
+	}
+	flat := flatEquity(bars[testStart:testEnd], capital)
+
+	train := *o
+	train.Bars = bars[trainStart:testStart]
+	var results []OptimizationResult
+	var err error
+	if wf.Method == SearchRandom {
+		results, err = train.RandomSearch(ranges, wf.Samples, wf.Seed)
+	} else {
+		results, err = train.GridSearch(ranges)
+	}
+	if err != nil {
+		window.Error = err.Error()
+		return window, flat, nil
+	}
+	if len(results) == 0 || !results[0].Valid {
+		window.Error = "样本内没有有效的参数组合"
+		return window, flat, nil
+	}
+	best := results[0]
+	window.Params = best.Params
+	window.TrainMetrics = best.Metrics
+	window.TrainAnnualized = annualizedReturn(best.Metrics.TotalReturn, testStart-trainStart, o.Timeframe)
+
+	s, err := o.build(best.Params)
+	if err != nil {
+		window.Error = err.Error()
+		return window, flat, nil
+	}
+	cfg := o.Backtest
+	cfg.InitialCapital = capital
+	cfg.TradeFrom = bars[testStart].Timestamp
+	test, err := Run(s, bars[trainStart:testEnd], o.Timeframe, cfg)
+	if err != nil {
+		window.Error = err.Error()
+		return window, flat, nil
+	}
+	window.TestMetrics = test.Metrics
+	window.TestAnnualized = annualizedReturn(test.Metrics.TotalReturn, testEnd-testStart, o.Timeframe)
+	return window, test.Equity, test.Trades
+}
+
+// flatEquity 返回空仓时的权益曲线
+func flatEquity(bars []models.Bar, capital float64) []EquityPoint {
+	points := make([]EquityPoint, len(bars))
+	for i, bar := range bars {
+		points[i] = EquityPoint{Timestamp: bar.Timestamp, Equity: capital}
+	}
+	return points
+}
@@ -1,29 +1,316 @@
 package config
 
 import (
+	"reflect"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
+// 标记为 reload:"true" 的字段可以在运行时通过修改配置文件热更新，
+// 其余字段的修改需要重启才能生效
 type Config struct {
-	AlpacaAPIKey    string
-	AlpacaAPISecret string
-	DBPath          string
-	ServerPort      string
-	LogDir          string
-	LogLevel        string
+	AlpacaAPIKey    string           `mapstructure:"alpacaAPIKey" secret:"true"`
+	AlpacaAPISecret string           `mapstructure:"alpacaAPISecret" secret:"true"`
+	SecretsFile     string           `mapstructure:"secretsFile"`
+	DBPath          string           `mapstructure:"dbPath"`
+	ServerPort      string           `mapstructure:"serverPort"`
+	LogDir          string           `mapstructure:"logDir"`
+	LogLevel        string           `mapstructure:"logLevel" reload:"true"`
+	Timeframes      []string         `mapstructure:"timeframes" reload:"true"`
+	Strategies      []StrategyConfig `mapstructure:"strategies" reload:"true"`
+	Alerts          AlertConfig      `mapstructure:"alerts" reload:"true"`
+	Analysis        AnalysisConfig   `mapstructure:"analysis"`
+	Trading         TradingConfig    `mapstructure:"trading"`
+	Risk            RiskConfig       `mapstructure:"risk" reload:"true"`
+	Notifications   NotifyConfig     `mapstructure:"notifications" reload:"true"`
+	Scripts         ScriptConfig     `mapstructure:"scripts" reload:"true"`
+}
+
+// StrategyConfig 描述一个策略实例，由 strategy.Registry 构建为具体策略
+type StrategyConfig struct {
+	Name      string `mapstructure:"name"`
+	Type      string `mapstructure:"type"`
+	Symbol    string `mapstructure:"symbol"`
+	Timeframe string `mapstructure:"timeframe"`
+	// Enabled 未设置时视为启用
+	Enabled *bool `mapstructure:"enabled"`
+	// Weight 和 Veto 仅在作为组合策略的子策略时使用，Veto 为 true 的子策略不参与投票，
+	// 只在其信号与组合结果方向相反时否决该结果
+	Weight   float64                `mapstructure:"weight"`
+	Veto     bool                   `mapstructure:"veto"`
+	Params   map[string]interface{} `mapstructure:"params"`
+	Children []StrategyConfig       `mapstructure:"children"`
+}
+
+func (sc StrategyConfig) IsEnabled() bool {
+	return sc.Enabled == nil || *sc.Enabled
+}
+
+// AlertConfig 告警阈值
+type AlertConfig struct {
+	// PriceMovePercent 相邻两次采集的最新价格变动超过该百分比时记录告警，0 表示关闭
+	PriceMovePercent float64 `mapstructure:"priceMovePercent"`
+}
+
+// TradingConfig 模拟交易设置
+type TradingConfig struct {
+	// Enabled 为 true 时按策略信号下单，所有订单都需通过风控检查
+	Enabled        bool    `mapstructure:"enabled" reload:"true"`
+	InitialCapital float64 `mapstructure:"initialCapital"`
+	FeeRate        float64 `mapstructure:"feeRate"`
+	// SnapshotInterval 保存账户权益快照的间隔，0 表示不保存
+	SnapshotInterval time.Duration `mapstructure:"snapshotInterval"`
+	Sizing           SizingConfig  `mapstructure:"sizing" reload:"true"`
+	// Protection 对新开仓位附加的保护性订单，修改后只影响之后开的仓位
+	Protection ProtectionConfig `mapstructure:"protection" reload:"true"`
+}
+
+// ProtectionConfig 止损、止盈、移动止损和保本设置。止损和止盈互为 OCO，任一触发即平仓并撤销其余订单
+type ProtectionConfig struct {
+	// UseSignalLevels 为 true 时优先使用策略信号给出的止损价和止盈价
+	UseSignalLevels bool `mapstructure:"useSignalLevels"`
+	ATRPeriod       int  `mapstructure:"atrPeriod"`
+	// StopLoss 的 type 可选 percent, atr；TakeProfit 还可选 r（初始风险的倍数）；Trailing 可选 percent, atr
+	StopLoss   LevelConfig `mapstructure:"stopLoss"`
+	TakeProfit LevelConfig `mapstructure:"takeProfit"`
+	Trailing   LevelConfig `mapstructure:"trailing"`
+	// BreakevenR 浮盈达到初始风险的该倍数后将止损移至开仓价，0 表示不移动
+	BreakevenR float64 `mapstructure:"breakevenR"`
+}
+
+// LevelConfig 保护性价格的计算方式，Type 为空表示不设置
+type LevelConfig struct {
+	Type        string  `mapstructure:"type"`
+	Percent     float64 `mapstructure:"percent"`
+	ATRMultiple float64 `mapstructure:"atrMultiple"`
+	RMultiple   float64 `mapstructure:"rMultiple"`
+}
+
+// SizingConfig 仓位计算设置，Method 决定买入数量的计算方式，数值上限为 0 表示不限制
+type SizingConfig struct {
+	// Method 可选 fixed_quote, fixed_fraction, kelly, volatility
+	Method      string  `mapstructure:"method"`
+	QuoteAmount float64 `mapstructure:"quoteAmount"`
+	Fraction    float64 `mapstructure:"fraction"`
+	// KellyFraction 实际使用的凯利比例系数，策略已平仓交易少于 KellyMinTrades 时改用 Fraction
+	KellyFraction  float64 `mapstructure:"kellyFraction"`
+	KellyMinTrades int     `mapstructure:"kellyMinTrades"`
+	// TargetVolatility 价格变动一个 ATR 时持仓盈亏占权益的比例
+	TargetVolatility float64 `mapstructure:"targetVolatility"`
+	ATRPeriod        int     `mapstructure:"atrPeriod"`
+	MaxFraction      float64 `mapstructure:"maxFraction"`
+	MaxQuote         float64 `mapstructure:"maxQuote"`
+	// Symbols 按标的设置的最小下单量和数量步长
+	Symbols map[string]SymbolRules `mapstructure:"symbols"`
+}
+
+// SymbolRules 交易所对标的下单数量的限制
+type SymbolRules struct {
+	MinQuantity float64 `mapstructure:"minQuantity"`
+	MinNotional float64 `mapstructure:"minNotional"`
+	LotStep     float64 `mapstructure:"lotStep"`
+}
+
+// RiskConfig 下单前的风控限制，数值为 0 表示不限制
+type RiskConfig struct {
+	// KillSwitch 为 true 时停止所有交易，FlattenOnKill 为 true 时同时平掉全部持仓
+	KillSwitch    bool `mapstructure:"killSwitch"`
+	FlattenOnKill bool `mapstructure:"flattenOnKill"`
+	// MaxPositionNotional 单个标的持仓的最大名义价值（计价货币）
+	MaxPositionNotional float64 `mapstructure:"maxPositionNotional"`
+	// MaxOrderNotional 和 MaxOrderQuantity 限制单笔订单的名义价值和数量
+	MaxOrderNotional float64 `mapstructure:"maxOrderNotional"`
+	MaxOrderQuantity float64 `mapstructure:"maxOrderQuantity"`
+	MaxOrdersPerHour int     `mapstructure:"maxOrdersPerHour"`
+	// DailyLossLimit 当日（UTC）已实现与未实现亏损之和的上限（计价货币）
+	DailyLossLimit float64 `mapstructure:"dailyLossLimit"`
+	// MaxDrawdownPercent 权益自最高点回撤的百分比上限
+	MaxDrawdownPercent float64 `mapstructure:"maxDrawdownPercent"`
+	// 连续亏损 MaxConsecutiveLosses 笔后暂停开仓 LossCooldown
+	MaxConsecutiveLosses int           `mapstructure:"maxConsecutiveLosses"`
+	LossCooldown         time.Duration `mapstructure:"lossCooldown"`
+}
+
+// NotifyConfig 通知设置，事件依次经过去重和各渠道的过滤条件后发送
+type NotifyConfig struct {
+	// DedupWindow 相同事件在该时间内只发送一次，0 表示不去重
+	DedupWindow time.Duration `mapstructure:"dedupWindow"`
+	// Timeout 单次发送的超时时间
+	Timeout time.Duration `mapstructure:"timeout"`
+	// 发送失败时最多尝试 MaxAttempts 次，间隔从 InitialBackoff 开始加倍，不超过 MaxBackoff
+	MaxAttempts    int             `mapstructure:"maxAttempts"`
+	InitialBackoff time.Duration   `mapstructure:"initialBackoff"`
+	MaxBackoff     time.Duration   `mapstructure:"maxBackoff"`
+	Channels       []ChannelConfig `mapstructure:"channels"`
+}
+
+// ChannelConfig 一个通知渠道，Type 可选 webhook, slack, telegram, email
+type ChannelConfig struct {
+	Name    string `mapstructure:"name"`
+	Type    string `mapstructure:"type"`
+	Enabled *bool  `mapstructure:"enabled"`
+	// URL webhook 和 slack 的地址，Secret 非空时 webhook 请求附带 HMAC-SHA256 签名
+	URL     string            `mapstructure:"url" secret:"true"`
+	Secret  string            `mapstructure:"secret" secret:"true"`
+	Headers map[string]string `mapstructure:"headers"`
+	// BotToken 和 ChatID 用于 telegram，APIURL 默认为 https://api.telegram.org
+	BotToken string `mapstructure:"botToken" secret:"true"`
+	ChatID   string `mapstructure:"chatID"`
+	APIURL   string `mapstructure:"apiURL"`
+	// SMTP 设置用于 email，Username 为空时不进行认证
+	SMTPHost string   `mapstructure:"smtpHost"`
+	SMTPPort int      `mapstructure:"smtpPort"`
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password" secret:"true"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+	// Title 和 Body 为 text/template 模板，为空时使用默认模板
+	Title  string       `mapstructure:"title"`
+	Body   string       `mapstructure:"body"`
+	Filter NotifyFilter `mapstructure:"filter"`
+}
+
+func (cc ChannelConfig) IsEnabled() bool {
+	return cc.Enabled == nil || *cc.Enabled
+}
+
+// NotifyFilter 渠道的过滤条件，列表为空表示不限制；事件不含对应字段时（如价格告警没有策略）不受该条件限制
+type NotifyFilter struct {
+	// Events 可选 signal, fill, price_alert, alert（用户告警规则）, risk, test
+	Events     []string `mapstructure:"events"`
+	Strategies []string `mapstructure:"strategies"`
+	// Actions 可选 BUY, SELL
+	Actions []string `mapstructure:"actions"`
+	Symbols []string `mapstructure:"symbols"`
+	// MinSeverity 可选 info, warning, critical，为空表示 info
+	MinSeverity string `mapstructure:"minSeverity"`
+}
+
+// AnalysisConfig /api/analysis 市场分析的趋势判断设置，策略的均线类型在各自的 params 中设置
+type AnalysisConfig struct {
+	TrendMAType      string `mapstructure:"trendMaType"`
+	TrendShortPeriod int    `mapstructure:"trendShortPeriod"`
+	TrendLongPeriod  int    `mapstructure:"trendLongPeriod"`
 }
 
+// ScriptConfig Starlark 脚本策略设置，资源上限对每次加载和每次评估分别生效
+type ScriptConfig struct {
+	// Dir 脚本目录，其中的 *.star 文件作为策略加载，文件修改后自动重新加载；为空表示不启用
+	Dir      string        `mapstructure:"dir"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+	MaxSteps uint64        `mapstructure:"maxSteps"`
+	// MaxMemoryMB 单次执行累计分配的内存上限（MB），按运算和内置函数的结果大小估算
+	MaxMemoryMB int `mapstructure:"maxMemoryMB"`
+}
+
+// LoadConfig 按以下优先级（由低到高）加载配置:
+// 默认值 < 配置文件 < 加密密钥文件 < 环境变量 < 环境变量 *_FILE 指向的文件
 func LoadConfig() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath(".")
 	viper.AddConfigPath("./config")
+	setDefaults(viper.GetViper())
 
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, err
 	}
 
+	return decode(viper.GetViper())
+}
+
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("dbPath", "./data/eth_trading.db")
+	v.SetDefault("serverPort", ":8080")
+	v.SetDefault("logDir", "./logs")
+	v.SetDefault("logLevel", "info")
+	v.SetDefault("timeframes", []string{"5Min", "15Min", "1Hour", "4Hour", "1Day"})
+	v.SetDefault("strategies", []map[string]interface{}{
+		{"name": "SimpleMA", "type": "simple_ma", "params": map[string]interface{}{"shortPeriod": 10, "longPeriod": 30}},
+		{"name": "MACD", "type": "macd"},
+	})
+	v.SetDefault("alerts.priceMovePercent", 0)
+	v.SetDefault("analysis.trendMaType", "sma")
+	v.SetDefault("analysis.trendShortPeriod", 10)
+	v.SetDefault("analysis.trendLongPeriod", 30)
+	v.SetDefault("trading.enabled", false)
+	v.SetDefault("trading.initialCapital", 10000)
+	v.SetDefault("trading.feeRate", 0.001)
+	v.SetDefault("trading.snapshotInterval", "1h")
+	v.SetDefault("trading.sizing.method", "fixed_quote")
+	v.SetDefault("trading.sizing.quoteAmount", 300)
+	v.SetDefault("trading.sizing.fraction", 0.1)
+	v.SetDefault("trading.sizing.kellyFraction", 0.5)
+	v.SetDefault("trading.sizing.kellyMinTrades", 20)
+	v.SetDefault("trading.sizing.targetVolatility", 0.01)
+	v.SetDefault("trading.sizing.atrPeriod", 14)
+	v.SetDefault("trading.sizing.maxFraction", 1)
+	v.SetDefault("trading.protection.atrPeriod", 14)
+	v.SetDefault("risk.killSwitch", false)
+	v.SetDefault("notifications.dedupWindow", "10m")
+	v.SetDefault("notifications.timeout", "10s")
+	v.SetDefault("notifications.maxAttempts", 3)
+	v.SetDefault("notifications.initialBackoff", "1s")
+	v.SetDefault("notifications.maxBackoff", "30s")
+	v.SetDefault("scripts.timeout", "1s")
+	v.SetDefault("scripts.maxSteps", 10000000)
+	v.SetDefault("scripts.maxMemoryMB", 64)
+}
+
+// decode 合并密钥来源并解析、校验配置，启动和热更新共用
+func decode(v *viper.Viper) (*Config, error) {
+	bindings := bindEnvs(v, reflect.TypeOf(Config{}), "", "")
+
+	if secretsFile := v.GetString("secretsFile"); secretsFile != "" {
+		if err := mergeSecretsFile(v, secretsFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyFileEnvs(v, bindings); err != nil {
+		return nil, err
+	}
+
 	var config Config
-	err := viper.Unmarshal(&config)
-	return &config, err
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// SecretValues 返回所有标记为 secret 的非空字段值，用于日志脱敏
+func (c *Config) SecretValues() []string {
+	return collectSecrets(reflect.ValueOf(*c))
+}
+
+func collectSecrets(v reflect.Value) []string {
+	var secrets []string
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := v.Field(i)
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			secrets = append(secrets, collectSecrets(value)...)
+			continue
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+			for j := 0; j < value.Len(); j++ {
+				secrets = append(secrets, collectSecrets(value.Index(j))...)
+			}
+			continue
+		}
+		if field.Tag.Get("secret") == "true" && value.Kind() == reflect.String && value.String() != "" {
+			secrets = append(secrets, value.String())
+		}
+	}
+	return secrets
 }
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix 是所有配置环境变量的前缀，例如 ETHBOT_ALPACA_API_KEY
+const EnvPrefix = "ETHBOT"
+
+type envBinding struct {
+	key string
+	env string
+}
+
+// bindEnvs 递归遍历配置结构体，为每个标量字段绑定对应的环境变量
+func bindEnvs(v *viper.Viper, t reflect.Type, keyPrefix, envPrefix string) []envBinding {
+	var bindings []envBinding
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		key := fieldKey(field)
+		if keyPrefix != "" {
+			key = keyPrefix + "." + key
+		}
+		env := toSnake(field.Name)
+		if envPrefix != "" {
+			env = envPrefix + "_" + env
+		}
+
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			bindings = append(bindings, bindEnvs(v, field.Type, key, env)...)
+			continue
+		case reflect.Map, reflect.Ptr, reflect.Interface, reflect.Func, reflect.Chan:
+			continue
+		case reflect.Slice:
+			if field.Type.Elem().Kind() != reflect.String {
+				continue
+			}
+		}
+
+		name := EnvPrefix + "_" + env
+		v.BindEnv(key, name)
+		bindings = append(bindings, envBinding{key: key, env: name})
+	}
+	return bindings
+}
+
+// applyFileEnvs 处理 Docker/K8s secrets 常用的 *_FILE 约定：
+// 若设置了 ETHBOT_XXX_FILE，则读取该文件内容作为 ETHBOT_XXX 的值
+func applyFileEnvs(v *viper.Viper, bindings []envBinding) error {
+	for _, b := range bindings {
+		path, ok := os.LookupEnv(b.env + "_FILE")
+		if !ok {
+			continue
+		}
+		if _, set := os.LookupEnv(b.env); set {
+			return fmt.Errorf("环境变量 %s 与 %s_FILE 不能同时设置", b.env, b.env)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("读取 %s_FILE 失败: %v", b.env, err)
+		}
+		v.Set(b.key, strings.TrimSpace(string(content)))
+	}
+	return nil
+}
+
+// envName 返回顶层字段对应的环境变量名
+func envName(fieldName string) string {
+	return EnvPrefix + "_" + toSnake(fieldName)
+}
+
+// toSnake 将 Go 字段名转换为大写下划线形式，保留连续的缩写，
+// 例如 AlpacaAPIKey -> ALPACA_API_KEY，DBPath -> DB_PATH
+func toSnake(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
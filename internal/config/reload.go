@@ -0,0 +1,220 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/qqqq/eth-trading-system/internal/utils"
+	"github.com/spf13/viper"
+)
+
+// Change 描述一次热更新中单个配置项的变化
+type Change struct {
+	Field string
+	Old   interface{}
+	New   interface{}
+}
+
+// reloadDebounce 合并编辑器保存文件时产生的连续事件，避免读到截断后的半成品文件
+const reloadDebounce = 300 * time.Millisecond
+
+// Watcher 监听配置文件变化，校验通过后将可热更新的配置项下发给订阅者
+type Watcher struct {
+	mu        sync.RWMutex
+	current   *Config
+	listeners []func(cfg *Config, changes []Change)
+	timer     *time.Timer
+}
+
+func NewWatcher(cfg *Config) *Watcher {
+	return &Watcher{current: cfg}
+}
+
+// Current 返回当前生效的配置
+func (w *Watcher) Current() *Config {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// OnReload 注册热更新回调，仅在可热更新的配置项发生变化时调用
+func (w *Watcher) OnReload(fn func(cfg *Config, changes []Change)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.listeners = append(w.listeners, fn)
+}
+
+// Start 开始监听配置文件
+func (w *Watcher) Start() {
+	viper.OnConfigChange(func(e fsnotify.Event) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.timer != nil {
+			w.timer.Stop()
+		}
+		w.timer = time.AfterFunc(reloadDebounce, func() {
+			if err := viper.ReadInConfig(); err != nil {
+				utils.Log.WithError(err).Error("重新读取配置文件失败，继续使用原配置")
+				return
+			}
+			w.reload(viper.GetViper())
+		})
+	})
+	viper.WatchConfig()
+	utils.Log.Infof("开始监听配置文件: %s", viper.ConfigFileUsed())
+}
+
+func (w *Watcher) reload(v *viper.Viper) {
+	next, err := decode(v)
+	if err != nil {
+		utils.Log.WithError(err).Error("配置文件已修改但校验失败，继续使用原配置")
+		return
+	}
+
+	w.mu.Lock()
+	prev := w.current
+	reloadable, restartRequired := Diff(prev, next)
+	for _, c := range restartRequired {
+		utils.Log.WithField("field", c.Field).Warn("该配置项不支持热更新，需重启后生效")
+	}
+	if len(reloadable) == 0 {
+		w.mu.Unlock()
+		return
+	}
+
+	// 仅采纳可热更新的字段，其余字段保持启动时的值
+	applied := *prev
+	applyReloadable(reflect.ValueOf(&applied).Elem(), reflect.ValueOf(*next))
+	w.current = &applied
+	listeners := append([]func(*Config, []Change){}, w.listeners...)
+	w.mu.Unlock()
+
+	for _, c := range reloadable {
+		utils.Log.WithFields(map[string]interface{}{
+			"field": c.Field,
+			"old":   formatValue(c.Old),
+			"new":   formatValue(c.New),
+		}).Info("配置已热更新")
+	}
+
+	for _, fn := range listeners {
+		fn(&applied, reloadable)
+	}
+}
+
+// Diff 比较两份配置，分别返回可热更新和需要重启的变化项
+func Diff(old, new *Config) (reloadable, restartRequired []Change) {
+	diffStruct(reflect.ValueOf(*old), reflect.ValueOf(*new), "", false, &reloadable, &restartRequired)
+	return reloadable, restartRequired
+}
+
+func diffStruct(old, new reflect.Value, prefix string, reload bool, reloadable, restartRequired *[]Change) {
+	t := old.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := fieldKey(field)
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		fieldReload := reload || field.Tag.Get("reload") == "true"
+
+		if field.Type.Kind() == reflect.Struct {
+			diffStruct(old.Field(i), new.Field(i), name, fieldReload, reloadable, restartRequired)
+			continue
+		}
+
+		o, n := old.Field(i).Interface(), new.Field(i).Interface()
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+		if field.Tag.Get("secret") == "true" {
+			o, n = redactedValue, redactedValue
+		} else if field.Type.Kind() == reflect.Slice {
+			o, n = redactSecrets(old.Field(i)).Interface(), redactSecrets(new.Field(i)).Interface()
+		}
+		change := Change{Field: name, Old: o, New: n}
+		if fieldReload {
+			*reloadable = append(*reloadable, change)
+		} else {
+			*restartRequired = append(*restartRequired, change)
+		}
+	}
+}
+
+func applyReloadable(dst, src reflect.Value) {
+	t := dst.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Tag.Get("reload") == "true" {
+			dst.Field(i).Set(src.Field(i))
+		} else if field.Type.Kind() == reflect.Struct {
+			applyReloadable(dst.Field(i), src.Field(i))
+		}
+	}
+}
+
+const redactedValue = "[REDACTED]"
+
+// redactSecrets 返回结构体切片的副本，其中标记为 secret 的非空字段替换为占位符，用于记录变化
+func redactSecrets(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(redactSecrets(v.Index(i)))
+		}
+		return out
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if field.Tag.Get("secret") == "true" && field.Type.Kind() == reflect.String && v.Field(i).String() != "" {
+				out.Field(i).SetString(redactedValue)
+			} else {
+				out.Field(i).Set(redactSecrets(v.Field(i)))
+			}
+		}
+		return out
+	}
+	return v
+}
+
+// formatValue 将复合类型格式化为 JSON，便于在日志中阅读
+func formatValue(v interface{}) string {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Slice, reflect.Map, reflect.Struct, reflect.Ptr:
+		if data, err := json.Marshal(v); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func fieldKey(field reflect.StructField) string {
+	if tag := field.Tag.Get("mapstructure"); tag != "" && tag != "-" {
+		for i, c := range tag {
+			if c == ',' {
+				return tag[:i]
+			}
+		}
+		return tag
+	}
+	return field.Name
+}
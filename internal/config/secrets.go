@@ -0,0 +1,134 @@
+package config
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 加密密钥文件格式: magic | salt(16) | nonce(24) | secretbox 密文
+// 密钥由口令经 scrypt 派生，明文为 YAML 格式的配置片段，例如:
+//
+//	alpacaAPIKey: "..."
+//	alpacaAPISecret: "..."
+const (
+	secretsMagic   = "ETHBOT-SECRETS-v1\n"
+	saltSize       = 16
+	nonceSize      = 24
+	keySize        = 32
+	scryptN        = 1 << 15
+	scryptR        = 8
+	scryptP        = 1
+	passphraseEnv  = EnvPrefix + "_SECRETS_PASSPHRASE"
+	minSecretsSize = len(secretsMagic) + saltSize + nonceSize + secretbox.Overhead
+)
+
+var ErrInvalidPassphrase = errors.New("密钥文件解密失败：口令错误或文件已损坏")
+
+// EncryptSecrets 使用口令加密明文配置片段
+func EncryptSecrets(plaintext []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("口令不能为空")
+	}
+
+	var salt [saltSize]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		return nil, err
+	}
+	var nonce [nonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+
+	key, err := deriveKey(passphrase, salt[:])
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, minSecretsSize+len(plaintext))
+	out = append(out, secretsMagic...)
+	out = append(out, salt[:]...)
+	out = append(out, nonce[:]...)
+	return secretbox.Seal(out, plaintext, &nonce, key), nil
+}
+
+// DecryptSecrets 使用口令解密由 EncryptSecrets 生成的数据
+func DecryptSecrets(data []byte, passphrase string) ([]byte, error) {
+	if len(data) < minSecretsSize || !bytes.HasPrefix(data, []byte(secretsMagic)) {
+		return nil, errors.New("无法识别的密钥文件格式")
+	}
+	data = data[len(secretsMagic):]
+
+	salt := data[:saltSize]
+	var nonce [nonceSize]byte
+	copy(nonce[:], data[saltSize:saltSize+nonceSize])
+
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, ok := secretbox.Open(nil, data[saltSize+nonceSize:], &nonce, key)
+	if !ok {
+		return nil, ErrInvalidPassphrase
+	}
+	return plaintext, nil
+}
+
+// SecretsPassphrase 从 ETHBOT_SECRETS_PASSPHRASE 或 ETHBOT_SECRETS_PASSPHRASE_FILE 读取口令
+func SecretsPassphrase() (string, error) {
+	if path, ok := os.LookupEnv(passphraseEnv + "_FILE"); ok {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("读取 %s_FILE 失败: %v", passphraseEnv, err)
+		}
+		return strings.TrimSpace(string(content)), nil
+	}
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	return "", fmt.Errorf("已配置加密密钥文件，但未设置 %s 或 %s_FILE", passphraseEnv, passphraseEnv)
+}
+
+// mergeSecretsFile 解密密钥文件并合并到配置层，其优先级低于环境变量
+func mergeSecretsFile(v *viper.Viper, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取密钥文件失败: %v", err)
+	}
+
+	passphrase, err := SecretsPassphrase()
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := DecryptSecrets(data, passphrase)
+	if err != nil {
+		return err
+	}
+
+	secrets := viper.New()
+	secrets.SetConfigType("yaml")
+	if err := secrets.ReadConfig(bytes.NewReader(plaintext)); err != nil {
+		return fmt.Errorf("解析密钥文件内容失败: %v", err)
+	}
+
+	return v.MergeConfigMap(secrets.AllSettings())
+}
+
+func deriveKey(passphrase string, salt []byte) (*[keySize]byte, error) {
+	derived, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, err
+	}
+	var key [keySize]byte
+	copy(key[:], derived)
+	return &key, nil
+}
@@ -0,0 +1,358 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/qqqq/eth-trading-system/internal/analysis/indicators"
+	"github.com/qqqq/eth-trading-system/internal/models"
+	"github.com/sirupsen/logrus"
+)
+
+// FieldError 描述单个配置项的校验错误
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationError 汇总所有配置校验错误，便于一次性修正
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	lines := make([]string, 0, len(e)+1)
+	lines = append(lines, "配置校验失败:")
+	for _, fe := range e {
+		lines = append(lines, "  - "+fe.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate 检查配置是否完整、合法
+func (c *Config) Validate() error {
+	var errs ValidationError
+	add := func(field, format string, args ...interface{}) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if c.AlpacaAPIKey == "" {
+		add("alpacaAPIKey", "不能为空，请通过 %s、%s_FILE 或加密密钥文件提供", envName("AlpacaAPIKey"), envName("AlpacaAPIKey"))
+	}
+	if c.AlpacaAPISecret == "" {
+		add("alpacaAPISecret", "不能为空，请通过 %s、%s_FILE 或加密密钥文件提供", envName("AlpacaAPISecret"), envName("AlpacaAPISecret"))
+	}
+	if c.DBPath == "" {
+		add("dbPath", "不能为空")
+	}
+	if c.LogDir == "" {
+		add("logDir", "不能为空")
+	}
+	if err := validateServerPort(c.ServerPort); err != nil {
+		add("serverPort", "%v", err)
+	}
+	if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+		add("logLevel", "无效的日志级别 %q，可选值: panic, fatal, error, warn, info, debug, trace", c.LogLevel)
+	}
+
+	if len(c.Timeframes) == 0 {
+		add("timeframes", "至少需要启用一个时间框架")
+	}
+	seen := make(map[string]bool)
+	for i, tf := range c.Timeframes {
+		if !models.IsValidTimeframe(tf) || tf == "1Min" {
+			add(fmt.Sprintf("timeframes[%d]", i), "不支持的时间框架 %q，可选值: 5Min, 15Min, 1Hour, 4Hour, 1Day", tf)
+		}
+		if seen[tf] {
+			add(fmt.Sprintf("timeframes[%d]", i), "时间框架 %q 重复", tf)
+		}
+		seen[tf] = true
+	}
+
+	if len(c.Strategies) == 0 {
+		add("strategies", "至少需要配置一个策略")
+	}
+	validateStrategies(c.Strategies, "strategies", add)
+
+	if c.Alerts.PriceMovePercent < 0 {
+		add("alerts.priceMovePercent", "不能为负数，当前为 %v", c.Alerts.PriceMovePercent)
+	}
+
+	if _, err := indicators.NewMovingAverage(indicators.MovingAverageType(c.Analysis.TrendMAType), 1); err != nil {
+		add("analysis.trendMaType", "%v", err)
+	}
+	if c.Analysis.TrendShortPeriod <= 0 || c.Analysis.TrendShortPeriod >= c.Analysis.TrendLongPeriod {
+		add("analysis.trendShortPeriod", "必须大于 0 且小于 trendLongPeriod，当前为 %d/%d", c.Analysis.TrendShortPeriod, c.Analysis.TrendLongPeriod)
+	}
+
+	if c.Trading.InitialCapital <= 0 {
+		add("trading.initialCapital", "必须大于 0，当前为 %v", c.Trading.InitialCapital)
+	}
+	if c.Trading.FeeRate < 0 || c.Trading.FeeRate >= 1 {
+		add("trading.feeRate", "必须在 [0, 1) 范围内，当前为 %v", c.Trading.FeeRate)
+	}
+	if c.Trading.SnapshotInterval < 0 {
+		add("trading.snapshotInterval", "不能为负数")
+	}
+	validateSizing(c.Trading.Sizing, add)
+	validateProtection(c.Trading.Protection, add)
+	validateRisk(c.Risk, add)
+	validateNotifications(c.Notifications, add)
+
+	if c.Scripts.Timeout <= 0 {
+		add("scripts.timeout", "必须大于 0")
+	}
+	if c.Scripts.MaxSteps == 0 {
+		add("scripts.maxSteps", "必须大于 0")
+	}
+	if c.Scripts.MaxMemoryMB <= 0 {
+		add("scripts.maxMemoryMB", "必须大于 0，当前为 %d", c.Scripts.MaxMemoryMB)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateSizing 检查仓位计算方式及其所需参数
+func validateSizing(sz SizingConfig, add func(field, format string, args ...interface{})) {
+	switch sz.Method {
+	case "fixed_quote":
+		if sz.QuoteAmount <= 0 {
+			add("trading.sizing.quoteAmount", "fixed_quote 方式下必须大于 0")
+		}
+	case "fixed_fraction", "kelly":
+		if sz.Fraction <= 0 || sz.Fraction > 1 {
+			add("trading.sizing.fraction", "必须在 (0, 1] 范围内，当前为 %v", sz.Fraction)
+		}
+		if sz.Method == "kelly" && (sz.KellyFraction <= 0 || sz.KellyFraction > 1) {
+			add("trading.sizing.kellyFraction", "必须在 (0, 1] 范围内，当前为 %v", sz.KellyFraction)
+		}
+	case "volatility":
+		if sz.TargetVolatility <= 0 {
+			add("trading.sizing.targetVolatility", "volatility 方式下必须大于 0")
+		}
+		if sz.ATRPeriod < 1 {
+			add("trading.sizing.atrPeriod", "必须大于 0")
+		}
+	default:
+		add("trading.sizing.method", "不支持的仓位计算方式 %q，可选值: fixed_quote, fixed_fraction, kelly, volatility", sz.Method)
+	}
+	if sz.MaxFraction < 0 || sz.MaxFraction > 1 {
+		add("trading.sizing.maxFraction", "必须在 [0, 1] 范围内，当前为 %v", sz.MaxFraction)
+	}
+	if sz.MaxQuote < 0 {
+		add("trading.sizing.maxQuote", "不能为负数")
+	}
+	for symbol, rules := range sz.Symbols {
+		if rules.MinQuantity < 0 || rules.MinNotional < 0 || rules.LotStep < 0 {
+			add("trading.sizing.symbols."+symbol, "minQuantity、minNotional 和 lotStep 不能为负数")
+		}
+	}
+}
+
+// validateProtection 检查保护性订单的计算方式及其参数
+func validateProtection(p ProtectionConfig, add func(field, format string, args ...interface{})) {
+	levels := []struct {
+		field   string
+		level   LevelConfig
+		allowed []string
+	}{
+		{"trading.protection.stopLoss", p.StopLoss, []string{"percent", "atr"}},
+		{"trading.protection.takeProfit", p.TakeProfit, []string{"percent", "atr", "r"}},
+		{"trading.protection.trailing", p.Trailing, []string{"percent", "atr"}},
+	}
+	usesATR := false
+	for _, l := range levels {
+		switch l.level.Type {
+		case "":
+			continue
+		case "percent":
+			if l.level.Percent <= 0 || l.level.Percent >= 100 {
+				add(l.field+".percent", "必须在 (0, 100) 范围内，当前为 %v", l.level.Percent)
+			}
+		case "atr":
+			usesATR = true
+			if l.level.ATRMultiple <= 0 {
+				add(l.field+".atrMultiple", "必须大于 0，当前为 %v", l.level.ATRMultiple)
+			}
+		case "r":
+			if l.level.RMultiple <= 0 {
+				add(l.field+".rMultiple", "必须大于 0，当前为 %v", l.level.RMultiple)
+			}
+		}
+		if !contains(l.allowed, l.level.Type) {
+			add(l.field+".type", "不支持的类型 %q，可选值: %s", l.level.Type, strings.Join(l.allowed, ", "))
+		}
+	}
+	if usesATR && p.ATRPeriod < 1 {
+		add("trading.protection.atrPeriod", "必须大于 0")
+	}
+	if p.TakeProfit.Type == "r" && p.StopLoss.Type == "" && !p.UseSignalLevels {
+		add("trading.protection.takeProfit.type", "r 需要设置 stopLoss 以确定初始风险")
+	}
+	if p.BreakevenR < 0 {
+		add("trading.protection.breakevenR", "不能为负数")
+	}
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRisk 检查风控限制均不为负数
+func validateRisk(r RiskConfig, add func(field, format string, args ...interface{})) {
+	limits := []struct {
+		field string
+		value float64
+	}{
+		{"risk.maxPositionNotional", r.MaxPositionNotional},
+		{"risk.maxOrderNotional", r.MaxOrderNotional},
+		{"risk.maxOrderQuantity", r.MaxOrderQuantity},
+		{"risk.maxOrdersPerHour", float64(r.MaxOrdersPerHour)},
+		{"risk.dailyLossLimit", r.DailyLossLimit},
+		{"risk.maxDrawdownPercent", r.MaxDrawdownPercent},
+		{"risk.maxConsecutiveLosses", float64(r.MaxConsecutiveLosses)},
+		{"risk.lossCooldown", float64(r.LossCooldown)},
+	}
+	for _, l := range limits {
+		if l.value < 0 {
+			add(l.field, "不能为负数")
+		}
+	}
+	if r.MaxDrawdownPercent >= 100 {
+		add("risk.maxDrawdownPercent", "必须小于 100，当前为 %v", r.MaxDrawdownPercent)
+	}
+	if r.MaxConsecutiveLosses > 0 && r.LossCooldown == 0 {
+		add("risk.lossCooldown", "设置 maxConsecutiveLosses 时必须大于 0")
+	}
+}
+
+// validateNotifications 检查重试设置、各渠道的必填项、模板和过滤条件
+func validateNotifications(n NotifyConfig, add func(field, format string, args ...interface{})) {
+	if n.DedupWindow < 0 {
+		add("notifications.dedupWindow", "不能为负数")
+	}
+	if n.Timeout <= 0 {
+		add("notifications.timeout", "必须大于 0")
+	}
+	if n.MaxAttempts < 1 {
+		add("notifications.maxAttempts", "必须大于 0，当前为 %d", n.MaxAttempts)
+	}
+	if n.InitialBackoff < 0 || n.MaxBackoff < n.InitialBackoff {
+		add("notifications.maxBackoff", "initialBackoff 不能为负数且不能大于 maxBackoff")
+	}
+
+	names := make(map[string]bool)
+	for i, ch := range n.Channels {
+		field := fmt.Sprintf("notifications.channels[%d]", i)
+		if ch.Name != "" {
+			field = fmt.Sprintf("%s (%s)", field, ch.Name)
+		}
+		if ch.Name == "" {
+			add(field, "name 不能为空")
+		} else if names[ch.Name] {
+			add(field, "渠道名称 %q 重复", ch.Name)
+		}
+		names[ch.Name] = true
+
+		// 未启用的渠道只检查类型、模板和过滤条件，允许保留未填写的示例配置
+		required := ch.IsEnabled()
+		switch ch.Type {
+		case "webhook", "slack":
+			if required && ch.URL == "" {
+				add(field+".url", "%s 渠道不能为空", ch.Type)
+			}
+		case "telegram":
+			if required && (ch.BotToken == "" || ch.ChatID == "") {
+				add(field, "telegram 渠道需要设置 botToken 和 chatID")
+			}
+		case "email":
+			if required && (ch.SMTPHost == "" || ch.SMTPPort < 1 || ch.SMTPPort > 65535) {
+				add(field, "email 渠道需要设置 smtpHost 和有效的 smtpPort")
+			}
+			if required && (ch.From == "" || len(ch.To) == 0) {
+				add(field, "email 渠道需要设置 from 和 to")
+			}
+		default:
+			add(field+".type", "不支持的渠道类型 %q，可选值: webhook, slack, telegram, email", ch.Type)
+		}
+
+		for name, text := range map[string]string{"title": ch.Title, "body": ch.Body} {
+			if _, err := template.New(name).Parse(text); err != nil {
+				add(field+"."+name, "模板解析失败: %v", err)
+			}
+		}
+
+		for _, event := range ch.Filter.Events {
+			if !contains([]string{"signal", "fill", "price_alert", "alert", "risk", "test"}, event) {
+				add(field+".filter.events", "不支持的事件类型 %q，可选值: signal, fill, price_alert, alert, risk, test", event)
+			}
+		}
+		for _, action := range ch.Filter.Actions {
+			if action != string(models.ActionBuy) && action != string(models.ActionSell) {
+				add(field+".filter.actions", "不支持的动作 %q，可选值: BUY, SELL", action)
+			}
+		}
+		if ch.Filter.MinSeverity != "" && !contains([]string{"info", "warning", "critical"}, ch.Filter.MinSeverity) {
+			add(field+".filter.minSeverity", "不支持的级别 %q，可选值: info, warning, critical", ch.Filter.MinSeverity)
+		}
+	}
+}
+
+// validateServerPort 接受 ":8080" 或 "host:8080" 形式
+func validateServerPort(addr string) error {
+	if addr == "" {
+		return fmt.Errorf("不能为空")
+	}
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("格式应为 \":8080\" 或 \"host:8080\"，当前为 %q", addr)
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("端口必须是 1-65535 之间的整数，当前为 %q", port)
+	}
+	return nil
+}
+
+// validateStrategies 检查策略配置的结构，参数的具体含义由 strategy.Registry 在构建时校验
+func validateStrategies(strategies []StrategyConfig, path string, add func(field, format string, args ...interface{})) {
+	names := make(map[string]bool)
+	for i, sc := range strategies {
+		field := fmt.Sprintf("%s[%d]", path, i)
+		if sc.Name != "" {
+			field = fmt.Sprintf("%s (%s)", field, sc.Name)
+		}
+
+		if sc.Name == "" {
+			add(field, "name 不能为空")
+		} else if names[sc.Name] {
+			add(field, "策略名称 %q 重复", sc.Name)
+		}
+		names[sc.Name] = true
+
+		if sc.Type == "" {
+			add(field, "type 不能为空")
+		}
+		if sc.Timeframe != "" && !models.IsValidTimeframe(sc.Timeframe) {
+			add(field, "不支持的时间框架 %q", sc.Timeframe)
+		}
+		if sc.Weight < 0 {
+			add(field, "weight 不能为负数，当前为 %v", sc.Weight)
+		}
+
+		validateStrategies(sc.Children, fmt.Sprintf("%s[%d].children", path, i), add)
+	}
+}
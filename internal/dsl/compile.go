@@ -0,0 +1,336 @@
+package dsl
+
+import (
+	"math"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// Type 表达式的类型。两种类型都是与K线对齐的序列
+type Type int
+
+const (
+	TypeNumber Type = iota
+	TypeBool
+)
+
+func (t Type) String() string {
+	if t == TypeBool {
+		return "布尔"
+	}
+	return "数值"
+}
+
+// env 一次评估的上下文，cache 按调用的源码缓存函数结果，避免重复计算相同指标
+type env struct {
+	bars  []models.Bar
+	cache map[string][]float64
+}
+
+// evalFunc 返回与 bars 对齐的序列。数值序列在数据不足处为 NaN，布尔序列用 1 和 0 表示
+type evalFunc func(e *env) []float64
+
+type compiled struct {
+	typ  Type
+	eval evalFunc
+}
+
+// Program 编译后的表达式，可并发评估
+type Program struct {
+	source string
+	root   compiled
+	// values 表达式中返回数值的函数调用，按出现顺序，用于在信号中展示指标值
+	values []namedSeries
+}
+
+type namedSeries struct {
+	label string
+	eval  evalFunc
+}
+
+// Compile 解析并检查表达式，结果可以是数值或布尔
+func Compile(src string) (*Program, error) {
+	node, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	c := &compiler{src: src, seen: make(map[string]bool)}
+	root, err := c.compile(node)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{source: src, root: root, values: c.values}, nil
+}
+
+// CompileCondition 编译结果必须为布尔的条件表达式
+func CompileCondition(src string) (*Program, error) {
+	p, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	if p.root.typ != TypeBool {
+		node, _ := Parse(src)
+		return nil, errorf(start(node), "条件表达式的结果必须是布尔值，当前为%s，是否缺少比较运算？", p.root.typ)
+	}
+	return p, nil
+}
+
+// Source 返回表达式源码
+func (p *Program) Source() string {
+	return p.source
+}
+
+// Type 返回表达式的类型
+func (p *Program) Type() Type {
+	return p.root.typ
+}
+
+// Eval 返回与 bars 对齐的结果序列
+func (p *Program) Eval(bars []models.Bar) []float64 {
+	return p.root.eval(newEnv(bars))
+}
+
+// Result 一次评估的结果及表达式中各指标在最后一根K线上的值
+type Result struct {
+	Series []float64
+	Values map[string]float64
+}
+
+// Evaluate 评估整个序列，并返回各指标在最后一根K线上的值，NaN 的指标不返回
+func (p *Program) Evaluate(bars []models.Bar) Result {
+	e := newEnv(bars)
+	result := Result{Series: p.root.eval(e), Values: make(map[string]float64, len(p.values))}
+	if len(bars) == 0 {
+		return result
+	}
+	for _, v := range p.values {
+		if last := v.eval(e)[len(bars)-1]; !math.IsNaN(last) {
+			result.Values[v.label] = last
+		}
+	}
+	return result
+}
+
+func newEnv(bars []models.Bar) *env {
+	return &env{bars: bars, cache: make(map[string][]float64)}
+}
+
+type compiler struct {
+	src    string
+	values []namedSeries
+	seen   map[string]bool
+}
+
+// start 返回节点在源码中的起始位置，二元运算的位置是运算符，这里取左操作数的起点
+func start(n Node) Pos {
+	if b, ok := n.(*Binary); ok {
+		return start(b.X)
+	}
+	return n.Pos()
+}
+
+func (c *compiler) compile(n Node) (compiled, error) {
+	switch n := n.(type) {
+	case *NumberLit:
+		v := n.Value
+		return compiled{typ: TypeNumber, eval: func(e *env) []float64 { return constant(len(e.bars), v) }}, nil
+	case *Ident:
+		field, ok := barFields[n.Name]
+		if !ok {
+			if _, isFunc := functions[n.Name]; isFunc {
+				return compiled{}, errorf(n.At, "%s 是函数，需要加括号调用，如 %s", n.Name, functions[n.Name].usage(n.Name))
+			}
+			return compiled{}, errorf(n.At, "未知的标识符 %q，可用的行情字段: open, high, low, close, volume", n.Name)
+		}
+		return compiled{typ: TypeNumber, eval: func(e *env) []float64 {
+			values := make([]float64, len(e.bars))
+			for i, bar := range e.bars {
+				values[i] = field(bar)
+			}
+			return values
+		}}, nil
+	case *Unary:
+		x, err := c.compile(n.X)
+		if err != nil {
+			return compiled{}, err
+		}
+		if n.Op == "not" {
+			if x.typ != TypeBool {
+				return compiled{}, errorf(start(n.X), "not 需要布尔操作数，当前为%s", x.typ)
+			}
+			return compiled{typ: TypeBool, eval: mapSeries(x.eval, func(v float64) float64 { return boolValue(v == 0) })}, nil
+		}
+		if x.typ != TypeNumber {
+			return compiled{}, errorf(start(n.X), "取负需要数值操作数，当前为%s", x.typ)
+		}
+		return compiled{typ: TypeNumber, eval: mapSeries(x.eval, func(v float64) float64 { return -v })}, nil
+	case *Binary:
+		return c.binary(n)
+	case *Call:
+		return c.call(n)
+	}
+	return compiled{}, errorf(n.Pos(), "不支持的语法")
+}
+
+func (c *compiler) binary(n *Binary) (compiled, error) {
+	x, err := c.compile(n.X)
+	if err != nil {
+		return compiled{}, err
+	}
+	y, err := c.compile(n.Y)
+	if err != nil {
+		return compiled{}, err
+	}
+
+	want, result := TypeNumber, TypeNumber
+	var op func(a, b float64) float64
+	switch n.Op {
+	case "and":
+		want, result = TypeBool, TypeBool
+		op = func(a, b float64) float64 { return boolValue(a != 0 && b != 0) }
+	case "or":
+		want, result = TypeBool, TypeBool
+		op = func(a, b float64) float64 { return boolValue(a != 0 || b != 0) }
+	case "+":
+		op = func(a, b float64) float64 { return a + b }
+	case "-":
+		op = func(a, b float64) float64 { return a - b }
+	case "*":
+		op = func(a, b float64) float64 { return a * b }
+	case "/":
+		op = func(a, b float64) float64 {
+			if b == 0 {
+				return math.NaN()
+			}
+			return a / b
+		}
+	default:
+		// 比较运算，任一侧为 NaN 时结果为假
+		result = TypeBool
+		op = comparisons[n.Op]
+	}
+
+	if x.typ != want {
+		return compiled{}, errorf(start(n.X), "运算符 %s 的左侧需要%s，当前为%s", n.Op, want, x.typ)
+	}
+	if y.typ != want {
+		return compiled{}, errorf(start(n.Y), "运算符 %s 的右侧需要%s，当前为%s", n.Op, want, y.typ)
+	}
+	return compiled{typ: result, eval: zipSeries(x.eval, y.eval, op)}, nil
+}
+
+var comparisons = map[string]func(a, b float64) float64{
+	"<":  func(a, b float64) float64 { return boolValue(a < b) },
+	"<=": func(a, b float64) float64 { return boolValue(a <= b) },
+	">":  func(a, b float64) float64 { return boolValue(a > b) },
+	">=": func(a, b float64) float64 { return boolValue(a >= b) },
+	"==": func(a, b float64) float64 { return boolValue(a == b) },
+	"!=": func(a, b float64) float64 { return boolValue(!math.IsNaN(a) && !math.IsNaN(b) && a != b) },
+}
+
+func (c *compiler) call(n *Call) (compiled, error) {
+	fn, ok := functions[n.Name]
+	if !ok {
+		if _, isField := barFields[n.Name]; isField {
+			return compiled{}, errorf(n.At, "%s 是行情字段，不能作为函数调用", n.Name)
+		}
+		return compiled{}, errorf(n.At, "未知函数 %q", n.Name)
+	}
+	var sig []param
+	for _, s := range fn.signatures {
+		if len(s) == len(n.Args) {
+			sig = s
+			break
+		}
+	}
+	if sig == nil {
+		return compiled{}, errorf(n.At, "%s 不接受 %d 个参数，用法: %s", n.Name, len(n.Args), fn.usage(n.Name))
+	}
+
+	args := make([]argument, len(n.Args))
+	for i, p := range sig {
+		a := n.Args[i]
+		switch p.kind {
+		case argSeries:
+			arg, err := c.compile(a)
+			if err != nil {
+				return compiled{}, err
+			}
+			if arg.typ != TypeNumber {
+				return compiled{}, errorf(start(a), "%s 的参数 %s 需要数值，当前为布尔", n.Name, p.name)
+			}
+			args[i].series = arg.eval
+		case argPeriod:
+			lit, ok := a.(*NumberLit)
+			if !ok || lit.Value != math.Trunc(lit.Value) || lit.Value < 1 || lit.Value > maxPeriod {
+				return compiled{}, errorf(start(a), "%s 的参数 %s 必须是 1 到 %d 之间的整数常量", n.Name, p.name, maxPeriod)
+			}
+			args[i].value = lit.Value
+		case argNumber:
+			lit, ok := a.(*NumberLit)
+			if !ok || lit.Value <= 0 {
+				return compiled{}, errorf(start(a), "%s 的参数 %s 必须是大于 0 的数字常量", n.Name, p.name)
+			}
+			args[i].value = lit.Value
+		}
+	}
+	if fn.check != nil {
+		if msg := fn.check(args); msg != "" {
+			return compiled{}, errorf(n.At, "%s: %s", n.Name, msg)
+		}
+	}
+
+	// 相同源码的调用共享缓存结果
+	key := c.src[n.At.Offset:n.End]
+	build := fn.build(args)
+	eval := func(e *env) []float64 {
+		if values, ok := e.cache[key]; ok {
+			return values
+		}
+		values := build(e)
+		e.cache[key] = values
+		return values
+	}
+	if fn.result == TypeNumber && !c.seen[key] {
+		c.seen[key] = true
+		c.values = append(c.values, namedSeries{label: key, eval: eval})
+	}
+	return compiled{typ: fn.result, eval: eval}, nil
+}
+
+func constant(n int, v float64) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = v
+	}
+	return values
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func mapSeries(x evalFunc, fn func(float64) float64) evalFunc {
+	return func(e *env) []float64 {
+		xs := x(e)
+		values := make([]float64, len(xs))
+		for i, v := range xs {
+			values[i] = fn(v)
+		}
+		return values
+	}
+}
+
+func zipSeries(x, y evalFunc, fn func(a, b float64) float64) evalFunc {
+	return func(e *env) []float64 {
+		xs, ys := x(e), y(e)
+		values := make([]float64, len(xs))
+		for i := range xs {
+			values[i] = fn(xs[i], ys[i])
+		}
+		return values
+	}
+}
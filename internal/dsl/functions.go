@@ -0,0 +1,472 @@
+package dsl
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/qqqq/eth-trading-system/internal/analysis/indicators"
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// maxPeriod 周期参数的上限
+const maxPeriod = 1000
+
+// barFields 可直接引用的行情字段
+var barFields = map[string]func(models.Bar) float64{
+	"open":   func(b models.Bar) float64 { return b.Open },
+	"high":   func(b models.Bar) float64 { return b.High },
+	"low":    func(b models.Bar) float64 { return b.Low },
+	"close":  func(b models.Bar) float64 { return b.Close },
+	"volume": func(b models.Bar) float64 { return b.Volume },
+}
+
+type argKind int
+
+const (
+	// argSeries 任意数值表达式
+	argSeries argKind = iota
+	// argPeriod 正整数常量
+	argPeriod
+	// argNumber 正数常量
+	argNumber
+)
+
+type param struct {
+	name string
+	kind argKind
+}
+
+type argument struct {
+	series evalFunc
+	value  float64
+}
+
+func (a argument) period() int {
+	return int(a.value)
+}
+
+// function 内置函数。signatures 按参数个数区分重载，check 对常量参数做额外校验，返回非空字符串表示错误
+type function struct {
+	signatures [][]param
+	result     Type
+	check      func(args []argument) string
+	build      func(args []argument) evalFunc
+	doc        string
+}
+
+func (f function) usage(name string) string {
+	forms := make([]string, 0, len(f.signatures))
+	for _, sig := range f.signatures {
+		names := make([]string, len(sig))
+		for i, p := range sig {
+			names[i] = p.name
+		}
+		forms = append(forms, fmt.Sprintf("%s(%s)", name, strings.Join(names, ", ")))
+	}
+	return strings.Join(forms, " 或 ")
+}
+
+var (
+	paramSource  = param{"source", argSeries}
+	paramPeriod  = param{"period", argPeriod}
+	paramA       = param{"a", argSeries}
+	paramB       = param{"b", argSeries}
+	paramFast    = param{"fast", argPeriod}
+	paramSlow    = param{"slow", argPeriod}
+	paramSignal  = param{"signal", argPeriod}
+	paramStdDevs = param{"k", argNumber}
+)
+
+// functions 内置函数表
+var functions = map[string]function{
+	"sma": {
+		signatures: [][]param{{paramPeriod}, {paramSource, paramPeriod}},
+		result:     TypeNumber,
+		build:      withSource(rollingMean),
+		doc:        "简单移动平均，source 默认为 close",
+	},
+	"ema": {
+		signatures: [][]param{{paramPeriod}, {paramSource, paramPeriod}},
+		result:     TypeNumber,
+		build:      withSource(exponentialMean),
+		doc:        "指数移动平均，以前 period 个值的平均为初值，source 默认为 close",
+	},
+	"rsi": {
+		signatures: [][]param{{paramPeriod}},
+		result:     TypeNumber,
+		build: func(args []argument) evalFunc {
+			return barIndicator(indicators.NewRelativeStrengthIndex(args[0].period()).Series)
+		},
+		doc: "收盘价的相对强弱指数",
+	},
+	"atr": {
+		signatures: [][]param{{paramPeriod}},
+		result:     TypeNumber,
+		build: func(args []argument) evalFunc {
+			return barIndicator(indicators.NewAverageTrueRange(args[0].period()).Series)
+		},
+		doc: "平均真实波幅",
+	},
+	"macd": {
+		signatures: [][]param{{}, {paramFast, paramSlow, paramSignal}},
+		result:     TypeNumber,
+		check:      checkMACD,
+		build:      macdLine(0),
+		doc:        "MACD 线，默认参数 (12, 26, 9)",
+	},
+	"macd_signal": {
+		signatures: [][]param{{}, {paramFast, paramSlow, paramSignal}},
+		result:     TypeNumber,
+		check:      checkMACD,
+		build:      macdLine(1),
+		doc:        "MACD 信号线",
+	},
+	"macd_hist": {
+		signatures: [][]param{{}, {paramFast, paramSlow, paramSignal}},
+		result:     TypeNumber,
+		check:      checkMACD,
+		build:      macdLine(2),
+		doc:        "MACD 柱，即 MACD 线减信号线",
+	},
+	"bb_upper": {
+		signatures: [][]param{{paramPeriod}, {paramPeriod, paramStdDevs}},
+		result:     TypeNumber,
+		build:      bollinger(0),
+		doc:        "布林带上轨，k 默认为 2",
+	},
+	"bb_middle": {
+		signatures: [][]param{{paramPeriod}, {paramPeriod, paramStdDevs}},
+		result:     TypeNumber,
+		build:      bollinger(1),
+		doc:        "布林带中轨",
+	},
+	"bb_lower": {
+		signatures: [][]param{{paramPeriod}, {paramPeriod, paramStdDevs}},
+		result:     TypeNumber,
+		build:      bollinger(2),
+		doc:        "布林带下轨，k 默认为 2",
+	},
+	"highest": {
+		signatures: [][]param{{paramSource, paramPeriod}},
+		result:     TypeNumber,
+		build:      window(math.Max),
+		doc:        "最近 period 根K线（含当前）的最大值",
+	},
+	"lowest": {
+		signatures: [][]param{{paramSource, paramPeriod}},
+		result:     TypeNumber,
+		build:      window(math.Min),
+		doc:        "最近 period 根K线（含当前）的最小值",
+	},
+	"prev": {
+		signatures: [][]param{{paramSource}, {paramSource, paramPeriod}},
+		result:     TypeNumber,
+		build: func(args []argument) evalFunc {
+			n := lagOf(args)
+			return func(e *env) []float64 {
+				xs := args[0].series(e)
+				return lag(xs, n)
+			}
+		},
+		doc: "period 根K线之前的值，period 默认为 1",
+	},
+	"change": {
+		signatures: [][]param{{paramSource}, {paramSource, paramPeriod}},
+		result:     TypeNumber,
+		build: func(args []argument) evalFunc {
+			n := lagOf(args)
+			return func(e *env) []float64 {
+				xs := args[0].series(e)
+				prev := lag(xs, n)
+				values := make([]float64, len(xs))
+				for i := range xs {
+					values[i] = xs[i] - prev[i]
+				}
+				return values
+			}
+		},
+		doc: "当前值减去 period 根K线之前的值，period 默认为 1",
+	},
+	"abs": {
+		signatures: [][]param{{paramSource}},
+		result:     TypeNumber,
+		build: func(args []argument) evalFunc {
+			return mapSeries(args[0].series, math.Abs)
+		},
+		doc: "绝对值",
+	},
+	"min": {
+		signatures: [][]param{{paramA, paramB}},
+		result:     TypeNumber,
+		build: func(args []argument) evalFunc {
+			return zipSeries(args[0].series, args[1].series, math.Min)
+		},
+		doc: "逐根K线取较小值",
+	},
+	"max": {
+		signatures: [][]param{{paramA, paramB}},
+		result:     TypeNumber,
+		build: func(args []argument) evalFunc {
+			return zipSeries(args[0].series, args[1].series, math.Max)
+		},
+		doc: "逐根K线取较大值",
+	},
+	"crossover": {
+		signatures: [][]param{{paramA, paramB}},
+		result:     TypeBool,
+		build:      cross(true),
+		doc:        "a 在当前K线上穿 b：上一根 a <= b 且当前 a > b",
+	},
+	"crossunder": {
+		signatures: [][]param{{paramA, paramB}},
+		result:     TypeBool,
+		build:      cross(false),
+		doc:        "a 在当前K线下穿 b：上一根 a >= b 且当前 a < b",
+	},
+	"rising": {
+		signatures: [][]param{{paramSource, paramPeriod}},
+		result:     TypeBool,
+		build:      monotonic(true),
+		doc:        "最近 period 根K线连续上升",
+	},
+	"falling": {
+		signatures: [][]param{{paramSource, paramPeriod}},
+		result:     TypeBool,
+		build:      monotonic(false),
+		doc:        "最近 period 根K线连续下降",
+	},
+}
+
+// FunctionDoc 内置函数的说明
+type FunctionDoc struct {
+	Name        string `json:"name"`
+	Usage       string `json:"usage"`
+	Returns     string `json:"returns"`
+	Description string `json:"description"`
+}
+
+// Functions 返回按名称排序的内置函数说明
+func Functions() []FunctionDoc {
+	docs := make([]FunctionDoc, 0, len(functions))
+	for name, fn := range functions {
+		docs = append(docs, FunctionDoc{Name: name, Usage: fn.usage(name), Returns: fn.result.String(), Description: fn.doc})
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Name < docs[j].Name })
+	return docs
+}
+
+// withSource 包装只依赖一个序列的指标，省略 source 时使用收盘价
+func withSource(fn func(xs []float64, period int) []float64) func(args []argument) evalFunc {
+	return func(args []argument) evalFunc {
+		src := evalFunc(func(e *env) []float64 {
+			values := make([]float64, len(e.bars))
+			for i, bar := range e.bars {
+				values[i] = bar.Close
+			}
+			return values
+		})
+		n := args[len(args)-1].period()
+		if len(args) == 2 {
+			src = args[0].series
+		}
+		return func(e *env) []float64 {
+			return fn(src(e), n)
+		}
+	}
+}
+
+func barIndicator(series func([]models.Bar) []float64) evalFunc {
+	return func(e *env) []float64 {
+		return series(e.bars)
+	}
+}
+
+func checkMACD(args []argument) string {
+	if len(args) == 3 && args[0].value >= args[1].value {
+		return "fast 必须小于 slow"
+	}
+	return ""
+}
+
+// macdLine 按 which 返回 MACD 线、信号线或柱
+func macdLine(which int) func(args []argument) evalFunc {
+	return func(args []argument) evalFunc {
+		macd := indicators.NewMACD(12, 26, 9)
+		if len(args) == 3 {
+			macd = indicators.NewMACD(args[0].period(), args[1].period(), args[2].period())
+		}
+		return func(e *env) []float64 {
+			line, signalLine := macd.Series(e.bars)
+			switch which {
+			case 0:
+				return line
+			case 1:
+				return signalLine
+			}
+			histogram := make([]float64, len(line))
+			for i := range line {
+				histogram[i] = line[i] - signalLine[i]
+			}
+			return histogram
+		}
+	}
+}
+
+// bollinger 按 which 返回上轨、中轨或下轨
+func bollinger(which int) func(args []argument) evalFunc {
+	return func(args []argument) evalFunc {
+		k := 2.0
+		if len(args) == 2 {
+			k = args[1].value
+		}
+		bb := indicators.NewBollingerBands(args[0].period(), k)
+		return func(e *env) []float64 {
+			upper, middle, lower := bb.Series(e.bars)
+			switch which {
+			case 0:
+				return upper
+			case 1:
+				return middle
+			}
+			return lower
+		}
+	}
+}
+
+// rollingMean 滑动平均，窗口内含 NaN 时结果为 NaN
+func rollingMean(xs []float64, n int) []float64 {
+	values := make([]float64, len(xs))
+	sum, nans := 0.0, 0
+	for i, x := range xs {
+		if math.IsNaN(x) {
+			nans++
+		} else {
+			sum += x
+		}
+		if i >= n {
+			if old := xs[i-n]; math.IsNaN(old) {
+				nans--
+			} else {
+				sum -= old
+			}
+		}
+		if i < n-1 || nans > 0 {
+			values[i] = math.NaN()
+		} else {
+			values[i] = sum / float64(n)
+		}
+	}
+	return values
+}
+
+// exponentialMean 从第一段连续 n 个有效值的平均开始递推的 EMA
+func exponentialMean(xs []float64, n int) []float64 {
+	values := make([]float64, len(xs))
+	k := 2.0 / float64(n+1)
+	seeded := false
+	sum, count := 0.0, 0
+	for i, x := range xs {
+		values[i] = math.NaN()
+		if math.IsNaN(x) {
+			if !seeded {
+				sum, count = 0, 0
+			} else {
+				values[i] = values[i-1]
+			}
+			continue
+		}
+		if seeded {
+			values[i] = x*k + values[i-1]*(1-k)
+			continue
+		}
+		sum += x
+		count++
+		if count == n {
+			values[i] = sum / float64(n)
+			seeded = true
+		}
+	}
+	return values
+}
+
+// window 对最近 period 个值做归约，窗口未满或含 NaN 时为 NaN
+func window(reduce func(a, b float64) float64) func(args []argument) evalFunc {
+	return func(args []argument) evalFunc {
+		n := args[1].period()
+		return func(e *env) []float64 {
+			xs := args[0].series(e)
+			values := make([]float64, len(xs))
+			for i := range xs {
+				if i < n-1 {
+					values[i] = math.NaN()
+					continue
+				}
+				acc := xs[i]
+				for j := i - n + 1; j < i; j++ {
+					acc = reduce(acc, xs[j])
+				}
+				values[i] = acc
+			}
+			return values
+		}
+	}
+}
+
+func lagOf(args []argument) int {
+	if len(args) == 2 {
+		return args[1].period()
+	}
+	return 1
+}
+
+func lag(xs []float64, n int) []float64 {
+	values := make([]float64, len(xs))
+	for i := range xs {
+		if i < n {
+			values[i] = math.NaN()
+		} else {
+			values[i] = xs[i-n]
+		}
+	}
+	return values
+}
+
+func cross(above bool) func(args []argument) evalFunc {
+	return func(args []argument) evalFunc {
+		return func(e *env) []float64 {
+			xs, ys := args[0].series(e), args[1].series(e)
+			values := make([]float64, len(xs))
+			for i := 1; i < len(xs); i++ {
+				// 比较运算遇到 NaN 为假，数据不足时不会产生穿越
+				if above {
+					values[i] = boolValue(xs[i-1] <= ys[i-1] && xs[i] > ys[i])
+				} else {
+					values[i] = boolValue(xs[i-1] >= ys[i-1] && xs[i] < ys[i])
+				}
+			}
+			return values
+		}
+	}
+}
+
+func monotonic(up bool) func(args []argument) evalFunc {
+	return func(args []argument) evalFunc {
+		n := args[1].period()
+		return func(e *env) []float64 {
+			xs := args[0].series(e)
+			values := make([]float64, len(xs))
+			run := 0
+			for i := 1; i < len(xs); i++ {
+				if (up && xs[i] > xs[i-1]) || (!up && xs[i] < xs[i-1]) {
+					run++
+				} else {
+					run = 0
+				}
+				values[i] = boolValue(run >= n)
+			}
+			return values
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Package dsl 实现用于定义交易规则的表达式语言，例如
+//
+//	crossover(sma(10), sma(30)) and rsi(14) < 70
+//
+// 表达式在加载时完成解析和类型检查，评估时对整段K线逐根计算，结果与K线对齐
+package dsl
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Pos 源码中的位置，Offset 为字节偏移，Line 和 Column 从 1 开始（列按字符计）
+type Pos struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Error 带位置的解析或类型错误
+type Error struct {
+	Pos     Pos    `json:"pos"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+func errorf(pos Pos, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Message: fmt.Sprintf(format, args...)}
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokLParen
+	tokRParen
+	tokComma
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  Pos
+}
+
+func (t token) describe() string {
+	switch t.kind {
+	case tokEOF:
+		return "表达式结尾"
+	case tokNumber, tokIdent:
+		return fmt.Sprintf("%q", t.text)
+	}
+	return fmt.Sprintf("'%s'", t.text)
+}
+
+// 关键字按标识符扫描，运算符中 and、or、not 与 &&、||、! 等价
+var keywordOps = map[string]string{
+	"and": "and",
+	"or":  "or",
+	"not": "not",
+}
+
+type lexer struct {
+	src    string
+	offset int
+	line   int
+	column int
+}
+
+func tokenize(src string) ([]token, error) {
+	l := &lexer{src: src, line: 1, column: 1}
+	var tokens []token
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+		if tok.kind == tokEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (l *lexer) pos() Pos {
+	return Pos{Offset: l.offset, Line: l.line, Column: l.column}
+}
+
+func (l *lexer) peek() rune {
+	if l.offset >= len(l.src) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.offset:])
+	return r
+}
+
+func (l *lexer) advance() rune {
+	r, size := utf8.DecodeRuneInString(l.src[l.offset:])
+	l.offset += size
+	if r == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+	return r
+}
+
+func (l *lexer) next() (token, error) {
+	for l.offset < len(l.src) && unicode.IsSpace(l.peek()) {
+		l.advance()
+	}
+	start := l.pos()
+	if l.offset >= len(l.src) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	r := l.peek()
+	switch {
+	case r >= '0' && r <= '9' || r == '.':
+		return l.number(start)
+	case r == '_' || unicode.IsLetter(r):
+		for l.offset < len(l.src) && (l.peek() == '_' || unicode.IsLetter(l.peek()) || unicode.IsDigit(l.peek())) {
+			l.advance()
+		}
+		text := l.src[start.Offset:l.offset]
+		if op, ok := keywordOps[strings.ToLower(text)]; ok {
+			return token{kind: tokOp, text: op, pos: start}, nil
+		}
+		return token{kind: tokIdent, text: text, pos: start}, nil
+	}
+
+	l.advance()
+	switch r {
+	case '(':
+		return token{kind: tokLParen, text: "(", pos: start}, nil
+	case ')':
+		return token{kind: tokRParen, text: ")", pos: start}, nil
+	case ',':
+		return token{kind: tokComma, text: ",", pos: start}, nil
+	case '+', '-', '*', '/':
+		return token{kind: tokOp, text: string(r), pos: start}, nil
+	case '<', '>', '=', '!':
+		if l.peek() == '=' {
+			l.advance()
+			return token{kind: tokOp, text: string(r) + "=", pos: start}, nil
+		}
+		switch r {
+		case '<', '>':
+			return token{kind: tokOp, text: string(r), pos: start}, nil
+		case '!':
+			return token{kind: tokOp, text: "not", pos: start}, nil
+		}
+		return token{}, errorf(start, "'=' 不是有效的运算符，比较相等请使用 '=='")
+	case '&', '|':
+		if l.peek() == r {
+			l.advance()
+			if r == '&' {
+				return token{kind: tokOp, text: "and", pos: start}, nil
+			}
+			return token{kind: tokOp, text: "or", pos: start}, nil
+		}
+	}
+	return token{}, errorf(start, "无法识别的字符 %q", r)
+}
+
+func (l *lexer) number(start Pos) (token, error) {
+	dot := false
+	for l.offset < len(l.src) {
+		r := l.peek()
+		if r == '.' {
+			if dot {
+				break
+			}
+			dot = true
+		} else if r < '0' || r > '9' {
+			break
+		}
+		l.advance()
+	}
+	text := l.src[start.Offset:l.offset]
+	if text == "." {
+		return token{}, errorf(start, "无效的数字 %q", text)
+	}
+	if r := l.peek(); r == '_' || unicode.IsLetter(r) {
+		return token{}, errorf(l.pos(), "数字后不能直接跟 %q", r)
+	}
+	return token{kind: tokNumber, text: text, pos: start}, nil
+}
@@ -0,0 +1,213 @@
+package dsl
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Node 语法树节点
+type Node interface {
+	Pos() Pos
+}
+
+// NumberLit 数字常量
+type NumberLit struct {
+	At    Pos
+	Value float64
+	Text  string
+}
+
+// Ident 标识符，如 close、volume
+type Ident struct {
+	At   Pos
+	Name string
+}
+
+// Call 函数调用，如 sma(close, 10)
+type Call struct {
+	At   Pos
+	Name string
+	Args []Node
+	// End 右括号之后的偏移，用于截取调用的源码
+	End int
+}
+
+// Unary 一元运算：取负或 not
+type Unary struct {
+	At Pos
+	Op string
+	X  Node
+}
+
+// Binary 二元运算：算术、比较、and 和 or
+type Binary struct {
+	At Pos
+	Op string
+	X  Node
+	Y  Node
+}
+
+func (n *NumberLit) Pos() Pos { return n.At }
+func (n *Ident) Pos() Pos     { return n.At }
+func (n *Call) Pos() Pos      { return n.At }
+func (n *Unary) Pos() Pos     { return n.At }
+func (n *Binary) Pos() Pos    { return n.At }
+
+// 二元运算符优先级，数值越大结合越紧
+var precedence = map[string]int{
+	"or":  1,
+	"and": 2,
+	"<":   4,
+	"<=":  4,
+	">":   4,
+	">=":  4,
+	"==":  4,
+	"!=":  4,
+	"+":   5,
+	"-":   5,
+	"*":   6,
+	"/":   6,
+}
+
+// not 的优先级介于 and 和比较之间，使 not a > b 解析为 not (a > b)
+const notPrecedence = 3
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse 把表达式解析为语法树，不做类型检查
+func Parse(src string) (Node, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, errorf(p.peek().pos, "表达式为空")
+	}
+	node, err := p.expr(0)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, errorf(tok.pos, "多余的 %s", tok.describe())
+	}
+	return node, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// expr 按优先级爬升解析二元运算，比较运算不能连写
+func (p *parser) expr(minPrec int) (Node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		prec, ok := precedence[tok.text]
+		if tok.kind != tokOp || !ok || prec <= minPrec {
+			return left, nil
+		}
+		p.next()
+		right, err := p.expr(prec)
+		if err != nil {
+			return nil, err
+		}
+		if prec == precedence["<"] {
+			if next := p.peek(); next.kind == tokOp && precedence[next.text] == prec {
+				return nil, errorf(next.pos, "比较运算不能连写，请使用 and 连接")
+			}
+		}
+		left = &Binary{At: tok.pos, Op: tok.text, X: left, Y: right}
+	}
+}
+
+func (p *parser) unary() (Node, error) {
+	tok := p.peek()
+	if tok.kind == tokOp {
+		switch tok.text {
+		case "-":
+			p.next()
+			x, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			return &Unary{At: tok.pos, Op: "-", X: x}, nil
+		case "not":
+			p.next()
+			x, err := p.expr(notPrecedence)
+			if err != nil {
+				return nil, err
+			}
+			return &Unary{At: tok.pos, Op: "not", X: x}, nil
+		}
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (Node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, errorf(tok.pos, "无效的数字 %q", tok.text)
+		}
+		return &NumberLit{At: tok.pos, Value: v, Text: tok.text}, nil
+	case tokIdent:
+		if p.peek().kind != tokLParen {
+			return &Ident{At: tok.pos, Name: tok.text}, nil
+		}
+		p.next()
+		call := &Call{At: tok.pos, Name: tok.text}
+		if p.peek().kind != tokRParen {
+			for {
+				arg, err := p.expr(0)
+				if err != nil {
+					return nil, err
+				}
+				call.Args = append(call.Args, arg)
+				if p.peek().kind != tokComma {
+					break
+				}
+				p.next()
+			}
+		}
+		closing := p.next()
+		if closing.kind != tokRParen {
+			return nil, errorf(closing.pos, "函数 %s 的参数列表缺少 ')'，遇到 %s", tok.text, closing.describe())
+		}
+		call.End = closing.pos.Offset + 1
+		return call, nil
+	case tokLParen:
+		node, err := p.expr(0)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, errorf(closing.pos, "缺少 ')'，遇到 %s", closing.describe())
+		}
+		return node, nil
+	}
+	return nil, errorf(tok.pos, "%s", unexpected(tok))
+}
+
+func unexpected(tok token) string {
+	if tok.kind == tokEOF {
+		return "表达式不完整"
+	}
+	return fmt.Sprintf("此处不应出现 %s", tok.describe())
+}
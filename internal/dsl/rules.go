@@ -0,0 +1,85 @@
+package dsl
+
+import (
+	"strings"
+)
+
+// 规则的动作
+const (
+	ActionBuy  = "buy"
+	ActionSell = "sell"
+)
+
+// Rule 一条 "buy when 条件" 或 "sell when 条件" 规则
+type Rule struct {
+	Action    string
+	Pos       Pos
+	Condition *Program
+}
+
+// CompileRules 编译由若干条规则组成的规则集，例如
+//
+//	buy when crossover(sma(10), sma(30)) and rsi(14) < 70
+//	sell when crossunder(sma(10), sma(30))
+//
+// 规则之间用空白（通常是换行）分隔，每条规则的条件必须是布尔表达式
+func CompileRules(src string) ([]Rule, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, errorf(p.peek().pos, "规则为空，格式: buy when 条件 或 sell when 条件")
+	}
+
+	var rules []Rule
+	for p.peek().kind != tokEOF {
+		tok := p.next()
+		action := strings.ToLower(tok.text)
+		if tok.kind != tokIdent || (action != ActionBuy && action != ActionSell) {
+			return nil, errorf(tok.pos, "规则应以 buy 或 sell 开头，遇到 %s", tok.describe())
+		}
+		if when := p.next(); when.kind != tokIdent || !strings.EqualFold(when.text, "when") {
+			return nil, errorf(when.pos, "%s 后应为 when，遇到 %s", action, when.describe())
+		}
+		condStart := p.peek()
+		if condStart.kind == tokEOF || isRuleStart(p) {
+			return nil, errorf(condStart.pos, "%s when 后缺少条件", action)
+		}
+		node, err := p.expr(0)
+		if err != nil {
+			return nil, err
+		}
+		if next := p.peek(); next.kind != tokEOF && !isRuleStart(p) {
+			return nil, errorf(next.pos, "多余的 %s，每条规则应以 buy when 或 sell when 开头", next.describe())
+		}
+
+		// 条件与整个规则集共享源码，错误位置和指标名都相对于原始文本
+		c := &compiler{src: src, seen: make(map[string]bool)}
+		root, err := c.compile(node)
+		if err != nil {
+			return nil, err
+		}
+		if root.typ != TypeBool {
+			return nil, errorf(start(node), "条件表达式的结果必须是布尔值，当前为%s，是否缺少比较运算？", root.typ)
+		}
+		condition := strings.TrimSpace(src[condStart.pos.Offset:p.peek().pos.Offset])
+		rules = append(rules, Rule{
+			Action:    action,
+			Pos:       tok.pos,
+			Condition: &Program{source: condition, root: root, values: c.values},
+		})
+	}
+	return rules, nil
+}
+
+// isRuleStart 判断下一个词法单元是否为新规则的开头
+func isRuleStart(p *parser) bool {
+	tok := p.peek()
+	if tok.kind != tokIdent {
+		return false
+	}
+	action := strings.ToLower(tok.text)
+	return action == ActionBuy || action == ActionSell
+}
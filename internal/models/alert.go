@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// 告警规则类型
+const (
+	AlertPriceCross   = "price_cross"
+	AlertPercentMove  = "percent_move"
+	AlertIndicator    = "indicator"
+	AlertVolumeSpike  = "volume_spike"
+	AlertModeOnce     = "once"
+	AlertModeRecur    = "recurring"
+	AlertModeCooldown = "cooldown"
+)
+
+// AlertRule 用户定义的告警规则。Timeframe 为空的价格类规则按每次采集的最新价格评估，
+// 否则在该时间框架每根新K线上评估一次
+type AlertRule struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Symbol    string `json:"symbol"`
+	Timeframe string `json:"timeframe,omitempty"`
+	// Condition 对 price_cross 和 indicator 可选 above, below, crosses_above, crosses_below；
+	// 对 percent_move 可选 up, down, any；volume_spike 不使用
+	Condition string `json:"condition,omitempty"`
+	// Value 价格、指标阈值、涨跌幅百分比或成交量倍数
+	Value float64 `json:"value"`
+	// Indicator 和 Period 为 indicator 规则的指标；设置 CompareIndicator 时与该指标比较而不是与 Value 比较
+	Indicator        string `json:"indicator,omitempty"`
+	Period           int    `json:"period,omitempty"`
+	CompareIndicator string `json:"compare_indicator,omitempty"`
+	ComparePeriod    int    `json:"compare_period,omitempty"`
+	// Window percent_move 的时间窗口，如 "1h"
+	Window string `json:"window,omitempty"`
+	// Mode 可选 once（触发一次后停用）, recurring（条件每次由不满足变为满足时触发）,
+	// cooldown（条件满足即触发，两次触发至少间隔 Cooldown）
+	Mode            string     `json:"mode"`
+	Cooldown        string     `json:"cooldown,omitempty"`
+	Enabled         bool       `json:"enabled"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// AlertTrigger 一次告警触发记录
+type AlertTrigger struct {
+	ID       int64   `json:"id"`
+	RuleID   int64   `json:"rule_id"`
+	RuleName string  `json:"rule_name"`
+	Symbol   string  `json:"symbol"`
+	Price    float64 `json:"price"`
+	// Value 触发时被比较的值，如指标值、涨跌幅或成交量倍数
+	Value     float64   `json:"value"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
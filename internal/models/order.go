@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// OrderSide 订单方向
+type OrderSide string
+
+const (
+	SideBuy  OrderSide = "buy"
+	SideSell OrderSide = "sell"
+)
+
+// Sign 买入为 1，卖出为 -1
+func (s OrderSide) Sign() float64 {
+	if s == SideSell {
+		return -1
+	}
+	return 1
+}
+
+// Order 提交给经纪商的订单
+type Order struct {
+	ID       string
+	Symbol   string
+	Side     OrderSide
+	Quantity float64
+	// Price 下单时的参考价格，用于风控计算名义价值和模拟成交
+	Price        float64
+	StrategyName string
+	Reason       string
+	CreatedAt    time.Time
+}
+
+// Notional 订单的名义价值
+func (o Order) Notional() float64 {
+	return o.Quantity * o.Price
+}
+
+// Fill 订单的成交回报
+type Fill struct {
+	OrderID string
+	// StrategyName 下单的策略，熔断平仓等非策略订单为空
+	StrategyName string
+	Symbol       string
+	Side         OrderSide
+	Quantity     float64
+	Price        float64
+	// Fee 以计价货币计的手续费
+	Fee       float64
+	Timestamp time.Time
+}
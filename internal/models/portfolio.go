@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Position 单个标的的持仓，空头数量为负数
+type Position struct {
+	Symbol   string  `json:"symbol"`
+	Quantity float64 `json:"quantity"`
+	// AvgCost 持仓的平均成本（不含手续费）
+	AvgCost       float64 `json:"avg_cost"`
+	LastPrice     float64 `json:"last_price"`
+	MarketValue   float64 `json:"market_value"`
+	UnrealizedPnL float64 `json:"unrealized_pnl"`
+	// RealizedPnL 该标的已平仓部分的盈亏（不含手续费），Fees 为该标的累计手续费
+	RealizedPnL float64 `json:"realized_pnl"`
+	Fees        float64 `json:"fees"`
+}
+
+// PortfolioSummary 账户的现金、持仓和盈亏汇总
+type PortfolioSummary struct {
+	Cash           float64    `json:"cash"`
+	PositionsValue float64    `json:"positions_value"`
+	Equity         float64    `json:"equity"`
+	RealizedPnL    float64    `json:"realized_pnl"`
+	UnrealizedPnL  float64    `json:"unrealized_pnl"`
+	Fees           float64    `json:"fees"`
+	Positions      []Position `json:"positions"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// EquitySnapshot 某一时刻的账户权益快照
+type EquitySnapshot struct {
+	Timestamp      time.Time `json:"timestamp"`
+	Cash           float64   `json:"cash"`
+	PositionsValue float64   `json:"positions_value"`
+	Equity         float64   `json:"equity"`
+	RealizedPnL    float64   `json:"realized_pnl"`
+	UnrealizedPnL  float64   `json:"unrealized_pnl"`
+	Fees           float64   `json:"fees"`
+}
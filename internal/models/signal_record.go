@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// SignalRecord 记录策略发出的一条交易信号及其事后验证结果
+type SignalRecord struct {
+	ID           int64
+	StrategyName string
+	Symbol       string
+	Timeframe    string
+	Action       SignalAction
+	Price        float64
+	// Timestamp 产生信号的K线开盘时间
+	Timestamp time.Time
+	// Resolved 为 true 时 Correct 表示信号方向是否与之后的价格走势一致
+	Resolved bool
+	Correct  bool
+}
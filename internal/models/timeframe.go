@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// 系统支持的K线时间框架及其对应的时长
+var timeframeDurations = map[string]time.Duration{
+	"1Min":  time.Minute,
+	"5Min":  5 * time.Minute,
+	"15Min": 15 * time.Minute,
+	"1Hour": time.Hour,
+	"4Hour": 4 * time.Hour,
+	"1Day":  24 * time.Hour,
+}
+
+// TimeframeDuration 返回时间框架对应的单根K线时长
+func TimeframeDuration(timeframe string) (time.Duration, bool) {
+	d, ok := timeframeDurations[timeframe]
+	return d, ok
+}
+
+// IsValidTimeframe 判断时间框架是否受支持
+func IsValidTimeframe(timeframe string) bool {
+	_, ok := timeframeDurations[timeframe]
+	return ok
+}
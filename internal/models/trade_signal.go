@@ -1,9 +1,40 @@
 package models
 
+import "time"
+
+// SignalAction 交易信号的动作
+type SignalAction string
+
+const (
+	ActionBuy  SignalAction = "BUY"
+	ActionSell SignalAction = "SELL"
+	ActionHold SignalAction = "HOLD"
+)
+
+// IsTrade 判断是否为需要执行的买入或卖出动作
+func (a SignalAction) IsTrade() bool {
+	return a == ActionBuy || a == ActionSell
+}
+
 // TradeSignal 结构体定义了交易信号
 type TradeSignal struct {
 	StrategyName string
-	Action       string // "BUY", "SELL", 或 "HOLD"
+	Action       SignalAction
 	Price        float64
 	Reason       string
+	// Strength 信号强度，取值 [0, 1]，HOLD 信号为 0
+	Strength float64
+	// Size 建议交易数量（以标的计），0 表示策略未给出建议
+	Size float64
+	// StopLoss 建议止损价，0 表示未设置
+	StopLoss float64
+	// TakeProfit 建议止盈价，0 表示未设置
+	TakeProfit float64
+
+	// Symbol、Timeframe 和 Timestamp 标识产生信号的标的、时间框架和K线（开盘时间）
+	Symbol    string
+	Timeframe string
+	Timestamp time.Time
+	// Indicators 产生信号时使用的指标值
+	Indicators map[string]float64
 }
@@ -0,0 +1,243 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 通用 webhook 签名使用的请求头。签名为 HMAC-SHA256(secret, timestamp + "." + body) 的十六进制，
+// 接收方应校验时间戳以防重放
+const (
+	SignatureHeader = "X-EthBot-Signature"
+	TimestampHeader = "X-EthBot-Timestamp"
+)
+
+const defaultTelegramAPIURL = "https://api.telegram.org"
+
+// postJSON 以 JSON 发送 payload
+func postJSON(ctx context.Context, client *http.Client, endpoint string, payload interface{}, headers map[string]string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Permanent(err)
+	}
+	return postRaw(ctx, client, endpoint, body, headers)
+}
+
+// postRaw 发送 JSON 请求体。429 和 5xx 可以重试，其余非 2xx 状态为不可重试的错误。
+// 网络错误只保留底层原因，避免把含有令牌的 URL 写入日志
+func postRaw(ctx context.Context, client *http.Client, endpoint string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(fmt.Errorf("创建请求失败: %v", stripURL(err)))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求失败: %v", stripURL(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return err
+	}
+	return Permanent(err)
+}
+
+func stripURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// WebhookChannel 以 JSON 形式发送事件到任意 HTTP 地址，设置了 secret 时附带 HMAC 签名
+type WebhookChannel struct {
+	url     string
+	secret  string
+	headers map[string]string
+	client  *http.Client
+}
+
+func NewWebhookChannel(url, secret string, headers map[string]string) *WebhookChannel {
+	return &WebhookChannel{url: url, secret: secret, headers: headers, client: &http.Client{}}
+}
+
+// webhookPayload 通用 webhook 的请求体
+type webhookPayload struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Event Event  `json:"event"`
+}
+
+func (c *WebhookChannel) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(webhookPayload{Title: msg.Title, Body: msg.Body, Event: msg.Event})
+	if err != nil {
+		return Permanent(err)
+	}
+	headers := make(map[string]string, len(c.headers)+2)
+	for k, v := range c.headers {
+		headers[k] = v
+	}
+	if c.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		headers[TimestampHeader] = timestamp
+		headers[SignatureHeader] = "sha256=" + Sign(c.secret, timestamp, body)
+	}
+	return postRaw(ctx, c.client, c.url, body, headers)
+}
+
+// Sign 计算 webhook 签名，接收方可用相同方法校验
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SlackChannel 通过 Slack incoming webhook 发送消息
+type SlackChannel struct {
+	url    string
+	client *http.Client
+}
+
+func NewSlackChannel(url string) *SlackChannel {
+	return &SlackChannel{url: url, client: &http.Client{}}
+}
+
+func (c *SlackChannel) Send(ctx context.Context, msg Message) error {
+	return postJSON(ctx, c.client, c.url, map[string]string{"text": "*" + msg.Title + "*\n" + msg.Body}, nil)
+}
+
+// TelegramChannel 通过 Telegram Bot API 的 sendMessage 发送纯文本消息
+type TelegramChannel struct {
+	apiURL string
+	token  string
+	chatID string
+	client *http.Client
+}
+
+func NewTelegramChannel(apiURL, token, chatID string) *TelegramChannel {
+	if apiURL == "" {
+		apiURL = defaultTelegramAPIURL
+	}
+	return &TelegramChannel{apiURL: strings.TrimRight(apiURL, "/"), token: token, chatID: chatID, client: &http.Client{}}
+}
+
+func (c *TelegramChannel) Send(ctx context.Context, msg Message) error {
+	payload := map[string]interface{}{
+		"chat_id":                  c.chatID,
+		"text":                     msg.Title + "\n\n" + msg.Body,
+		"disable_web_page_preview": true,
+	}
+	return postJSON(ctx, c.client, c.apiURL+"/bot"+c.token+"/sendMessage", payload, nil)
+}
+
+// EmailChannel 通过 SMTP 发送纯文本邮件。端口 465 使用隐式 TLS，其余端口在服务器支持时使用 STARTTLS
+type EmailChannel struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func NewEmailChannel(host string, port int, username, password, from string, to []string) *EmailChannel {
+	return &EmailChannel{host: host, port: port, username: username, password: password, from: from, to: to}
+}
+
+func (c *EmailChannel) Send(ctx context.Context, msg Message) error {
+	addr := net.JoinHostPort(c.host, strconv.Itoa(c.port))
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if c.port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: c.host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("连接 SMTP 服务器失败: %v", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, c.host)
+	if err != nil {
+		return fmt.Errorf("SMTP 握手失败: %v", err)
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok && c.port != 465 {
+		if err := client.StartTLS(&tls.Config{ServerName: c.host}); err != nil {
+			return fmt.Errorf("STARTTLS 失败: %v", err)
+		}
+	}
+	if c.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.username, c.password, c.host)); err != nil {
+			return Permanent(fmt.Errorf("SMTP 认证失败: %v", err))
+		}
+	}
+	if err := client.Mail(c.from); err != nil {
+		return fmt.Errorf("MAIL FROM 失败: %v", err)
+	}
+	for _, to := range c.to {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("RCPT TO %s 失败: %v", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("DATA 失败: %v", err)
+	}
+	if _, err := w.Write(c.compose(msg)); err != nil {
+		return fmt.Errorf("写入邮件失败: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	return client.Quit()
+}
+
+// compose 生成 UTF-8 纯文本邮件，标题按 RFC 2047 编码
+func (c *EmailChannel) compose(msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + c.from + "\r\n")
+	b.WriteString("To: " + strings.Join(c.to, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Title) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"fmt"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// FillEvent 订单成交事件
+func FillEvent(fill models.Fill) Event {
+	action := models.ActionBuy
+	if fill.Side == models.SideSell {
+		action = models.ActionSell
+	}
+	return Event{
+		Type:      EventFill,
+		Severity:  SeverityInfo,
+		Title:     fmt.Sprintf("%s 成交 %s %.8g %s", fill.StrategyName, fill.Side, fill.Quantity, fill.Symbol),
+		Message:   fmt.Sprintf("成交价 %.2f，手续费 %.4f", fill.Price, fill.Fee),
+		Strategy:  fill.StrategyName,
+		Symbol:    fill.Symbol,
+		Action:    string(action),
+		Timestamp: fill.Timestamp,
+		Fields: map[string]interface{}{
+			"order":    fill.OrderID,
+			"quantity": fill.Quantity,
+			"price":    fill.Price,
+			"fee":      fill.Fee,
+		},
+		DedupKey: "fill|" + fill.OrderID,
+	}
+}
+
+// PriceAlertEvent 最新价格变动超过告警阈值的事件
+func PriceAlertEvent(symbol string, bar models.Bar, previous, changePercent float64) Event {
+	return Event{
+		Type:      EventPriceAlert,
+		Severity:  SeverityWarning,
+		Title:     fmt.Sprintf("%s 价格变动 %+.2f%%", symbol, changePercent),
+		Message:   fmt.Sprintf("价格从 %.2f 变为 %.2f", previous, bar.Close),
+		Symbol:    symbol,
+		Timestamp: bar.Timestamp,
+		Fields: map[string]interface{}{
+			"previous": previous,
+			"price":    bar.Close,
+		},
+	}
+}
+
+// RejectionEvent 订单被风控拒绝的事件，同一策略被同一规则拒绝时按去重窗口合并
+func RejectionEvent(order models.Order, rule, reason string) Event {
+	return Event{
+		Type:      EventRisk,
+		Severity:  SeverityWarning,
+		Title:     fmt.Sprintf("%s 的订单被风控拒绝 (%s)", order.StrategyName, rule),
+		Message:   reason,
+		Strategy:  order.StrategyName,
+		Symbol:    order.Symbol,
+		Timestamp: order.CreatedAt,
+		Fields: map[string]interface{}{
+			"side":     order.Side,
+			"quantity": order.Quantity,
+			"price":    order.Price,
+		},
+		DedupKey: "reject|" + order.StrategyName + "|" + rule,
+	}
+}
+
+// KillEvent 熔断触发事件
+func KillEvent(reason string) Event {
+	return Event{
+		Type:     EventRisk,
+		Severity: SeverityCritical,
+		Title:    "风控熔断已触发，停止所有交易",
+		Message:  reason,
+	}
+}
+
+// AlertEvent 用户告警规则触发事件
+func AlertEvent(trigger models.AlertTrigger) Event {
+	return Event{
+		Type:      EventAlert,
+		Severity:  SeverityWarning,
+		Title:     "告警: " + trigger.RuleName,
+		Message:   trigger.Message,
+		Symbol:    trigger.Symbol,
+		Timestamp: trigger.Timestamp,
+		Fields: map[string]interface{}{
+			"price": trigger.Price,
+			"value": trigger.Value,
+		},
+		DedupKey: fmt.Sprintf("alert|%d", trigger.ID),
+	}
+}
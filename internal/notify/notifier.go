@@ -0,0 +1,379 @@
+// Package notify 将交易信号和系统事件发送到 webhook、Slack、Telegram 和邮件等渠道
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/config"
+	"github.com/qqqq/eth-trading-system/internal/models"
+	"github.com/qqqq/eth-trading-system/internal/utils"
+)
+
+// 事件类型
+const (
+	EventSignal     = "signal"
+	EventFill       = "fill"
+	EventPriceAlert = "price_alert"
+	EventRisk       = "risk"
+	EventAlert      = "alert"
+	EventTest       = "test"
+)
+
+// 事件级别
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+var severityRank = map[string]int{
+	"":               0,
+	SeverityInfo:     0,
+	SeverityWarning:  1,
+	SeverityCritical: 2,
+}
+
+// 未配置模板时使用的默认模板，Fields 按键名排序输出
+const (
+	defaultTitleTemplate = `[{{.Severity}}] {{.Title}}`
+	defaultBodyTemplate  = `{{.Message}}{{range $k, $v := .Fields}}
+{{$k}}: {{$v}}{{end}}
+{{.Timestamp.Format "2006-01-02 15:04:05 MST"}}`
+)
+
+// Event 一条待发送的事件，渲染模板时作为模板数据
+type Event struct {
+	Type      string                 `json:"type"`
+	Severity  string                 `json:"severity"`
+	Title     string                 `json:"title"`
+	Message   string                 `json:"message"`
+	Strategy  string                 `json:"strategy,omitempty"`
+	Symbol    string                 `json:"symbol,omitempty"`
+	Action    string                 `json:"action,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+	// DedupKey 去重键，为空时按类型、标题和内容去重
+	DedupKey string `json:"-"`
+}
+
+func (e Event) dedupKey() string {
+	if e.DedupKey != "" {
+		return e.DedupKey
+	}
+	return e.Type + "|" + e.Title + "|" + e.Message
+}
+
+// Message 渲染后的通知内容
+type Message struct {
+	Title string
+	Body  string
+	Event Event
+}
+
+// Channel 通知渠道。返回 Permanent 包装的错误时不再重试
+type Channel interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 标记不应重试的错误，例如请求被拒绝或参数错误
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+type channel struct {
+	name   string
+	typ    string
+	sender Channel
+	title  *template.Template
+	body   *template.Template
+	filter config.NotifyFilter
+}
+
+// accepts 判断事件是否满足渠道的过滤条件
+func (c *channel) accepts(e Event) bool {
+	f := c.filter
+	return matches(f.Events, e.Type, false) &&
+		matches(f.Strategies, e.Strategy, false) &&
+		matches(f.Actions, e.Action, false) &&
+		matches(f.Symbols, e.Symbol, true) &&
+		severityRank[e.Severity] >= severityRank[f.MinSeverity]
+}
+
+// matches 列表为空或事件不含该字段时视为匹配
+func matches(values []string, v string, fold bool) bool {
+	if len(values) == 0 || v == "" {
+		return true
+	}
+	for _, candidate := range values {
+		if candidate == v || (fold && strings.EqualFold(candidate, v)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *channel) render(e Event) (Message, error) {
+	var title, body bytes.Buffer
+	if err := c.title.Execute(&title, e); err != nil {
+		return Message{}, fmt.Errorf("渲染标题失败: %v", err)
+	}
+	if err := c.body.Execute(&body, e); err != nil {
+		return Message{}, fmt.Errorf("渲染内容失败: %v", err)
+	}
+	return Message{Title: title.String(), Body: body.String(), Event: e}, nil
+}
+
+// Notifier 按渠道的过滤条件分发事件，发送失败时按退避间隔重试，相同事件在去重窗口内只发送一次
+type Notifier struct {
+	mu       sync.Mutex
+	cfg      config.NotifyConfig
+	channels []*channel
+	// sent 记录去重窗口内已发送事件的时间
+	sent map[string]time.Time
+	// lastSignals 记录每个策略在每个时间框架上已通知的最新信号K线
+	lastSignals map[string]time.Time
+}
+
+func NewNotifier(cfg config.NotifyConfig) (*Notifier, error) {
+	n := &Notifier{
+		sent:        make(map[string]time.Time),
+		lastSignals: make(map[string]time.Time),
+	}
+	if err := n.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// SetConfig 重新构建通知渠道，出错时保留原有渠道
+func (n *Notifier) SetConfig(cfg config.NotifyConfig) error {
+	var channels []*channel
+	for _, cc := range cfg.Channels {
+		if !cc.IsEnabled() {
+			continue
+		}
+		ch, err := newChannel(cc)
+		if err != nil {
+			return fmt.Errorf("渠道 %s: %v", cc.Name, err)
+		}
+		channels = append(channels, ch)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.cfg = cfg
+	n.channels = channels
+	return nil
+}
+
+func newChannel(cc config.ChannelConfig) (*channel, error) {
+	ch := &channel{name: cc.Name, typ: cc.Type, filter: cc.Filter}
+	switch cc.Type {
+	case "webhook":
+		ch.sender = NewWebhookChannel(cc.URL, cc.Secret, cc.Headers)
+	case "slack":
+		ch.sender = NewSlackChannel(cc.URL)
+	case "telegram":
+		ch.sender = NewTelegramChannel(cc.APIURL, cc.BotToken, cc.ChatID)
+	case "email":
+		ch.sender = NewEmailChannel(cc.SMTPHost, cc.SMTPPort, cc.Username, cc.Password, cc.From, cc.To)
+	default:
+		return nil, fmt.Errorf("不支持的渠道类型 %q", cc.Type)
+	}
+
+	titleText, bodyText := cc.Title, cc.Body
+	if titleText == "" {
+		titleText = defaultTitleTemplate
+	}
+	if bodyText == "" {
+		bodyText = defaultBodyTemplate
+	}
+	var err error
+	if ch.title, err = template.New("title").Parse(titleText); err != nil {
+		return nil, fmt.Errorf("标题模板解析失败: %v", err)
+	}
+	if ch.body, err = template.New("body").Parse(bodyText); err != nil {
+		return nil, fmt.Errorf("内容模板解析失败: %v", err)
+	}
+	return ch, nil
+}
+
+// Enabled 返回是否配置了启用的渠道
+func (n *Notifier) Enabled() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.channels) > 0
+}
+
+// Notify 异步发送事件到所有满足过滤条件的渠道
+func (n *Notifier) Notify(e Event) {
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+	if e.Severity == "" {
+		e.Severity = SeverityInfo
+	}
+
+	n.mu.Lock()
+	cfg, channels := n.cfg, n.channels
+	if len(channels) == 0 || n.duplicate(e, cfg.DedupWindow) {
+		n.mu.Unlock()
+		return
+	}
+	n.mu.Unlock()
+
+	for _, ch := range channels {
+		if !ch.accepts(e) {
+			continue
+		}
+		go func(ch *channel) {
+			if err := deliver(ch, e, cfg); err != nil {
+				utils.Log.WithError(err).WithFields(map[string]interface{}{
+					"channel": ch.name,
+					"event":   e.Type,
+				}).Error("发送通知失败")
+			}
+		}(ch)
+	}
+}
+
+// duplicate 判断事件是否在去重窗口内发送过，并清理过期记录。调用方需持有 n.mu
+func (n *Notifier) duplicate(e Event, window time.Duration) bool {
+	if window <= 0 {
+		return false
+	}
+	now := time.Now()
+	for key, at := range n.sent {
+		if now.Sub(at) >= window {
+			delete(n.sent, key)
+		}
+	}
+	key := e.dedupKey()
+	if _, ok := n.sent[key]; ok {
+		return true
+	}
+	n.sent[key] = now
+	return false
+}
+
+// NotifySignal 发送非 HOLD 信号，每个策略在每个时间框架上的同一根K线只通知一次
+func (n *Notifier) NotifySignal(signal *models.TradeSignal) {
+	if signal == nil || !signal.Action.IsTrade() {
+		return
+	}
+	key := signal.StrategyName + "|" + signal.Timeframe
+	n.mu.Lock()
+	if last, ok := n.lastSignals[key]; ok && !signal.Timestamp.After(last) {
+		n.mu.Unlock()
+		return
+	}
+	n.lastSignals[key] = signal.Timestamp
+	n.mu.Unlock()
+
+	fields := map[string]interface{}{
+		"price":     signal.Price,
+		"strength":  fmt.Sprintf("%.2f", signal.Strength),
+		"timeframe": signal.Timeframe,
+	}
+	if signal.StopLoss > 0 {
+		fields["stop_loss"] = signal.StopLoss
+	}
+	if signal.TakeProfit > 0 {
+		fields["take_profit"] = signal.TakeProfit
+	}
+	n.Notify(Event{
+		Type:      EventSignal,
+		Severity:  SeverityInfo,
+		Title:     fmt.Sprintf("%s %s %s", signal.StrategyName, signal.Action, signal.Symbol),
+		Message:   signal.Reason,
+		Strategy:  signal.StrategyName,
+		Symbol:    signal.Symbol,
+		Action:    string(signal.Action),
+		Timestamp: signal.Timestamp,
+		Fields:    fields,
+		DedupKey:  fmt.Sprintf("signal|%s|%s|%d", signal.StrategyName, signal.Timeframe, signal.Timestamp.Unix()),
+	})
+}
+
+// TestResult 一个渠道的测试结果
+type TestResult struct {
+	Channel string `json:"channel"`
+	Type    string `json:"type"`
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Test 同步地向所有启用的渠道发送测试消息，忽略过滤条件和去重
+func (n *Notifier) Test() []TestResult {
+	n.mu.Lock()
+	cfg, channels := n.cfg, n.channels
+	n.mu.Unlock()
+
+	e := Event{
+		Type:      EventTest,
+		Severity:  SeverityInfo,
+		Title:     "测试通知",
+		Message:   "这是一条测试通知，收到说明渠道配置正确",
+		Timestamp: time.Now().UTC(),
+	}
+	results := make([]TestResult, len(channels))
+	var wg sync.WaitGroup
+	for i, ch := range channels {
+		wg.Add(1)
+		go func(i int, ch *channel) {
+			defer wg.Done()
+			results[i] = TestResult{Channel: ch.name, Type: ch.typ, OK: true}
+			if err := deliver(ch, e, cfg); err != nil {
+				results[i].OK = false
+				results[i].Error = err.Error()
+			}
+		}(i, ch)
+	}
+	wg.Wait()
+	return results
+}
+
+// deliver 渲染并发送事件，失败时按指数退避重试，直到成功、遇到不可重试的错误或达到最大次数
+func deliver(ch *channel, e Event, cfg config.NotifyConfig) error {
+	msg, err := ch.render(e)
+	if err != nil {
+		return err
+	}
+
+	backoff := cfg.InitialBackoff
+	attempts := cfg.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+		err = ch.sender.Send(ctx, msg)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt >= attempts {
+			return fmt.Errorf("第 %d 次发送失败: %w", attempt, err)
+		}
+		utils.Log.WithError(err).WithField("channel", ch.name).Warnf("发送通知失败，%s 后第 %d 次重试", backoff, attempt)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > cfg.MaxBackoff {
+			backoff = cfg.MaxBackoff
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package portfolio
+
+import (
+	"math"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// Holding 按平均成本法核算的单个标的持仓，账本、风控和各策略的持仓记录共用，空头数量为负数
+type Holding struct {
+	Quantity float64
+	AvgCost  float64
+	// Realized 已实现盈亏，不含手续费
+	Realized float64
+	Fees     float64
+}
+
+// Closing 一笔成交平掉的持仓部分
+type Closing struct {
+	Quantity float64
+	// Cost 平仓部分按平均成本计算的成本
+	Cost float64
+	// PnL 平仓部分的已实现盈亏，不含手续费
+	PnL float64
+}
+
+// Net 扣除手续费后的已实现盈亏
+func (c Closing) Net(fee float64) float64 {
+	return c.PnL - fee
+}
+
+// Apply 按平均成本法更新持仓：同向成交摊薄成本，反向成交按平均成本结算已实现盈亏，
+// 超出持仓的部分以成交价反向开仓。返回本次成交平掉的部分，未平仓时数量为 0
+func (h *Holding) Apply(fill models.Fill) Closing {
+	signed := fill.Side.Sign() * fill.Quantity
+	h.Fees += fill.Fee
+
+	if h.Quantity != 0 && h.Quantity*signed < 0 {
+		quantity := math.Min(math.Abs(signed), math.Abs(h.Quantity))
+		direction := math.Copysign(1, h.Quantity)
+		closing := Closing{
+			Quantity: quantity,
+			Cost:     h.AvgCost * quantity,
+			PnL:      direction * (fill.Price - h.AvgCost) * quantity,
+		}
+		h.Realized += closing.PnL
+		h.Quantity += signed
+		if math.Abs(h.Quantity) < 1e-12 {
+			h.Quantity = 0
+		}
+		switch {
+		case h.Quantity == 0:
+			h.AvgCost = 0
+		case h.Quantity*direction < 0:
+			h.AvgCost = fill.Price
+		}
+		return closing
+	}
+	total := h.Quantity + signed
+	h.AvgCost = (h.AvgCost*math.Abs(h.Quantity) + fill.Price*math.Abs(signed)) / math.Abs(total)
+	h.Quantity = total
+	return Closing{}
+}
+
+// Unrealized 按标记价格计算的未实现盈亏
+func (h *Holding) Unrealized(mark float64) float64 {
+	return h.Quantity * (mark - h.AvgCost)
+}
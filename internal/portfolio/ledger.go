@@ -0,0 +1,159 @@
+// Package portfolio 根据成交记录维护账户的现金、持仓和盈亏
+package portfolio
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+	"github.com/qqqq/eth-trading-system/internal/storage"
+	"github.com/qqqq/eth-trading-system/internal/utils"
+)
+
+// PriceSource 提供用于计算未实现盈亏的最新价格
+type PriceSource interface {
+	GetLatestPrice() (*models.Bar, error)
+}
+
+type holding struct {
+	Holding
+	// lastFill 最新成交价，无法取得最新价格时用于估值
+	lastFill float64
+}
+
+// Ledger 账户账本。成交记录持久化在 SQLite 中，启动时重放全部成交恢复持仓
+type Ledger struct {
+	repo   storage.PortfolioRepository
+	prices PriceSource
+
+	mu          sync.Mutex
+	initialCash float64
+	cash        float64
+	holdings    map[string]*holding
+}
+
+// NewLedger 创建账本并重放已保存的成交记录
+func NewLedger(initialCash float64, repo storage.PortfolioRepository, prices PriceSource) (*Ledger, error) {
+	l := &Ledger{
+		repo:        repo,
+		prices:      prices,
+		initialCash: initialCash,
+		cash:        initialCash,
+		holdings:    make(map[string]*holding),
+	}
+	fills, err := repo.Fills()
+	if err != nil {
+		return nil, err
+	}
+	for _, fill := range fills {
+		l.apply(fill)
+	}
+	if len(fills) > 0 {
+		utils.Log.Infof("已从 %d 条成交记录恢复账户持仓", len(fills))
+	}
+	return l, nil
+}
+
+// RecordFill 保存成交记录并更新持仓
+func (l *Ledger) RecordFill(fill models.Fill) error {
+	if err := l.repo.RecordFill(&fill); err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.apply(fill)
+	return nil
+}
+
+// apply 更新现金和持仓，调用方需持有 l.mu（构造时除外）
+func (l *Ledger) apply(fill models.Fill) {
+	h, ok := l.holdings[fill.Symbol]
+	if !ok {
+		h = &holding{}
+		l.holdings[fill.Symbol] = h
+	}
+	l.cash -= fill.Side.Sign()*fill.Quantity*fill.Price + fill.Fee
+	h.lastFill = fill.Price
+	h.Apply(fill)
+}
+
+// Summary 返回按最新价格估值的账户汇总。最新价格取自 latest_price 表（当前仅有 ETH/USD），
+// 读取失败时使用各标的的最新成交价
+func (l *Ledger) Summary() models.PortfolioSummary {
+	price, updatedAt := 0.0, time.Now().UTC()
+	if bar, err := l.prices.GetLatestPrice(); err == nil {
+		price, updatedAt = bar.Close, bar.Timestamp
+	} else {
+		utils.Log.WithError(err).Warn("读取最新价格失败，按最新成交价估值")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	summary := models.PortfolioSummary{Cash: l.cash, Positions: []models.Position{}, UpdatedAt: updatedAt}
+	for symbol, h := range l.holdings {
+		mark := price
+		if mark <= 0 {
+			mark = h.lastFill
+		}
+		position := models.Position{
+			Symbol:        symbol,
+			Quantity:      h.Quantity,
+			AvgCost:       h.AvgCost,
+			LastPrice:     mark,
+			MarketValue:   h.Quantity * mark,
+			UnrealizedPnL: h.Unrealized(mark),
+			RealizedPnL:   h.Realized,
+			Fees:          h.Fees,
+		}
+		summary.Positions = append(summary.Positions, position)
+		summary.PositionsValue += position.MarketValue
+		summary.RealizedPnL += position.RealizedPnL
+		summary.UnrealizedPnL += position.UnrealizedPnL
+		summary.Fees += position.Fees
+	}
+	sort.Slice(summary.Positions, func(i, j int) bool { return summary.Positions[i].Symbol < summary.Positions[j].Symbol })
+	summary.Equity = summary.Cash + summary.PositionsValue
+	return summary
+}
+
+// Snapshot 保存当前的权益快照，时间戳截断到秒
+func (l *Ledger) Snapshot() error {
+	summary := l.Summary()
+	return l.repo.RecordSnapshot(&models.EquitySnapshot{
+		Timestamp:      time.Now().UTC().Truncate(time.Second),
+		Cash:           summary.Cash,
+		PositionsValue: summary.PositionsValue,
+		Equity:         summary.Equity,
+		RealizedPnL:    summary.RealizedPnL,
+		UnrealizedPnL:  summary.UnrealizedPnL,
+		Fees:           summary.Fees,
+	})
+}
+
+// StartSnapshots 每隔 interval 保存一次权益快照，interval 不大于 0 时不启动
+func (l *Ledger) StartSnapshots(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := l.Snapshot(); err != nil {
+				utils.Log.WithError(err).Error("保存权益快照失败")
+			}
+		}
+	}()
+	utils.Log.Infof("权益快照已启动，间隔: %s", interval)
+}
+
+// EquityHistory 返回时间范围内的权益快照
+func (l *Ledger) EquityHistory(start, end time.Time) ([]models.EquitySnapshot, error) {
+	return l.repo.EquitySnapshots(start, end)
+}
+
+// Fills 返回全部成交记录
+func (l *Ledger) Fills() ([]models.Fill, error) {
+	return l.repo.Fills()
+}
@@ -0,0 +1,320 @@
+package portfolio
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// 卖出时选择税务批次的方式
+const (
+	LotFIFO     = "fifo"
+	LotLIFO     = "lifo"
+	LotHIFO     = "hifo"
+	LotSpecific = "specific"
+)
+
+// 持有期限，持有超过一年为长期
+const (
+	TermShort = "short"
+	TermLong  = "long"
+)
+
+// lotEpsilon 视为已用完的批次剩余数量
+const lotEpsilon = 1e-12
+
+// Lot 一笔买入形成的税务批次，成本包含买入手续费
+type Lot struct {
+	ID       string    `json:"id"`
+	Symbol   string    `json:"symbol"`
+	Acquired time.Time `json:"acquired"`
+	// Quantity 剩余数量，UnitCost 每单位成本（成交价加分摊的手续费）
+	Quantity float64 `json:"quantity"`
+	UnitCost float64 `json:"unit_cost"`
+}
+
+// Disposal 一笔卖出从某个批次处置的部分，对应 Form 8949 的一行
+type Disposal struct {
+	SaleID   string    `json:"sale_id"`
+	LotID    string    `json:"lot_id"`
+	Symbol   string    `json:"symbol"`
+	Quantity float64   `json:"quantity"`
+	Acquired time.Time `json:"acquired"`
+	Sold     time.Time `json:"sold"`
+	// Proceeds 扣除分摊卖出手续费后的收入，CostBasis 含分摊买入手续费的成本
+	Proceeds  float64 `json:"proceeds"`
+	CostBasis float64 `json:"cost_basis"`
+	Gain      float64 `json:"gain"`
+	Term      string  `json:"term"`
+}
+
+// Selection 指定批次方式下，一笔卖出从某个批次处置的数量
+type Selection struct {
+	SaleID   string  `json:"sale_id"`
+	LotID    string  `json:"lot_id"`
+	Quantity float64 `json:"quantity"`
+}
+
+// LotResult 按批次重放成交记录的结果
+type LotResult struct {
+	Method    string     `json:"method"`
+	Disposals []Disposal `json:"disposals"`
+	// Open 仍有剩余数量的批次
+	Open []Lot `json:"open"`
+}
+
+// MatchLots 按时间顺序重放成交记录，用 method 为每笔卖出选择批次。指定批次方式下使用 selections，
+// 未指定或指定数量不足的部分按先进先出处理。只支持多头持仓，卖出数量超过持仓时返回错误
+func MatchLots(fills []models.Fill, method string, selections []Selection) (*LotResult, error) {
+	switch method {
+	case LotFIFO, LotLIFO, LotHIFO, LotSpecific:
+	default:
+		return nil, fmt.Errorf("不支持的批次方式 %q，可选值: fifo, lifo, hifo, specific", method)
+	}
+
+	selected := make(map[string][]Selection)
+	for _, s := range selections {
+		if s.Quantity <= 0 {
+			return nil, fmt.Errorf("卖出 %s 指定批次 %s 的数量必须大于 0", s.SaleID, s.LotID)
+		}
+		selected[s.SaleID] = append(selected[s.SaleID], s)
+	}
+
+	sorted := make([]models.Fill, len(fills))
+	copy(sorted, fills)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Timestamp.Before(sorted[j].Timestamp) })
+
+	result := &LotResult{Method: method, Disposals: []Disposal{}, Open: []Lot{}}
+	lots := make(map[string][]*Lot)
+	for _, fill := range sorted {
+		if fill.Quantity <= 0 {
+			continue
+		}
+		if fill.Side == models.SideBuy {
+			lots[fill.Symbol] = append(lots[fill.Symbol], &Lot{
+				ID:       fill.OrderID,
+				Symbol:   fill.Symbol,
+				Acquired: fill.Timestamp,
+				Quantity: fill.Quantity,
+				UnitCost: fill.Price + fill.Fee/fill.Quantity,
+			})
+			continue
+		}
+
+		disposals, err := dispose(lots[fill.Symbol], fill, method, selected[fill.OrderID])
+		if err != nil {
+			return nil, err
+		}
+		result.Disposals = append(result.Disposals, disposals...)
+	}
+
+	for _, symbolLots := range lots {
+		for _, lot := range symbolLots {
+			if lot.Quantity > lotEpsilon {
+				result.Open = append(result.Open, *lot)
+			}
+		}
+	}
+	sort.Slice(result.Open, func(i, j int) bool { return result.Open[i].Acquired.Before(result.Open[j].Acquired) })
+	return result, nil
+}
+
+// dispose 从 lots 中扣减一笔卖出的数量，卖出手续费按数量分摊到各行
+func dispose(lots []*Lot, sale models.Fill, method string, selections []Selection) ([]Disposal, error) {
+	remaining := sale.Quantity
+	unitProceeds := sale.Price - sale.Fee/sale.Quantity
+	var disposals []Disposal
+	take := func(lot *Lot, quantity float64) {
+		quantity = math.Min(quantity, lot.Quantity)
+		lot.Quantity -= quantity
+		remaining -= quantity
+		d := Disposal{
+			SaleID:    sale.OrderID,
+			LotID:     lot.ID,
+			Symbol:    sale.Symbol,
+			Quantity:  quantity,
+			Acquired:  lot.Acquired,
+			Sold:      sale.Timestamp,
+			Proceeds:  quantity * unitProceeds,
+			CostBasis: quantity * lot.UnitCost,
+			Term:      holdingTerm(lot.Acquired, sale.Timestamp),
+		}
+		d.Gain = d.Proceeds - d.CostBasis
+		disposals = append(disposals, d)
+	}
+
+	if method == LotSpecific {
+		for _, s := range selections {
+			lot := findLot(lots, s.LotID)
+			if lot == nil || lot.Quantity <= lotEpsilon {
+				return nil, fmt.Errorf("卖出 %s 指定的批次 %s 不存在或已用完", sale.OrderID, s.LotID)
+			}
+			if s.Quantity > lot.Quantity+lotEpsilon {
+				return nil, fmt.Errorf("卖出 %s 指定批次 %s 的数量 %.8f 超过剩余数量 %.8f", sale.OrderID, s.LotID, s.Quantity, lot.Quantity)
+			}
+			if s.Quantity > remaining+lotEpsilon {
+				return nil, fmt.Errorf("卖出 %s 指定的批次数量之和超过卖出数量 %.8f", sale.OrderID, sale.Quantity)
+			}
+			take(lot, s.Quantity)
+		}
+	}
+
+	for _, lot := range orderLots(lots, method) {
+		if remaining <= lotEpsilon {
+			break
+		}
+		if lot.Quantity > lotEpsilon {
+			take(lot, remaining)
+		}
+	}
+	if remaining > lotEpsilon {
+		return nil, fmt.Errorf("%s 卖出 %s 的数量超过持仓 %.8f，不支持做空", sale.Symbol, sale.OrderID, remaining)
+	}
+	return disposals, nil
+}
+
+// orderLots 返回按 method 排列的批次，指定批次方式的剩余部分按先进先出
+func orderLots(lots []*Lot, method string) []*Lot {
+	ordered := make([]*Lot, len(lots))
+	copy(ordered, lots)
+	switch method {
+	case LotLIFO:
+		sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Acquired.After(ordered[j].Acquired) })
+	case LotHIFO:
+		sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].UnitCost > ordered[j].UnitCost })
+	}
+	return ordered
+}
+
+func findLot(lots []*Lot, id string) *Lot {
+	for _, lot := range lots {
+		if lot.ID == id {
+			return lot
+		}
+	}
+	return nil
+}
+
+// holdingTerm 持有超过一年为长期：按 UTC 日历日比较，卖出日须晚于买入日的一周年当天，
+// 同一天内的买卖时刻不影响结果
+func holdingTerm(acquired, sold time.Time) string {
+	acquired = acquired.UTC()
+	sold = sold.UTC()
+	anniversary := time.Date(acquired.Year()+1, acquired.Month(), acquired.Day(), 0, 0, 0, 0, time.UTC)
+	saleDate := time.Date(sold.Year(), sold.Month(), sold.Day(), 0, 0, 0, 0, time.UTC)
+	if saleDate.After(anniversary) {
+		return TermLong
+	}
+	return TermShort
+}
+
+// GainsTotals 一类持有期限的合计
+type GainsTotals struct {
+	Proceeds  float64 `json:"proceeds"`
+	CostBasis float64 `json:"cost_basis"`
+	Gain      float64 `json:"gain"`
+}
+
+func (t *GainsTotals) add(d Disposal) {
+	t.Proceeds += d.Proceeds
+	t.CostBasis += d.CostBasis
+	t.Gain += d.Gain
+}
+
+// GainsReport 某一年度（UTC）的已实现损益
+type GainsReport struct {
+	Year      int         `json:"year"`
+	Method    string      `json:"method"`
+	ShortTerm GainsTotals `json:"short_term"`
+	LongTerm  GainsTotals `json:"long_term"`
+	Total     GainsTotals `json:"total"`
+	// Disposals 短期在前、长期在后，各自按卖出时间排列
+	Disposals []Disposal `json:"disposals"`
+}
+
+// NewGainsReport 汇总卖出时间在 year 年内的处置记录
+func NewGainsReport(result *LotResult, year int) *GainsReport {
+	report := &GainsReport{Year: year, Method: result.Method, Disposals: []Disposal{}}
+	for _, d := range result.Disposals {
+		if d.Sold.UTC().Year() != year {
+			continue
+		}
+		report.Disposals = append(report.Disposals, d)
+		if d.Term == TermLong {
+			report.LongTerm.add(d)
+		} else {
+			report.ShortTerm.add(d)
+		}
+		report.Total.add(d)
+	}
+	sort.SliceStable(report.Disposals, func(i, j int) bool {
+		a, b := report.Disposals[i], report.Disposals[j]
+		if a.Term != b.Term {
+			return a.Term == TermShort
+		}
+		return a.Sold.Before(b.Sold)
+	})
+	return report
+}
+
+// WriteForm8949CSV 按 Form 8949 的栏目输出：Part I 为短期，Part II 为长期，各部分末尾附合计行。
+// 卖出手续费已从收入中扣除、买入手续费已计入成本，因此调整栏为空
+func (r *GainsReport) WriteForm8949CSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	header := []string{"Part", "(a) Description of property", "(b) Date acquired", "(c) Date sold or disposed of",
+		"(d) Proceeds", "(e) Cost or other basis", "(f) Code", "(g) Amount of adjustment", "(h) Gain or (loss)"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	parts := []struct {
+		name, term string
+		totals     GainsTotals
+	}{
+		{"I", TermShort, r.ShortTerm},
+		{"II", TermLong, r.LongTerm},
+	}
+	for _, part := range parts {
+		for _, d := range r.Disposals {
+			if d.Term != part.term {
+				continue
+			}
+			row := []string{part.name, describe(d), formatDate(d.Acquired), formatDate(d.Sold),
+				formatAmount(d.Proceeds), formatAmount(d.CostBasis), "", "", formatAmount(d.Gain)}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+		total := []string{part.name, "Total Part " + part.name, "", "",
+			formatAmount(part.totals.Proceeds), formatAmount(part.totals.CostBasis), "", "", formatAmount(part.totals.Gain)}
+		if err := writer.Write(total); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// describe 生成 "0.50000000 ETH" 形式的资产描述
+func describe(d Disposal) string {
+	asset := d.Symbol
+	if i := strings.Index(asset, "/"); i > 0 {
+		asset = asset[:i]
+	}
+	return strconv.FormatFloat(d.Quantity, 'f', 8, 64) + " " + asset
+}
+
+func formatDate(t time.Time) string {
+	return t.UTC().Format("01/02/2006")
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', 2, 64)
+}
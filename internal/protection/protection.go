@@ -0,0 +1,197 @@
+// Package protection 管理附加在多头持仓上的保护性订单：止损、止盈、移动止损和保本止损
+package protection
+
+import (
+	"math"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/analysis/indicators"
+	"github.com/qqqq/eth-trading-system/internal/config"
+	"github.com/qqqq/eth-trading-system/internal/models"
+	"github.com/qqqq/eth-trading-system/internal/utils"
+)
+
+// 保护性价格的计算方式
+const (
+	LevelPercent = "percent"
+	LevelATR     = "atr"
+	LevelR       = "r"
+)
+
+// 平仓原因
+const (
+	ReasonStopLoss   = "止损"
+	ReasonBreakeven  = "保本止损"
+	ReasonTrailing   = "移动止损"
+	ReasonTakeProfit = "止盈"
+)
+
+// Bracket 一个多头持仓及其保护性订单。StopLoss、TakeProfit 和移动止损属于同一 OCO 组，
+// 任一触发即平掉全部数量，其余订单随之撤销
+type Bracket struct {
+	Symbol       string    `json:"symbol"`
+	StrategyName string    `json:"strategy"`
+	Quantity     float64   `json:"quantity"`
+	Entry        float64   `json:"entry"`
+	OpenedAt     time.Time `json:"opened_at"`
+	// StopLoss 当前的固定止损价，移至保本后等于开仓价，0 表示未设置
+	StopLoss float64 `json:"stop_loss,omitempty"`
+	// InitialRisk 开仓价与初始止损价之差，即 1R
+	InitialRisk float64 `json:"initial_risk,omitempty"`
+	TakeProfit  float64 `json:"take_profit,omitempty"`
+	// TrailDistance 移动止损与最高价的距离，TrailPercent 不为 0 时按百分比计算
+	TrailDistance float64 `json:"trail_distance,omitempty"`
+	TrailPercent  float64 `json:"trail_percent,omitempty"`
+	HighWater     float64 `json:"high_water"`
+	BreakevenR    float64 `json:"breakeven_r,omitempty"`
+	Breakeven     bool    `json:"breakeven"`
+}
+
+// Exit 保护性订单触发后的平仓
+type Exit struct {
+	Price  float64
+	Reason string
+}
+
+// TrailingStop 返回当前的移动止损价，未设置时为 0
+func (b *Bracket) TrailingStop() float64 {
+	switch {
+	case b.TrailPercent > 0:
+		return b.HighWater * (1 - b.TrailPercent/100)
+	case b.TrailDistance > 0:
+		return b.HighWater - b.TrailDistance
+	}
+	return 0
+}
+
+// EffectiveStop 返回固定止损与移动止损中较高者及对应的平仓原因
+func (b *Bracket) EffectiveStop() (float64, string) {
+	stop, reason := b.StopLoss, ReasonStopLoss
+	if b.Breakeven {
+		reason = ReasonBreakeven
+	}
+	if trailing := b.TrailingStop(); trailing > stop {
+		stop, reason = trailing, ReasonTrailing
+	}
+	return stop, reason
+}
+
+// OnBar 用一根K线检查保护性订单。先按开仓后已知的止损/止盈判断是否触发（同时触及按止损处理，
+// 跳空越过时以开盘价成交），未触发时再用这根K线更新最高价、移动止损和保本止损。
+// 开盘时间早于开仓时间的K线会被忽略
+func (b *Bracket) OnBar(bar models.Bar) *Exit {
+	if bar.Timestamp.Before(b.OpenedAt) {
+		return nil
+	}
+
+	if stop, reason := b.EffectiveStop(); stop > 0 && bar.Low <= stop {
+		return &Exit{Price: math.Min(bar.Open, stop), Reason: reason}
+	}
+	if b.TakeProfit > 0 && bar.High >= b.TakeProfit {
+		return &Exit{Price: math.Max(bar.Open, b.TakeProfit), Reason: ReasonTakeProfit}
+	}
+
+	b.HighWater = math.Max(b.HighWater, bar.High)
+	if b.BreakevenR > 0 && b.InitialRisk > 0 && !b.Breakeven && b.HighWater-b.Entry >= b.BreakevenR*b.InitialRisk {
+		b.StopLoss = math.Max(b.StopLoss, b.Entry)
+		b.Breakeven = true
+		utils.Log.WithFields(map[string]interface{}{
+			"strategy": b.StrategyName,
+			"entry":    b.Entry,
+		}).Info("浮盈达到保本条件，止损移至开仓价")
+	}
+	return nil
+}
+
+// OnPrice 用最新成交价检查保护性订单
+func (b *Bracket) OnPrice(price float64, at time.Time) *Exit {
+	return b.OnBar(models.Bar{Open: price, High: price, Low: price, Close: price, Timestamp: at})
+}
+
+// Planner 按配置为新开仓位生成保护性订单
+type Planner struct {
+	cfg config.ProtectionConfig
+}
+
+func NewPlanner(cfg config.ProtectionConfig) *Planner {
+	return &Planner{cfg: cfg}
+}
+
+// Attach 为开仓价 entry 的多头持仓生成保护性订单，未配置任何保护时返回 nil。
+// signal 为开仓信号，UseSignalLevels 时使用其止损/止盈价；bars 用于计算 ATR
+func (p *Planner) Attach(symbol, strategyName string, quantity, entry float64, at time.Time, signal *models.TradeSignal, bars []models.Bar) *Bracket {
+	cfg := p.cfg
+	b := &Bracket{
+		Symbol:       symbol,
+		StrategyName: strategyName,
+		Quantity:     quantity,
+		Entry:        entry,
+		OpenedAt:     at,
+		HighWater:    entry,
+		BreakevenR:   cfg.BreakevenR,
+	}
+
+	atr := math.NaN()
+	if cfg.StopLoss.Type == LevelATR || cfg.TakeProfit.Type == LevelATR || cfg.Trailing.Type == LevelATR {
+		if series := indicators.NewAverageTrueRange(cfg.ATRPeriod).Series(bars); len(series) > 0 {
+			atr = series[len(series)-1]
+		}
+		if math.IsNaN(atr) {
+			utils.Log.WithField("strategy", strategyName).Warnf("K线数量 %d 不足以计算 ATR(%d)，跳过基于 ATR 的保护性订单", len(bars), cfg.ATRPeriod)
+		}
+	}
+
+	if cfg.UseSignalLevels && signal != nil && signal.StopLoss > 0 && signal.StopLoss < entry {
+		b.StopLoss = signal.StopLoss
+	} else {
+		b.StopLoss = distanceBelow(entry, cfg.StopLoss, atr)
+	}
+	if b.StopLoss > 0 {
+		b.InitialRisk = entry - b.StopLoss
+	}
+
+	if cfg.UseSignalLevels && signal != nil && signal.TakeProfit > entry {
+		b.TakeProfit = signal.TakeProfit
+	} else {
+		switch cfg.TakeProfit.Type {
+		case LevelPercent:
+			b.TakeProfit = entry * (1 + cfg.TakeProfit.Percent/100)
+		case LevelATR:
+			if !math.IsNaN(atr) {
+				b.TakeProfit = entry + cfg.TakeProfit.ATRMultiple*atr
+			}
+		case LevelR:
+			if b.InitialRisk > 0 {
+				b.TakeProfit = entry + cfg.TakeProfit.RMultiple*b.InitialRisk
+			}
+		}
+	}
+
+	switch cfg.Trailing.Type {
+	case LevelPercent:
+		b.TrailPercent = cfg.Trailing.Percent
+	case LevelATR:
+		if !math.IsNaN(atr) {
+			b.TrailDistance = cfg.Trailing.ATRMultiple * atr
+		}
+	}
+
+	if b.StopLoss == 0 && b.TakeProfit == 0 && b.TrailingStop() == 0 {
+		return nil
+	}
+	return b
+}
+
+// distanceBelow 返回开仓价下方按百分比或 ATR 倍数计算的价格，无法计算时返回 0
+func distanceBelow(entry float64, level config.LevelConfig, atr float64) float64 {
+	var price float64
+	switch level.Type {
+	case LevelPercent:
+		price = entry * (1 - level.Percent/100)
+	case LevelATR:
+		if !math.IsNaN(atr) {
+			price = entry - level.ATRMultiple*atr
+		}
+	}
+	return math.Max(price, 0)
+}
@@ -0,0 +1,358 @@
+// Package risk 在订单提交给经纪商之前检查风控限制，并维护熔断开关
+package risk
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/config"
+	"github.com/qqqq/eth-trading-system/internal/models"
+	"github.com/qqqq/eth-trading-system/internal/portfolio"
+	"github.com/qqqq/eth-trading-system/internal/utils"
+)
+
+// 风控规则名称
+const (
+	RuleKillSwitch        = "kill_switch"
+	RuleOrderQuantity     = "max_order_quantity"
+	RuleOrderNotional     = "max_order_notional"
+	RulePositionNotional  = "max_position_notional"
+	RuleOrdersPerHour     = "max_orders_per_hour"
+	RuleDailyLoss         = "daily_loss_limit"
+	RuleDrawdown          = "max_drawdown"
+	RuleConsecutiveLosses = "loss_cooldown"
+)
+
+// Rejection 订单被风控拒绝的原因
+type Rejection struct {
+	Rule   string
+	Reason string
+}
+
+func (r *Rejection) Error() string {
+	return fmt.Sprintf("风控拒绝 (%s): %s", r.Rule, r.Reason)
+}
+
+type position struct {
+	portfolio.Holding
+	price float64
+}
+
+// Manager 根据成交回报维护持仓、盈亏和权益，并据此检查每笔订单
+type Manager struct {
+	mu  sync.Mutex
+	cfg config.RiskConfig
+
+	initialEquity float64
+	positions     map[string]*position
+	peakEquity    float64
+	day           time.Time
+	dayStart      float64
+
+	orderTimes        []time.Time
+	consecutiveLosses int
+	cooldownUntil     time.Time
+
+	// killedByConfig 和 killedManually 分别记录由配置和 API 触发的熔断，任一为 true 即停止交易
+	killedByConfig bool
+	killedManually bool
+	killReason     string
+}
+
+// NewManager 创建风控管理器，initialEquity 为账户初始权益
+func NewManager(cfg config.RiskConfig, initialEquity float64) *Manager {
+	m := &Manager{
+		initialEquity: initialEquity,
+		positions:     make(map[string]*position),
+		peakEquity:    initialEquity,
+		dayStart:      initialEquity,
+	}
+	m.SetConfig(cfg)
+	return m
+}
+
+// SetConfig 更新风控限制，返回配置中的熔断开关是否由关闭变为开启
+func (m *Manager) SetConfig(cfg config.RiskConfig) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cfg = cfg
+	activated := cfg.KillSwitch && !m.killedByConfig
+	if cfg.KillSwitch != m.killedByConfig {
+		m.killedByConfig = cfg.KillSwitch
+		if cfg.KillSwitch {
+			m.killReason = "配置开启了熔断开关"
+			utils.Log.Warn("风控熔断已由配置开启，停止所有交易")
+		} else {
+			utils.Log.Info("配置关闭了熔断开关")
+		}
+	}
+	return activated
+}
+
+// Kill 手动触发熔断，停止所有交易直到 Resume
+func (m *Manager) Kill(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.killedManually = true
+	m.killReason = reason
+	utils.Log.WithField("reason", reason).Warn("风控熔断已触发，停止所有交易")
+}
+
+// Resume 解除手动触发的熔断，配置中的熔断开关需修改配置解除
+func (m *Manager) Resume() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.killedByConfig {
+		return fmt.Errorf("熔断开关由配置开启，请修改配置 risk.killSwitch 解除")
+	}
+	m.killedManually = false
+	m.killReason = ""
+	utils.Log.Info("风控熔断已解除，恢复交易")
+	return nil
+}
+
+// Halted 返回是否处于熔断状态及原因
+func (m *Manager) Halted() (bool, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.halted(), m.killReason
+}
+
+func (m *Manager) halted() bool {
+	return m.killedByConfig || m.killedManually
+}
+
+// Check 检查订单是否满足风控限制，不满足时返回 *Rejection 并记录日志。
+// 减少持仓的订单只受熔断开关和下单频率限制，以免风控阻止止损离场
+func (m *Manager) Check(order models.Order) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rejection := m.check(order)
+	if rejection != nil {
+		utils.Log.WithFields(map[string]interface{}{
+			"strategy": order.StrategyName,
+			"symbol":   order.Symbol,
+			"side":     order.Side,
+			"quantity": order.Quantity,
+			"price":    order.Price,
+			"rule":     rejection.Rule,
+		}).Warnf("订单被风控拒绝: %s", rejection.Reason)
+		return rejection
+	}
+	m.orderTimes = append(m.orderTimes, order.CreatedAt)
+	return nil
+}
+
+func (m *Manager) check(order models.Order) *Rejection {
+	cfg := m.cfg
+	now := order.CreatedAt
+	m.rollDay(now)
+
+	if m.halted() {
+		return &Rejection{Rule: RuleKillSwitch, Reason: m.killReason}
+	}
+
+	if cfg.MaxOrdersPerHour > 0 {
+		cutoff := now.Add(-time.Hour)
+		recent := m.orderTimes[:0]
+		for _, t := range m.orderTimes {
+			if t.After(cutoff) {
+				recent = append(recent, t)
+			}
+		}
+		m.orderTimes = recent
+		if len(recent) >= cfg.MaxOrdersPerHour {
+			return &Rejection{Rule: RuleOrdersPerHour, Reason: fmt.Sprintf("最近一小时已下单 %d 笔，上限 %d", len(recent), cfg.MaxOrdersPerHour)}
+		}
+	}
+
+	current := 0.0
+	if p, ok := m.positions[order.Symbol]; ok {
+		current = p.Quantity
+	}
+	next := current + order.Side.Sign()*order.Quantity
+	if math.Abs(next) <= math.Abs(current) && next*current >= 0 {
+		return nil
+	}
+
+	if cfg.MaxOrderQuantity > 0 && order.Quantity > cfg.MaxOrderQuantity {
+		return &Rejection{Rule: RuleOrderQuantity, Reason: fmt.Sprintf("订单数量 %.6f 超过上限 %.6f", order.Quantity, cfg.MaxOrderQuantity)}
+	}
+	if cfg.MaxOrderNotional > 0 && order.Notional() > cfg.MaxOrderNotional {
+		return &Rejection{Rule: RuleOrderNotional, Reason: fmt.Sprintf("订单名义价值 %.2f 超过上限 %.2f", order.Notional(), cfg.MaxOrderNotional)}
+	}
+	if notional := math.Abs(next) * order.Price; cfg.MaxPositionNotional > 0 && notional > cfg.MaxPositionNotional {
+		return &Rejection{Rule: RulePositionNotional, Reason: fmt.Sprintf("成交后持仓名义价值 %.2f 超过上限 %.2f", notional, cfg.MaxPositionNotional)}
+	}
+
+	equity := m.equity()
+	if loss := m.dayStart - equity; cfg.DailyLossLimit > 0 && loss >= cfg.DailyLossLimit {
+		return &Rejection{Rule: RuleDailyLoss, Reason: fmt.Sprintf("当日亏损 %.2f 已达上限 %.2f", loss, cfg.DailyLossLimit)}
+	}
+	if dd := m.drawdown(equity); cfg.MaxDrawdownPercent > 0 && dd >= cfg.MaxDrawdownPercent {
+		return &Rejection{Rule: RuleDrawdown, Reason: fmt.Sprintf("权益回撤 %.2f%% 已达上限 %.2f%%", dd, cfg.MaxDrawdownPercent)}
+	}
+	if now.Before(m.cooldownUntil) {
+		return &Rejection{Rule: RuleConsecutiveLosses, Reason: fmt.Sprintf("连续亏损达到 %d 笔，冷却至 %s", cfg.MaxConsecutiveLosses, m.cooldownUntil.UTC().Format(time.RFC3339))}
+	}
+	return nil
+}
+
+// RecordFill 根据成交回报更新持仓、已实现盈亏和连续亏损计数
+func (m *Manager) RecordFill(fill models.Fill) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rollDay(fill.Timestamp)
+
+	p, ok := m.positions[fill.Symbol]
+	if !ok {
+		p = &position{}
+		m.positions[fill.Symbol] = p
+	}
+	p.price = fill.Price
+	if closing := p.Apply(fill); closing.Quantity > 0 {
+		m.recordTradeResult(closing.Net(fill.Fee), fill.Timestamp)
+	}
+	m.updatePeak()
+}
+
+func (m *Manager) recordTradeResult(pnl float64, at time.Time) {
+	if pnl >= 0 {
+		m.consecutiveLosses = 0
+		return
+	}
+	m.consecutiveLosses++
+	if m.cfg.MaxConsecutiveLosses > 0 && m.consecutiveLosses >= m.cfg.MaxConsecutiveLosses {
+		m.cooldownUntil = at.Add(m.cfg.LossCooldown)
+		utils.Log.WithFields(map[string]interface{}{
+			"losses": m.consecutiveLosses,
+			"until":  m.cooldownUntil,
+		}).Warn("连续亏损达到上限，暂停开仓")
+		m.consecutiveLosses = 0
+	}
+}
+
+// UpdatePrice 更新标的最新价格，用于计算未实现盈亏
+func (m *Manager) UpdatePrice(symbol string, price float64, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rollDay(at)
+	if p, ok := m.positions[symbol]; ok {
+		p.price = price
+		m.updatePeak()
+	}
+}
+
+// Positions 返回各标的的持仓数量，空头为负数
+func (m *Manager) Positions() map[string]float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	positions := make(map[string]float64, len(m.positions))
+	for symbol, p := range m.positions {
+		if p.Quantity != 0 {
+			positions[symbol] = p.Quantity
+		}
+	}
+	return positions
+}
+
+// Position 返回标的的持仓数量和最新价格
+func (m *Manager) Position(symbol string) (quantity, price float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p, ok := m.positions[symbol]; ok {
+		return p.Quantity, p.price
+	}
+	return 0, 0
+}
+
+// Equity 返回当前权益
+func (m *Manager) Equity() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.equity()
+}
+
+// Status 风控状态快照
+type Status struct {
+	Halted            bool      `json:"halted"`
+	KillReason        string    `json:"kill_reason,omitempty"`
+	Equity            float64   `json:"equity"`
+	PeakEquity        float64   `json:"peak_equity"`
+	DrawdownPercent   float64   `json:"drawdown_percent"`
+	DailyPnL          float64   `json:"daily_pnl"`
+	RealizedPnL       float64   `json:"realized_pnl"`
+	OrdersLastHour    int       `json:"orders_last_hour"`
+	ConsecutiveLosses int       `json:"consecutive_losses"`
+	CooldownUntil     time.Time `json:"cooldown_until,omitempty"`
+}
+
+// Status 返回当前的风控状态
+func (m *Manager) Status(now time.Time) Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rollDay(now)
+	equity := m.equity()
+	orders := 0
+	for _, t := range m.orderTimes {
+		if t.After(now.Add(-time.Hour)) {
+			orders++
+		}
+	}
+	status := Status{
+		Halted:            m.halted(),
+		KillReason:        m.killReason,
+		Equity:            equity,
+		PeakEquity:        m.peakEquity,
+		DrawdownPercent:   m.drawdown(equity),
+		DailyPnL:          equity - m.dayStart,
+		RealizedPnL:       m.realized(),
+		OrdersLastHour:    orders,
+		ConsecutiveLosses: m.consecutiveLosses,
+	}
+	if now.Before(m.cooldownUntil) {
+		status.CooldownUntil = m.cooldownUntil
+	}
+	return status
+}
+
+// realized 已实现盈亏（已扣手续费）
+func (m *Manager) realized() float64 {
+	realized := 0.0
+	for _, p := range m.positions {
+		realized += p.Realized - p.Fees
+	}
+	return realized
+}
+
+// equity 初始权益加已实现盈亏（已扣手续费）和按最新价格计算的未实现盈亏
+func (m *Manager) equity() float64 {
+	equity := m.initialEquity + m.realized()
+	for _, p := range m.positions {
+		equity += p.Unrealized(p.price)
+	}
+	return equity
+}
+
+func (m *Manager) drawdown(equity float64) float64 {
+	if m.peakEquity <= 0 {
+		return 0
+	}
+	return math.Max(0, (m.peakEquity-equity)/m.peakEquity*100)
+}
+
+func (m *Manager) updatePeak() {
+	m.peakEquity = math.Max(m.peakEquity, m.equity())
+}
+
+// rollDay 在 UTC 日期变化时重置当日盈亏的起点
+func (m *Manager) rollDay(now time.Time) {
+	day := now.UTC().Truncate(24 * time.Hour)
+	if day.After(m.day) {
+		m.day = day
+		m.dayStart = m.equity()
+	}
+}
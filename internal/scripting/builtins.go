@@ -0,0 +1,290 @@
+package scripting
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/qqqq/eth-trading-system/internal/analysis/indicators"
+	"github.com/qqqq/eth-trading-system/internal/dsl"
+	"github.com/qqqq/eth-trading-system/internal/models"
+	starlarkmath "go.starlark.net/lib/math"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// signalCtor 标识由 buy、sell、hold 创建的信号
+var signalCtor = starlark.String("signal")
+
+// predeclared 脚本可直接使用的全局名称
+var predeclared = starlark.StringDict{
+	"buy":        starlark.NewBuiltin("buy", signalBuiltin(models.ActionBuy)),
+	"sell":       starlark.NewBuiltin("sell", signalBuiltin(models.ActionSell)),
+	"hold":       starlark.NewBuiltin("hold", holdBuiltin),
+	"crossover":  starlark.NewBuiltin("crossover", crossBuiltin(true)),
+	"crossunder": starlark.NewBuiltin("crossunder", crossBuiltin(false)),
+	"isnan":      starlark.NewBuiltin("isnan", isnanBuiltin),
+	"nan":        starlark.Float(math.NaN()),
+	"math":       starlarkmath.Module,
+	"struct":     starlark.NewBuiltin("struct", starlarkstruct.Make),
+}
+
+// signalBuiltin 创建 buy 或 sell 信号：
+//
+//	buy(reason="", strength=0.5, size=0, stop_loss=0, take_profit=0, indicators=None)
+func signalBuiltin(action models.SignalAction) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var reason starlark.String
+		var strength, size, stopLoss, takeProfit starlark.Value = starlark.Float(0.5), starlark.MakeInt(0), starlark.MakeInt(0), starlark.MakeInt(0)
+		var values *starlark.Dict
+		if err := starlark.UnpackArgs(fn.Name(), args, kwargs,
+			"reason?", &reason, "strength?", &strength, "size?", &size,
+			"stop_loss?", &stopLoss, "take_profit?", &takeProfit, "indicators?", &values); err != nil {
+			return nil, err
+		}
+		numbers := starlark.StringDict{}
+		for name, v := range map[string]starlark.Value{"strength": strength, "size": size, "stop_loss": stopLoss, "take_profit": takeProfit} {
+			f, ok := starlark.AsFloat(v)
+			if !ok || math.IsNaN(f) || f < 0 {
+				return nil, fmt.Errorf("%s: %s 必须是非负数，当前为 %s", fn.Name(), name, v)
+			}
+			numbers[name] = starlark.Float(f)
+		}
+		if s := float64(numbers["strength"].(starlark.Float)); s > 1 {
+			return nil, fmt.Errorf("%s: strength 必须在 [0, 1] 范围内，当前为 %v", fn.Name(), s)
+		}
+		numbers["action"] = starlark.String(action)
+		numbers["reason"] = reason
+		numbers["indicators"] = dictOrEmpty(values)
+		return starlarkstruct.FromStringDict(signalCtor, numbers), nil
+	}
+}
+
+// holdBuiltin hold(reason="", indicators=None)
+func holdBuiltin(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var reason starlark.String
+	var values *starlark.Dict
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "reason?", &reason, "indicators?", &values); err != nil {
+		return nil, err
+	}
+	return starlarkstruct.FromStringDict(signalCtor, starlark.StringDict{
+		"action":     starlark.String(models.ActionHold),
+		"reason":     reason,
+		"indicators": dictOrEmpty(values),
+	}), nil
+}
+
+func dictOrEmpty(d *starlark.Dict) *starlark.Dict {
+	if d == nil {
+		return starlark.NewDict(0)
+	}
+	return d
+}
+
+// crossBuiltin crossover(a, b) 判断序列 a 是否在最后一根K线上穿（或下穿）b，b 可以是数字
+func crossBuiltin(above bool) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var a, b starlark.Value
+		if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 2, &a, &b); err != nil {
+			return nil, err
+		}
+		prevA, curA, err := lastTwo(fn.Name(), a)
+		if err != nil {
+			return nil, err
+		}
+		prevB, curB, err := lastTwo(fn.Name(), b)
+		if err != nil {
+			return nil, err
+		}
+		if above {
+			return starlark.Bool(prevA <= prevB && curA > curB), nil
+		}
+		return starlark.Bool(prevA >= prevB && curA < curB), nil
+	}
+}
+
+// lastTwo 返回序列的最后两个值，数字视为常数序列
+func lastTwo(fnName string, v starlark.Value) (float64, float64, error) {
+	if f, ok := starlark.AsFloat(v); ok {
+		return f, f, nil
+	}
+	seq, ok := v.(starlark.Indexable)
+	if !ok {
+		return 0, 0, fmt.Errorf("%s: 参数需要是序列或数字，当前为 %s", fnName, v.Type())
+	}
+	n := seq.Len()
+	if n < 2 {
+		return math.NaN(), math.NaN(), nil
+	}
+	prev, ok1 := starlark.AsFloat(seq.Index(n - 2))
+	cur, ok2 := starlark.AsFloat(seq.Index(n - 1))
+	if !ok1 || !ok2 {
+		return 0, 0, fmt.Errorf("%s: 序列元素需要是数字", fnName)
+	}
+	return prev, cur, nil
+}
+
+func isnanBuiltin(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var x starlark.Value
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &x); err != nil {
+		return nil, err
+	}
+	f, ok := starlark.AsFloat(x)
+	if !ok {
+		return starlark.False, nil
+	}
+	return starlark.Bool(math.IsNaN(f)), nil
+}
+
+// newContext 构建传给 evaluate(ctx) 的只读上下文：行情序列、分析结果和计算指标的方法。
+// 指标序列与K线对齐，数据不足的位置为 nan
+func newContext(s *ScriptStrategy, bars []models.Bar, analysis *models.AnalysisResult) *starlarkstruct.Struct {
+	fields := map[string]func(models.Bar) float64{
+		"open":   func(b models.Bar) float64 { return b.Open },
+		"high":   func(b models.Bar) float64 { return b.High },
+		"low":    func(b models.Bar) float64 { return b.Low },
+		"close":  func(b models.Bar) float64 { return b.Close },
+		"volume": func(b models.Bar) float64 { return b.Volume },
+	}
+	members := starlark.StringDict{
+		"symbol":       starlark.String(s.Symbol()),
+		"timeframe":    starlark.String(s.Timeframe()),
+		"market_state": starlark.String(marketState(analysis)),
+	}
+	for name, field := range fields {
+		values := make([]float64, len(bars))
+		for i, bar := range bars {
+			values[i] = field(bar)
+		}
+		members[name] = seriesValue(values)
+	}
+	times := make([]starlark.Value, len(bars))
+	for i, bar := range bars {
+		times[i] = starlark.MakeInt64(bar.Timestamp.Unix())
+	}
+	members["time"] = frozenList(times)
+
+	members["sma"] = periodMethod("sma", 0, func(period int) []float64 {
+		return indicators.NewSimpleMovingAverage(period).Series(bars)
+	})
+	members["rsi"] = periodMethod("rsi", 14, func(period int) []float64 {
+		return indicators.NewRelativeStrengthIndex(period).Series(bars)
+	})
+	members["atr"] = periodMethod("atr", 14, func(period int) []float64 {
+		return indicators.NewAverageTrueRange(period).Series(bars)
+	})
+	members["macd"] = starlark.NewBuiltin("macd", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		fast, slow, signal := 12, 26, 9
+		if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "fast?", &fast, "slow?", &slow, "signal?", &signal); err != nil {
+			return nil, err
+		}
+		if fast < 1 || signal < 1 || fast >= slow {
+			return nil, fmt.Errorf("macd: 需要满足 0 < fast < slow 且 signal > 0")
+		}
+		line, signalLine := indicators.NewMACD(fast, slow, signal).Series(bars)
+		histogram := make([]float64, len(line))
+		for i := range line {
+			histogram[i] = line[i] - signalLine[i]
+		}
+		return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"macd":      seriesValue(line),
+			"signal":    seriesValue(signalLine),
+			"histogram": seriesValue(histogram),
+		}), nil
+	})
+	members["bollinger"] = starlark.NewBuiltin("bollinger", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		period := 20
+		var k starlark.Value = starlark.Float(2)
+		if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "period?", &period, "k?", &k); err != nil {
+			return nil, err
+		}
+		stdDevs, ok := starlark.AsFloat(k)
+		if period < 1 || !ok || stdDevs <= 0 {
+			return nil, fmt.Errorf("bollinger: period 和 k 必须大于 0")
+		}
+		upper, middle, lower := indicators.NewBollingerBands(period, stdDevs).Series(bars)
+		return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"upper":  seriesValue(upper),
+			"middle": seriesValue(middle),
+			"lower":  seriesValue(lower),
+		}), nil
+	})
+	members["donchian"] = starlark.NewBuiltin("donchian", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		period := 20
+		if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "period?", &period); err != nil {
+			return nil, err
+		}
+		if period < 1 {
+			return nil, fmt.Errorf("donchian: period 必须大于 0")
+		}
+		upper, lower := indicators.NewDonchianChannel(period).Series(bars)
+		return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+			"upper": seriesValue(upper),
+			"lower": seriesValue(lower),
+		}), nil
+	})
+	// expr 复用规则表达式语言计算任意指标序列，布尔表达式的结果为 True/False 序列
+	members["expr"] = starlark.NewBuiltin("expr", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var src string
+		if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &src); err != nil {
+			return nil, err
+		}
+		program, err := s.program(src)
+		if err != nil {
+			return nil, fmt.Errorf("expr: %v", err)
+		}
+		values := program.Eval(bars)
+		if program.Type() == dsl.TypeBool {
+			bools := make([]starlark.Value, len(values))
+			for i, v := range values {
+				bools[i] = starlark.Bool(v != 0)
+			}
+			return frozenList(bools), nil
+		}
+		return seriesValue(values), nil
+	})
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, members)
+}
+
+func periodMethod(name string, defaultPeriod int, series func(period int) []float64) *starlark.Builtin {
+	return starlark.NewBuiltin(name, func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		period := defaultPeriod
+		spec := "period?"
+		if defaultPeriod == 0 {
+			spec = "period"
+		}
+		if err := starlark.UnpackArgs(fn.Name(), args, kwargs, spec, &period); err != nil {
+			return nil, err
+		}
+		if period < 1 {
+			return nil, fmt.Errorf("%s: period 必须大于 0", name)
+		}
+		return seriesValue(series(period)), nil
+	})
+}
+
+func marketState(analysis *models.AnalysisResult) string {
+	if analysis == nil {
+		return ""
+	}
+	switch analysis.MarketState {
+	case models.Bullish:
+		return "bullish"
+	case models.Bearish:
+		return "bearish"
+	}
+	return "neutral"
+}
+
+func seriesValue(values []float64) *starlark.List {
+	elems := make([]starlark.Value, len(values))
+	for i, v := range values {
+		elems[i] = starlark.Float(v)
+	}
+	return frozenList(elems)
+}
+
+func frozenList(elems []starlark.Value) *starlark.List {
+	list := starlark.NewList(elems)
+	list.Freeze()
+	return list
+}
@@ -0,0 +1,58 @@
+// Package scripting 将 Starlark 脚本加载为交易策略。脚本在沙箱中执行，不能访问文件、网络或系统时间，
+// 每次加载和评估都受执行时间、执行步数和内存分配上限约束。
+//
+// Starlark 不提供分配钩子，加载时把字符串和列表的拼接、重复、格式化以及增长容器的方法和内置函数
+// 改写为先估算并计入分配大小的版本，累计超过上限时取消执行。推导式、字面量等逐个元素分配的操作
+// 由执行步数上限约束
+package scripting
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/config"
+	"github.com/qqqq/eth-trading-system/internal/utils"
+	"go.starlark.net/starlark"
+)
+
+// Limits 单次执行（加载脚本或一次评估）的资源上限
+type Limits struct {
+	Timeout  time.Duration
+	MaxSteps uint64
+	// MaxMemory 累计分配的字节数上限，0 表示不限制
+	MaxMemory int64
+}
+
+// LimitsFromConfig 从配置构建资源上限
+func LimitsFromConfig(cfg config.ScriptConfig) Limits {
+	return Limits{
+		Timeout:   cfg.Timeout,
+		MaxSteps:  cfg.MaxSteps,
+		MaxMemory: int64(cfg.MaxMemoryMB) << 20,
+	}
+}
+
+// run 在资源上限内执行 fn。超时或分配超过上限时取消线程，脚本在下一步执行时以错误退出
+func run(name string, limits Limits, fn func(thread *starlark.Thread) error) error {
+	thread := &starlark.Thread{
+		Name: name,
+		Print: func(_ *starlark.Thread, msg string) {
+			utils.Log.WithField("script", name).Info(msg)
+		},
+		Load: func(_ *starlark.Thread, module string) (starlark.StringDict, error) {
+			return nil, fmt.Errorf("脚本不支持 load(%q)", module)
+		},
+	}
+	if limits.MaxSteps > 0 {
+		thread.SetMaxExecutionSteps(limits.MaxSteps)
+	}
+	thread.SetLocal(memoryKey, &memoryBudget{limit: limits.MaxMemory})
+
+	if limits.Timeout > 0 {
+		timer := time.AfterFunc(limits.Timeout, func() {
+			thread.Cancel(fmt.Sprintf("执行超过 %s", limits.Timeout))
+		})
+		defer timer.Stop()
+	}
+	return fn(thread)
+}
@@ -0,0 +1,268 @@
+package scripting
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/qqqq/eth-trading-system/internal/config"
+	"github.com/qqqq/eth-trading-system/internal/strategy"
+	"github.com/qqqq/eth-trading-system/internal/utils"
+)
+
+// scriptExt 脚本文件的扩展名
+const scriptExt = ".star"
+
+// reloadDebounce 合并编辑器保存文件时产生的连续事件
+const reloadDebounce = 300 * time.Millisecond
+
+// ScriptStatus 一个脚本文件的加载状态
+type ScriptStatus struct {
+	File      string `json:"file"`
+	Name      string `json:"name,omitempty"`
+	Timeframe string `json:"timeframe,omitempty"`
+	Loaded    bool   `json:"loaded"`
+	// Error 最近一次加载失败的原因，此时若之前加载成功过则继续使用旧版本
+	Error string `json:"error,omitempty"`
+}
+
+type entry struct {
+	src      []byte
+	strategy *ScriptStrategy
+	err      error
+}
+
+// Manager 加载脚本目录中的策略，目录中的文件新增、修改或删除后自动重新加载，并通知订阅者
+type Manager struct {
+	// reloadMu 串行执行重新加载，避免较早开始的扫描覆盖较新的结果
+	reloadMu  sync.Mutex
+	mu        sync.Mutex
+	cfg       config.ScriptConfig
+	scripts   map[string]*entry
+	listeners []func(strategies []strategy.Strategy)
+	watcher   *fsnotify.Watcher
+	timer     *time.Timer
+}
+
+func NewManager() *Manager {
+	return &Manager{scripts: make(map[string]*entry)}
+}
+
+// OnChange 注册回调，脚本集合重新加载后以当前全部可用的脚本策略调用
+func (m *Manager) OnChange(fn func(strategies []strategy.Strategy)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+// SetConfig 应用配置：目录变化时重新监听，资源上限变化时按新上限重新加载全部脚本
+func (m *Manager) SetConfig(cfg config.ScriptConfig) error {
+	m.mu.Lock()
+	prev := m.cfg
+	m.cfg = cfg
+	if cfg.Dir != prev.Dir || m.watcher == nil {
+		m.stopWatching()
+		m.scripts = make(map[string]*entry)
+		if cfg.Dir != "" {
+			if err := m.watch(cfg.Dir); err != nil {
+				m.mu.Unlock()
+				m.notify()
+				return err
+			}
+		}
+	} else if LimitsFromConfig(cfg) != LimitsFromConfig(prev) {
+		m.scripts = make(map[string]*entry)
+	}
+	m.mu.Unlock()
+
+	m.Reload()
+	return nil
+}
+
+// watch 开始监听目录，调用方需持有 m.mu
+func (m *Manager) watch(dir string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("创建脚本目录监听失败: %v", err)
+	}
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return fmt.Errorf("监听脚本目录 %s 失败: %v", dir, err)
+	}
+	m.watcher = watcher
+	go m.loop(watcher)
+	utils.Log.Infof("开始监听脚本目录: %s", dir)
+	return nil
+}
+
+func (m *Manager) loop(watcher *fsnotify.Watcher) {
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if !strings.HasSuffix(event.Name, scriptExt) {
+				continue
+			}
+			m.mu.Lock()
+			if m.timer != nil {
+				m.timer.Stop()
+			}
+			m.timer = time.AfterFunc(reloadDebounce, m.Reload)
+			m.mu.Unlock()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			utils.Log.WithError(err).Error("监听脚本目录出错")
+		}
+	}
+}
+
+// stopWatching 停止监听，调用方需持有 m.mu
+func (m *Manager) stopWatching() {
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	if m.watcher != nil {
+		m.watcher.Close()
+		m.watcher = nil
+	}
+}
+
+// Stop 停止监听脚本目录
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopWatching()
+}
+
+// Reload 重新扫描脚本目录。加载失败的脚本若之前加载成功过，继续使用旧版本
+func (m *Manager) Reload() {
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
+
+	m.mu.Lock()
+	cfg := m.cfg
+	prev := m.scripts
+	m.mu.Unlock()
+
+	next := make(map[string]*entry)
+	if cfg.Dir != "" {
+		paths, err := filepath.Glob(filepath.Join(cfg.Dir, "*"+scriptExt))
+		if err != nil {
+			utils.Log.WithError(err).Error("扫描脚本目录失败")
+		}
+		sort.Strings(paths)
+		names := make(map[string]string)
+		for _, path := range paths {
+			src, err := os.ReadFile(path)
+			e := &entry{src: src}
+			old, existed := prev[path]
+			if err == nil && existed && old.err == nil && bytes.Equal(old.src, src) {
+				// 内容未变化的脚本沿用已加载的策略
+				e.strategy = old.strategy
+			} else if err == nil {
+				e.strategy, err = Load(path, src, LimitsFromConfig(cfg))
+			}
+			if err == nil {
+				if other, ok := names[e.strategy.Name()]; ok {
+					e.strategy, err = nil, fmt.Errorf("策略名称 %q 与 %s 重复", e.strategy.Name(), filepath.Base(other))
+				}
+			}
+			if err != nil {
+				e.err = err
+				if existed && old.strategy != nil {
+					if _, taken := names[old.strategy.Name()]; !taken {
+						e.strategy = old.strategy
+					}
+				}
+				utils.Log.WithError(err).WithField("script", path).Error("加载脚本失败")
+			} else if !existed {
+				utils.Log.WithField("script", path).Infof("已加载脚本策略 %s", e.strategy.Name())
+			} else if e.strategy != old.strategy {
+				utils.Log.WithField("script", path).Infof("已重新加载脚本策略 %s", e.strategy.Name())
+			}
+			if e.strategy != nil {
+				names[e.strategy.Name()] = path
+			}
+			next[path] = e
+		}
+		for path := range prev {
+			if _, ok := next[path]; !ok {
+				utils.Log.WithField("script", path).Info("脚本已删除，移除对应策略")
+			}
+		}
+	}
+
+	m.mu.Lock()
+	if m.cfg.Dir != cfg.Dir {
+		// 扫描期间目录已被修改，以新目录的扫描结果为准
+		m.mu.Unlock()
+		return
+	}
+	m.scripts = next
+	m.mu.Unlock()
+	m.notify()
+}
+
+func (m *Manager) notify() {
+	strategies := m.Strategies()
+	m.mu.Lock()
+	listeners := append([]func([]strategy.Strategy){}, m.listeners...)
+	m.mu.Unlock()
+	for _, fn := range listeners {
+		fn(strategies)
+	}
+}
+
+// Strategies 返回当前可用的脚本策略，按文件名排序
+func (m *Manager) Strategies() []strategy.Strategy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var strategies []strategy.Strategy
+	for _, path := range m.paths() {
+		if s := m.scripts[path].strategy; s != nil {
+			strategies = append(strategies, s)
+		}
+	}
+	return strategies
+}
+
+// Status 返回脚本目录中每个文件的加载状态，按文件名排序
+func (m *Manager) Status() []ScriptStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	statuses := make([]ScriptStatus, 0, len(m.scripts))
+	for _, path := range m.paths() {
+		e := m.scripts[path]
+		status := ScriptStatus{File: filepath.Base(path), Loaded: e.strategy != nil}
+		if e.strategy != nil {
+			status.Name = e.strategy.Name()
+			status.Timeframe = e.strategy.Timeframe()
+		}
+		if e.err != nil {
+			status.Error = e.err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// paths 返回排序后的脚本路径，调用方需持有 m.mu
+func (m *Manager) paths() []string {
+	paths := make([]string, 0, len(m.scripts))
+	for path := range m.scripts {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
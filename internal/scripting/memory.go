@@ -0,0 +1,514 @@
+package scripting
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// memoryKey 线程本地存储中本次执行的内存计数
+const memoryKey = "memory"
+
+// 估算内存时列表和元组每个元素、字典和集合每项占用的字节数
+const (
+	elemSize  = 16
+	entrySize = 48
+	// maxReprDepth 估算字符串形式长度时的最大嵌套深度
+	maxReprDepth = 32
+)
+
+// memoryBudget 单次执行（加载脚本或一次评估）的内存分配计数
+type memoryBudget struct {
+	limit int64
+	used  int64
+}
+
+// charge 计入一次分配，超过上限时取消线程并返回错误
+func charge(thread *starlark.Thread, bytes int64) error {
+	b, _ := thread.Local(memoryKey).(*memoryBudget)
+	if b == nil || b.limit <= 0 || bytes <= 0 {
+		return nil
+	}
+	b.used = saturatingAdd(b.used, bytes)
+	if b.used > b.limit {
+		reason := fmt.Sprintf("内存分配超过 %d MB", b.limit>>20)
+		thread.Cancel(reason)
+		return errors.New(reason)
+	}
+	return nil
+}
+
+// remaining 返回本次执行剩余可分配的字节数，用于限制估算的遍历范围
+func remaining(thread *starlark.Thread) int64 {
+	b, _ := thread.Local(memoryKey).(*memoryBudget)
+	if b == nil || b.limit <= 0 {
+		return math.MaxInt64
+	}
+	return b.limit - b.used
+}
+
+// 改写后的运算和方法调用对应的内置函数
+var (
+	binaryOps = map[syntax.Token]string{
+		syntax.PLUS:    "_memory_add",
+		syntax.STAR:    "_memory_mul",
+		syntax.PERCENT: "_memory_mod",
+		syntax.PIPE:    "_memory_or",
+	}
+	augmentedOps = map[syntax.Token]struct {
+		name string
+		op   syntax.Token
+	}{
+		syntax.PLUS_EQ:    {"_memory_iadd", syntax.PLUS},
+		syntax.STAR_EQ:    {"_memory_imul", syntax.STAR},
+		syntax.PERCENT_EQ: {"_memory_imod", syntax.PERCENT},
+		syntax.PIPE_EQ:    {"_memory_ior", syntax.PIPE},
+	}
+	// growthMethods 可能分配大量内存的字符串、列表和字典方法
+	growthMethods = map[string]bool{
+		"append": true, "insert": true, "extend": true, "update": true,
+		"join": true, "replace": true, "format": true,
+		"split": true, "rsplit": true, "splitlines": true,
+	}
+)
+
+const methodBuiltinName = "_memory_method"
+
+func init() {
+	// 内置函数以运算符命名，脚本出错时显示为 "Error in *: ..."
+	for op, name := range binaryOps {
+		predeclared[name] = starlark.NewBuiltin(op.String(), binaryBuiltin(op))
+	}
+	for augOp, aug := range augmentedOps {
+		predeclared[aug.name] = starlark.NewBuiltin(augOp.String(), augmentedBuiltin(aug.op))
+	}
+	predeclared[methodBuiltinName] = starlark.NewBuiltin(methodBuiltinName, methodBuiltin)
+
+	// 覆盖按参数长度创建容器或字符串的内置函数
+	for _, name := range []string{"list", "tuple", "sorted", "reversed", "enumerate", "set", "zip", "dict", "str", "repr"} {
+		predeclared[name] = universeBuiltin(name)
+	}
+}
+
+var (
+	exprType  = reflect.TypeOf((*syntax.Expr)(nil)).Elem()
+	exprsType = reflect.TypeOf([]syntax.Expr(nil))
+)
+
+// limitAllocations 把字符串和列表的拼接、重复、格式化以及会增长容器的方法调用改写为先计入内存上限的内置函数，
+// 例如 s * n 改写为 _memory_mul(s, n)，x += y 改写为 x += _memory_iadd(x, y)。
+// 复合赋值的左侧会被再求值一次，因此只能包含变量、属性、下标和运算
+func limitAllocations(f *syntax.File) error {
+	var err error
+	syntax.Walk(f, func(n syntax.Node) bool {
+		// Walk 在遍历完每个节点的子节点后以 nil 调用 f
+		if n == nil || err != nil {
+			return false
+		}
+		if assign, ok := n.(*syntax.AssignStmt); ok {
+			if aug, ok := augmentedOps[assign.Op]; ok {
+				var target syntax.Expr
+				if target, err = cloneTarget(assign.LHS); err != nil {
+					return false
+				}
+				assign.RHS = call(aug.name, assign.OpPos, target, assign.RHS)
+			}
+		}
+
+		v := reflect.ValueOf(n).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			switch field.Type() {
+			case exprType:
+				if !field.IsNil() {
+					field.Set(reflect.ValueOf(rewriteExpr(field.Interface().(syntax.Expr))))
+				}
+			case exprsType:
+				for j := 0; j < field.Len(); j++ {
+					elem := field.Index(j)
+					elem.Set(reflect.ValueOf(rewriteExpr(elem.Interface().(syntax.Expr))))
+				}
+			}
+		}
+		return true
+	})
+	return err
+}
+
+func rewriteExpr(e syntax.Expr) syntax.Expr {
+	switch e := e.(type) {
+	case *syntax.BinaryExpr:
+		if name, ok := binaryOps[e.Op]; ok {
+			return call(name, e.OpPos, e.X, e.Y)
+		}
+	case *syntax.CallExpr:
+		if dot, ok := e.Fn.(*syntax.DotExpr); ok && growthMethods[dot.Name.Name] {
+			name := &syntax.Literal{Token: syntax.STRING, TokenPos: dot.NamePos, Raw: strconv.Quote(dot.Name.Name), Value: dot.Name.Name}
+			e.Fn = call(methodBuiltinName, dot.Dot, dot.X, name)
+		}
+	}
+	return e
+}
+
+func call(name string, pos syntax.Position, args ...syntax.Expr) *syntax.CallExpr {
+	_, end := args[len(args)-1].Span()
+	return &syntax.CallExpr{Fn: &syntax.Ident{NamePos: pos, Name: name}, Lparen: pos, Args: args, Rparen: end}
+}
+
+// cloneTarget 复制复合赋值的左侧，用于计算赋值前的值
+func cloneTarget(e syntax.Expr) (syntax.Expr, error) {
+	switch e := e.(type) {
+	case *syntax.Ident:
+		return &syntax.Ident{NamePos: e.NamePos, Name: e.Name}, nil
+	case *syntax.Literal:
+		return &syntax.Literal{Token: e.Token, TokenPos: e.TokenPos, Raw: e.Raw, Value: e.Value}, nil
+	case *syntax.ParenExpr:
+		x, err := cloneTarget(e.X)
+		return &syntax.ParenExpr{Lparen: e.Lparen, X: x, Rparen: e.Rparen}, err
+	case *syntax.DotExpr:
+		x, err := cloneTarget(e.X)
+		return &syntax.DotExpr{X: x, Dot: e.Dot, NamePos: e.NamePos, Name: &syntax.Ident{NamePos: e.Name.NamePos, Name: e.Name.Name}}, err
+	case *syntax.IndexExpr:
+		x, err := cloneTarget(e.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := cloneTarget(e.Y)
+		return &syntax.IndexExpr{X: x, Lbrack: e.Lbrack, Y: y, Rbrack: e.Rbrack}, err
+	case *syntax.UnaryExpr:
+		x, err := cloneTarget(e.X)
+		return &syntax.UnaryExpr{OpPos: e.OpPos, Op: e.Op, X: x}, err
+	case *syntax.BinaryExpr:
+		x, err := cloneTarget(e.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := cloneTarget(e.Y)
+		return &syntax.BinaryExpr{X: x, OpPos: e.OpPos, Op: e.Op, Y: y}, err
+	}
+	start, _ := e.Span()
+	return nil, fmt.Errorf("%s: 复合赋值的左侧只能包含变量、属性、下标和运算", start)
+}
+
+// binaryBuiltin 计入运算结果的大小后执行二元运算
+func binaryBuiltin(op syntax.Token) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var x, y starlark.Value
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &x, &y); err != nil {
+			return nil, err
+		}
+		if err := charge(thread, binarySize(thread, op, x, y)); err != nil {
+			return nil, err
+		}
+		return starlark.Binary(op, x, y)
+	}
+}
+
+// augmentedBuiltin 计入复合赋值分配的内存后原样返回右侧的值：列表的 += 和字典的 |= 原地扩展，只计入新增部分
+func augmentedBuiltin(op syntax.Token) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var x, y starlark.Value
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &x, &y); err != nil {
+			return nil, err
+		}
+		size := binarySize(thread, op, x, y)
+		switch x.(type) {
+		case *starlark.List, *starlark.Dict:
+			if op == syntax.PLUS || op == syntax.PIPE {
+				size = sizeOf(y)
+			}
+		}
+		if err := charge(thread, size); err != nil {
+			return nil, err
+		}
+		return y, nil
+	}
+}
+
+// methodBuiltin 返回 recv 的方法，调用时先计入可能分配的内存
+func methodBuiltin(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var recv starlark.Value
+	var name string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 2, &recv, &name); err != nil {
+		return nil, err
+	}
+	var method starlark.Value
+	if attrs, ok := recv.(starlark.HasAttrs); ok {
+		var err error
+		if method, err = attrs.Attr(name); err != nil {
+			return nil, err
+		}
+	}
+	if method == nil {
+		return nil, fmt.Errorf("%s has no .%s field or method", recv.Type(), name)
+	}
+	return starlark.NewBuiltin(name, func(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		return chargedCall(thread, method, args, kwargs, methodSize(thread, recv, name, args, kwargs))
+	}), nil
+}
+
+// universeBuiltin 包装同名的内置函数，调用时先计入可能分配的内存
+func universeBuiltin(name string) *starlark.Builtin {
+	fn := starlark.Universe[name]
+	return starlark.NewBuiltin(name, func(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		return chargedCall(thread, fn, args, kwargs, universeSize(thread, name, args, kwargs))
+	})
+}
+
+// chargedCall 调用 fn：能事先估算分配大小（size >= 0）时先计入，否则按返回值的大小计入
+func chargedCall(thread *starlark.Thread, fn starlark.Value, args starlark.Tuple, kwargs []starlark.Tuple, size int64) (starlark.Value, error) {
+	if size >= 0 {
+		if err := charge(thread, size); err != nil {
+			return nil, err
+		}
+	}
+	result, err := starlark.Call(thread, fn, args, kwargs)
+	if err != nil || size >= 0 {
+		return result, err
+	}
+	return result, charge(thread, sizeOf(result))
+}
+
+// binarySize 估算二元运算结果的大小
+func binarySize(thread *starlark.Thread, op syntax.Token, x, y starlark.Value) int64 {
+	switch op {
+	case syntax.STAR:
+		if n, ok := repeatCount(y); ok && isSequence(x) {
+			return saturatingMul(sizeOf(x), n)
+		}
+		if n, ok := repeatCount(x); ok && isSequence(y) {
+			return saturatingMul(sizeOf(y), n)
+		}
+	case syntax.PERCENT:
+		if format, ok := x.(starlark.String); ok {
+			return saturatingAdd(int64(len(format)), reprSize(y, remaining(thread)))
+		}
+		return 0
+	}
+	return saturatingAdd(sizeOf(x), sizeOf(y))
+}
+
+// methodSize 估算方法调用分配的大小，无法事先估算时返回 -1
+func methodSize(thread *starlark.Thread, recv starlark.Value, name string, args starlark.Tuple, kwargs []starlark.Tuple) int64 {
+	switch name {
+	case "append", "insert":
+		return elemSize
+	case "extend":
+		if len(args) > 0 {
+			return lenSize(args[0], elemSize)
+		}
+	case "update":
+		size := int64(len(kwargs)) * entrySize
+		if len(args) > 0 {
+			size = saturatingAdd(size, lenSize(args[0], entrySize))
+		}
+		return size
+	case "join":
+		sep, ok := recv.(starlark.String)
+		if !ok || len(args) == 0 {
+			return -1
+		}
+		seq, ok := args[0].(starlark.Indexable)
+		if !ok {
+			return -1
+		}
+		size := saturatingMul(int64(len(sep)), int64(seq.Len()))
+		for i := 0; i < seq.Len() && size <= remaining(thread); i++ {
+			size = saturatingAdd(size, sizeOf(seq.Index(i)))
+		}
+		return size
+	case "replace":
+		return replaceSize(recv, args)
+	case "format":
+		size := sizeOf(recv)
+		budget := remaining(thread)
+		for _, arg := range args {
+			size = saturatingAdd(size, reprSize(arg, budget))
+		}
+		for _, kv := range kwargs {
+			size = saturatingAdd(size, reprSize(kv[1], budget))
+		}
+		return size
+	}
+	return -1
+}
+
+// replaceSize 计算 s.replace(old, new[, count]) 结果的长度
+func replaceSize(recv starlark.Value, args starlark.Tuple) int64 {
+	s, ok := recv.(starlark.String)
+	if !ok || len(args) < 2 {
+		return -1
+	}
+	old, ok1 := args[0].(starlark.String)
+	repl, ok2 := args[1].(starlark.String)
+	if !ok1 || !ok2 {
+		return -1
+	}
+	n := int64(strings.Count(string(s), string(old)))
+	if len(args) > 2 {
+		if count, err := starlark.AsInt32(args[2]); err == nil && count >= 0 && int64(count) < n {
+			n = int64(count)
+		}
+	}
+	if old == "" && n > int64(utf8.RuneCountInString(string(s)))+1 {
+		n = int64(utf8.RuneCountInString(string(s))) + 1
+	}
+	growth := saturatingMul(n, int64(len(repl)-len(old)))
+	return saturatingAdd(int64(len(s)), max(growth, 0))
+}
+
+// universeSize 估算内置函数调用分配的大小，无法事先估算时返回 -1
+func universeSize(thread *starlark.Thread, name string, args starlark.Tuple, kwargs []starlark.Tuple) int64 {
+	switch name {
+	case "str", "repr":
+		if len(args) == 1 {
+			return reprSize(args[0], remaining(thread))
+		}
+	case "dict":
+		size := int64(len(kwargs)) * entrySize
+		if len(args) > 0 {
+			size = saturatingAdd(size, lenSize(args[0], entrySize))
+		}
+		return size
+	case "zip":
+		var longest int64
+		for _, arg := range args {
+			longest = max(longest, lenSize(arg, elemSize))
+		}
+		return saturatingMul(longest, int64(len(args)+1))
+	case "enumerate", "set":
+		if len(args) > 0 {
+			return lenSize(args[0], 3*elemSize)
+		}
+	default:
+		if len(args) > 0 {
+			return lenSize(args[0], elemSize)
+		}
+	}
+	return -1
+}
+
+// sizeOf 估算值本身占用的大小，容器只计算元素的引用，不包括元素本身
+func sizeOf(v starlark.Value) int64 {
+	switch v := v.(type) {
+	case starlark.String:
+		return int64(len(v))
+	case starlark.Bytes:
+		return int64(len(v))
+	case starlark.Int:
+		if _, ok := v.Int64(); ok {
+			return 0
+		}
+		return int64(v.BigInt().BitLen() / 8)
+	case *starlark.List:
+		return int64(v.Len()) * elemSize
+	case starlark.Tuple:
+		return int64(v.Len()) * elemSize
+	case *starlark.Dict:
+		return int64(v.Len()) * entrySize
+	case *starlark.Set:
+		return int64(v.Len()) * entrySize
+	}
+	return 0
+}
+
+// lenSize 按可迭代对象的长度估算创建同样长度的容器的大小，长度未知时返回 -1
+func lenSize(v starlark.Value, perItem int64) int64 {
+	if n := starlark.Len(v); n >= 0 {
+		return saturatingMul(int64(n), perItem)
+	}
+	return -1
+}
+
+// reprSize 估算值的字符串形式的长度，超过 budget 后停止遍历
+func reprSize(v starlark.Value, budget int64) int64 {
+	var total int64
+	var visit func(v starlark.Value, depth int)
+	visit = func(v starlark.Value, depth int) {
+		if total > budget {
+			return
+		}
+		switch v := v.(type) {
+		case starlark.String:
+			total = saturatingAdd(total, int64(len(v))+2)
+		case starlark.Bytes:
+			total = saturatingAdd(total, int64(len(v))+3)
+		case starlark.Int:
+			total = saturatingAdd(total, 3*sizeOf(v)+20)
+		case *starlark.List, starlark.Tuple:
+			seq := v.(starlark.Indexable)
+			total += 2
+			for i := 0; i < seq.Len() && depth < maxReprDepth && total <= budget; i++ {
+				visit(seq.Index(i), depth+1)
+				total += 2
+			}
+		case *starlark.Dict:
+			total += 2
+			if depth < maxReprDepth {
+				for _, item := range v.Items() {
+					visit(item[0], depth+1)
+					visit(item[1], depth+1)
+					total += 4
+				}
+			}
+		case starlark.HasAttrs:
+			total += 16
+			if depth < maxReprDepth {
+				for _, name := range v.AttrNames() {
+					if attr, err := v.Attr(name); err == nil && attr != nil {
+						total += int64(len(name)) + 4
+						visit(attr, depth+1)
+					}
+				}
+			}
+		default:
+			total += 32
+		}
+	}
+	visit(v, 0)
+	return total
+}
+
+func isSequence(v starlark.Value) bool {
+	switch v.(type) {
+	case starlark.String, starlark.Bytes, *starlark.List, starlark.Tuple:
+		return true
+	}
+	return false
+}
+
+// repeatCount 返回序列重复运算的次数，超出 int32 范围的次数由 Starlark 自身报错
+func repeatCount(v starlark.Value) (int64, bool) {
+	if _, ok := v.(starlark.Int); !ok {
+		return 0, false
+	}
+	n, err := starlark.AsInt32(v)
+	if err != nil {
+		return 0, false
+	}
+	return int64(n), true
+}
+
+func saturatingAdd(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+func saturatingMul(a, b int64) int64 {
+	if a <= 0 || b <= 0 {
+		return 0
+	}
+	if a > math.MaxInt64/b {
+		return math.MaxInt64
+	}
+	return a * b
+}
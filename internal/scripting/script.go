@@ -0,0 +1,213 @@
+package scripting
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/qqqq/eth-trading-system/internal/dsl"
+	"github.com/qqqq/eth-trading-system/internal/models"
+	"github.com/qqqq/eth-trading-system/internal/strategy"
+	"github.com/qqqq/eth-trading-system/internal/utils"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+	"go.starlark.net/syntax"
+)
+
+// fileOptions 允许 while 循环和顶层 if/for，执行步数上限防止死循环
+var fileOptions = &syntax.FileOptions{Set: true, While: true, TopLevelControl: true}
+
+// ScriptStrategy 由 Starlark 脚本实现的策略。脚本需定义 evaluate(ctx) 函数并返回 buy(...)、sell(...)、
+// hold(...) 创建的信号或 None（视为 HOLD），可选定义全局变量 name、symbol 和 timeframe：
+//
+//	name = "RSIDip"
+//	timeframe = "1Hour"
+//
+//	def evaluate(ctx):
+//	    rsi = ctx.rsi(14)
+//	    if crossover(rsi, 30):
+//	        return buy(reason = "RSI 上穿 30", strength = 0.6)
+//	    return hold()
+//
+// 脚本加载后全局变量被冻结，每次评估都基于完整的K线重新计算，与 Go 策略一样不保存状态
+type ScriptStrategy struct {
+	name      string
+	symbol    string
+	timeframe string
+	path      string
+	limits    Limits
+	evaluate  starlark.Callable
+
+	mu sync.Mutex
+	// programs 缓存 ctx.expr 编译过的表达式
+	programs map[string]*dsl.Program
+}
+
+// Load 在资源上限内执行脚本并返回策略，path 用于错误信息和默认名称
+func Load(path string, src []byte, limits Limits) (*ScriptStrategy, error) {
+	s := &ScriptStrategy{
+		name:     strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		symbol:   strategy.DefaultSymbol,
+		path:     path,
+		limits:   limits,
+		programs: make(map[string]*dsl.Program),
+	}
+
+	var globals starlark.StringDict
+	f, err := fileOptions.Parse(path, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := limitAllocations(f); err != nil {
+		return nil, err
+	}
+	program, err := starlark.FileProgram(f, predeclared.Has)
+	if err != nil {
+		return nil, err
+	}
+	err = run(s.name, limits, func(thread *starlark.Thread) error {
+		var err error
+		globals, err = program.Init(thread, predeclared)
+		globals.Freeze()
+		return err
+	})
+	if err != nil {
+		return nil, scriptError(err)
+	}
+
+	fn, ok := globals["evaluate"].(*starlark.Function)
+	if !ok {
+		return nil, fmt.Errorf("脚本需要定义 evaluate(ctx) 函数")
+	}
+	if fn.NumParams() != 1 {
+		return nil, fmt.Errorf("evaluate 应只接受一个参数 ctx，当前为 %d 个", fn.NumParams())
+	}
+	s.evaluate = fn
+
+	for key, target := range map[string]*string{"name": &s.name, "symbol": &s.symbol, "timeframe": &s.timeframe} {
+		v, ok := globals[key]
+		if !ok {
+			continue
+		}
+		str, ok := starlark.AsString(v)
+		if !ok {
+			return nil, fmt.Errorf("全局变量 %s 应为字符串，当前为 %s", key, v.Type())
+		}
+		*target = str
+	}
+	if s.name == "" {
+		return nil, fmt.Errorf("name 不能为空")
+	}
+	if !strings.EqualFold(s.symbol, strategy.DefaultSymbol) {
+		return nil, fmt.Errorf("不支持的标的 %q，当前仅支持 %s", s.symbol, strategy.DefaultSymbol)
+	}
+	s.symbol = strategy.DefaultSymbol
+	if s.timeframe != "" && (!models.IsValidTimeframe(s.timeframe) || s.timeframe == "1Min") {
+		return nil, fmt.Errorf("不支持的时间框架 %q，可选值: 5Min, 15Min, 1Hour, 4Hour, 1Day", s.timeframe)
+	}
+	return s, nil
+}
+
+func (s *ScriptStrategy) Name() string      { return s.name }
+func (s *ScriptStrategy) Symbol() string    { return s.symbol }
+func (s *ScriptStrategy) Timeframe() string { return s.timeframe }
+
+// Path 返回脚本文件路径
+func (s *ScriptStrategy) Path() string {
+	return s.path
+}
+
+// Evaluate 调用脚本的 evaluate(ctx)。脚本出错或超出资源上限时返回 HOLD 并记录错误
+func (s *ScriptStrategy) Evaluate(data []models.Bar, analysisResult *models.AnalysisResult) *models.TradeSignal {
+	if len(data) == 0 {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "不足够的数据"}
+	}
+
+	var result starlark.Value
+	err := run(s.name, s.limits, func(thread *starlark.Thread) error {
+		var err error
+		result, err = starlark.Call(thread, s.evaluate, starlark.Tuple{newContext(s, data, analysisResult)}, nil)
+		return err
+	})
+	if err == nil {
+		var signal *models.TradeSignal
+		if signal, err = s.toSignal(result, data); err == nil {
+			return signal
+		}
+	}
+	err = scriptError(err)
+	utils.Log.WithError(err).WithField("script", s.path).Error("脚本策略执行失败")
+	return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: fmt.Sprintf("脚本执行失败: %v", err)}
+}
+
+// toSignal 把脚本返回值转换为交易信号
+func (s *ScriptStrategy) toSignal(v starlark.Value, data []models.Bar) (*models.TradeSignal, error) {
+	if v == starlark.None {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "脚本未返回信号"}, nil
+	}
+	st, ok := v.(*starlarkstruct.Struct)
+	if !ok || st.Constructor() != signalCtor {
+		return nil, fmt.Errorf("evaluate 应返回 buy()、sell()、hold() 或 None，当前为 %s", v.Type())
+	}
+
+	signal := &models.TradeSignal{StrategyName: s.Name()}
+	action, _ := st.Attr("action")
+	signal.Action = models.SignalAction(action.(starlark.String).GoString())
+	reason, _ := st.Attr("reason")
+	signal.Reason = reason.(starlark.String).GoString()
+
+	values, _ := st.Attr("indicators")
+	if dict := values.(*starlark.Dict); dict.Len() > 0 {
+		signal.Indicators = make(map[string]float64, dict.Len())
+		for _, item := range dict.Items() {
+			key, ok := starlark.AsString(item[0])
+			f, isNumber := starlark.AsFloat(item[1])
+			if !ok || !isNumber {
+				return nil, fmt.Errorf("indicators 的键应为字符串、值应为数字，当前为 %s: %s", item[0], item[1])
+			}
+			if !math.IsNaN(f) {
+				signal.Indicators[key] = f
+			}
+		}
+	}
+	if signal.Action == models.ActionHold {
+		return signal, nil
+	}
+
+	signal.Price = data[len(data)-1].Close
+	for name, target := range map[string]*float64{"strength": &signal.Strength, "size": &signal.Size, "stop_loss": &signal.StopLoss, "take_profit": &signal.TakeProfit} {
+		v, _ := st.Attr(name)
+		*target = float64(v.(starlark.Float))
+	}
+	if signal.Reason == "" {
+		signal.Reason = fmt.Sprintf("脚本 %s 产生 %s 信号", s.name, signal.Action)
+	}
+	return signal, nil
+}
+
+// program 编译并缓存 ctx.expr 的表达式
+func (s *ScriptStrategy) program(src string) (*dsl.Program, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.programs[src]; ok {
+		return p, nil
+	}
+	p, err := dsl.Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	s.programs[src] = p
+	return p, nil
+}
+
+// scriptError 为 Starlark 运行错误附加调用栈，便于定位脚本中的出错行
+func scriptError(err error) error {
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		return errors.New(evalErr.Backtrace())
+	}
+	return err
+}
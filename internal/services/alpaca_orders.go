@@ -0,0 +1,101 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/qqqq/eth-trading-system/internal/protection"
+)
+
+// AlpacaOrderRequest 对应 Alpaca 交易 API POST /v2/orders 的请求体，数值按接口要求使用字符串
+type AlpacaOrderRequest struct {
+	Symbol        string            `json:"symbol"`
+	Qty           string            `json:"qty"`
+	Side          string            `json:"side"`
+	Type          string            `json:"type"`
+	TimeInForce   string            `json:"time_in_force"`
+	LimitPrice    string            `json:"limit_price,omitempty"`
+	StopPrice     string            `json:"stop_price,omitempty"`
+	TrailPercent  string            `json:"trail_percent,omitempty"`
+	TrailPrice    string            `json:"trail_price,omitempty"`
+	OrderClass    string            `json:"order_class,omitempty"`
+	TakeProfit    *AlpacaTakeProfit `json:"take_profit,omitempty"`
+	StopLoss      *AlpacaStopLoss   `json:"stop_loss,omitempty"`
+	ClientOrderID string            `json:"client_order_id,omitempty"`
+}
+
+type AlpacaTakeProfit struct {
+	LimitPrice string `json:"limit_price"`
+}
+
+type AlpacaStopLoss struct {
+	StopPrice string `json:"stop_price"`
+}
+
+// AlpacaProtectiveOrders 将保护性订单映射为 Alpaca 原生订单，group 用于生成 client_order_id。
+// 固定止损和止盈合并为一个 oco 订单；设置了移动止损时改用 trailing_stop 订单，
+// 由于原生 oco 只支持限价单与止损单组合，移动止损与止盈分别下单，其中一个成交后需撤销另一个。
+// 移动止损单无法同时携带固定止损（包括保本止损），该部分仍由系统按K线检查。
+// Alpaca 的加密货币只支持 market、limit 和 stop_limit 订单，加密货币标的返回错误，其保护性订单只能由系统执行
+func AlpacaProtectiveOrders(b *protection.Bracket, group string) ([]AlpacaOrderRequest, error) {
+	if isCryptoSymbol(b.Symbol) {
+		return nil, fmt.Errorf("Alpaca 不支持加密货币 %s 的 oco、stop 和 trailing_stop 订单，保护性订单由系统执行", b.Symbol)
+	}
+	base := AlpacaOrderRequest{
+		Symbol:      b.Symbol,
+		Qty:         formatDecimal(b.Quantity),
+		Side:        "sell",
+		TimeInForce: "gtc",
+	}
+	trailing := b.TrailPercent > 0 || b.TrailDistance > 0
+
+	if !trailing && b.StopLoss > 0 && b.TakeProfit > 0 {
+		order := base
+		order.Type = "limit"
+		order.OrderClass = "oco"
+		order.TakeProfit = &AlpacaTakeProfit{LimitPrice: formatDecimal(b.TakeProfit)}
+		order.StopLoss = &AlpacaStopLoss{StopPrice: formatDecimal(b.StopLoss)}
+		order.ClientOrderID = group + "-oco"
+		return []AlpacaOrderRequest{order}, nil
+	}
+
+	var orders []AlpacaOrderRequest
+	switch {
+	case trailing:
+		order := base
+		order.Type = "trailing_stop"
+		if b.TrailPercent > 0 {
+			order.TrailPercent = formatDecimal(b.TrailPercent)
+		} else {
+			order.TrailPrice = formatDecimal(b.TrailDistance)
+		}
+		order.ClientOrderID = group + "-trail"
+		orders = append(orders, order)
+	case b.StopLoss > 0:
+		order := base
+		order.Type = "stop"
+		order.StopPrice = formatDecimal(b.StopLoss)
+		order.ClientOrderID = group + "-sl"
+		orders = append(orders, order)
+	}
+	if b.TakeProfit > 0 {
+		order := base
+		order.Type = "limit"
+		order.LimitPrice = formatDecimal(b.TakeProfit)
+		order.ClientOrderID = group + "-tp"
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
+
+// isCryptoSymbol 判断是否为 Alpaca 加密货币交易对，如 ETH/USD
+func isCryptoSymbol(symbol string) bool {
+	return strings.Contains(symbol, "/")
+}
+
+// formatDecimal 保留 8 位小数并去掉末尾的 0，避免浮点误差产生过长的数字
+func formatDecimal(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e8)/1e8, 'f', -1, 64)
+}
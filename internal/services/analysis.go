@@ -2,13 +2,19 @@
 package services
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/qqqq/eth-trading-system/internal/analysis"
 	"github.com/qqqq/eth-trading-system/internal/models"
 	"github.com/qqqq/eth-trading-system/internal/storage"
+	"github.com/qqqq/eth-trading-system/internal/strategy"
+	"github.com/qqqq/eth-trading-system/internal/utils"
 )
 
+// auxiliaryLookbackBars 加载辅助时间框架时回溯的K线数量
+const auxiliaryLookbackBars = 200
+
 type AnalysisService struct {
 	engine          analysis.Engine
 	dataRepo        storage.DataRepository
@@ -29,12 +35,46 @@ func (s *AnalysisService) AnalyzeMarket(timeframe string, bars []models.Bar) (*m
 		return nil, err
 	}
 
-	signals := s.strategyService.EvaluateStrategies(bars, analysisResult)
+	ctx := strategy.NewMultiTimeframeContext(timeframe, bars, analysisResult)
+	for _, tf := range s.strategyService.RequiredTimeframes(timeframe) {
+		data, err := s.loadAlignedTimeframe(tf, ctx.AsOf)
+		if err != nil {
+			utils.Log.WithError(err).Warnf("加载辅助时间框架数据失败: %s", tf)
+			continue
+		}
+		ctx.Data[tf] = data
+	}
+
+	signals := s.strategyService.EvaluateStrategies(ctx)
 	analysisResult.StrategySignals = signals
 
 	return analysisResult, nil
 }
 
+// loadAlignedTimeframe 加载辅助时间框架在 asOf 之前已收盘的K线并进行分析，
+// 未收盘的K线会被剔除，避免策略看到主时间框架当前时刻之后的数据
+func (s *AnalysisService) loadAlignedTimeframe(timeframe string, asOf time.Time) (*strategy.TimeframeData, error) {
+	duration, ok := models.TimeframeDuration(timeframe)
+	if !ok {
+		return nil, fmt.Errorf("不支持的时间框架: %s", timeframe)
+	}
+
+	bars, err := s.dataRepo.GetHistoricalData(timeframe, asOf.Add(-duration*auxiliaryLookbackBars), asOf)
+	if err != nil {
+		return nil, err
+	}
+	bars = strategy.ClosedBarsAsOf(bars, timeframe, asOf)
+
+	data := &strategy.TimeframeData{Bars: bars}
+	analysisResult, err := s.engine.Analyze(bars)
+	if err != nil {
+		utils.Log.WithError(err).Warnf("辅助时间框架分析失败: %s", timeframe)
+		return data, nil
+	}
+	data.Analysis = analysisResult
+	return data, nil
+}
+
 func (s *AnalysisService) GetLatestAnalysis(timeframe string) (*models.AnalysisResult, error) {
 	// 从数据库获取最近的一定数量的K线数据
 	end := time.Now()
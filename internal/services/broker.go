@@ -0,0 +1,38 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// Broker 执行订单并返回成交回报
+type Broker interface {
+	SubmitOrder(order models.Order) (*models.Fill, error)
+}
+
+// PaperBroker 模拟经纪商，按订单参考价格立即全部成交并收取手续费
+type PaperBroker struct {
+	feeRate float64
+}
+
+func NewPaperBroker(feeRate float64) *PaperBroker {
+	return &PaperBroker{feeRate: feeRate}
+}
+
+func (b *PaperBroker) SubmitOrder(order models.Order) (*models.Fill, error) {
+	if order.Quantity <= 0 || order.Price <= 0 {
+		return nil, fmt.Errorf("订单数量和价格必须大于 0")
+	}
+	return &models.Fill{
+		OrderID:      order.ID,
+		StrategyName: order.StrategyName,
+		Symbol:       order.Symbol,
+		Side:         order.Side,
+		Quantity:     order.Quantity,
+		Price:        order.Price,
+		Fee:          order.Notional() * b.feeRate,
+		Timestamp:    time.Now().UTC(),
+	}, nil
+}
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"math"
+	"sync"
 	"time"
 
 	"github.com/qqqq/eth-trading-system/internal/datamanager"
@@ -8,46 +10,153 @@ import (
 	"github.com/qqqq/eth-trading-system/internal/utils"
 )
 
+// latestPriceTimeframe 仅用于采集最新价格，不属于可配置的历史数据时间框架
+const latestPriceTimeframe = "1Min"
+
 type DataCollectionService struct {
 	dataManager *datamanager.DataManager
+
+	mu               sync.Mutex
+	timeframes       []string
+	tickers          map[string]chan struct{}
+	priceMovePercent float64
+	lastPrice        float64
+	barListeners     []func(timeframe string)
+	priceListeners   []func(bar models.Bar)
+	alertListeners   []func(bar models.Bar, previous, changePercent float64)
 }
 
-func NewDataCollectionService(dataManager *datamanager.DataManager) *DataCollectionService {
+func NewDataCollectionService(dataManager *datamanager.DataManager, timeframes ...string) *DataCollectionService {
+	if len(timeframes) == 0 {
+		timeframes = []string{"5Min", "15Min", "1Hour", "4Hour", "1Day"}
+	}
 	return &DataCollectionService{
 		dataManager: dataManager,
+		timeframes:  timeframes,
+		tickers:     make(map[string]chan struct{}),
 	}
 }
 
 func (s *DataCollectionService) Start() {
 	s.initializeData()
 
-	go s.startTicker("1Min", 1*time.Minute)
-	go s.startTicker("5Min", 5*time.Minute)
-	go s.startTicker("15Min", 15*time.Minute)
-	go s.startTicker("1Hour", 1*time.Hour)
-	go s.startTicker("4Hour", 4*time.Hour)
-	go s.startTicker("1Day", 24*time.Hour)
+	s.mu.Lock()
+	s.startTickerLocked(latestPriceTimeframe)
+	for _, timeframe := range s.timeframes {
+		s.startTickerLocked(timeframe)
+	}
+	s.mu.Unlock()
 	utils.Log.Info("数据收集服务已启动")
 }
 
+// SetTimeframes 调整启用的时间框架：停止被移除的采集任务，为新增的时间框架初始化历史数据并启动采集
+func (s *DataCollectionService) SetTimeframes(timeframes []string) {
+	s.mu.Lock()
+	enabled := make(map[string]bool, len(timeframes))
+	for _, timeframe := range timeframes {
+		enabled[timeframe] = true
+	}
+
+	for _, timeframe := range s.timeframes {
+		if !enabled[timeframe] {
+			s.stopTickerLocked(timeframe)
+			utils.Log.Infof("停止数据收集，时间框架: %s", timeframe)
+		}
+	}
+
+	var added []string
+	for _, timeframe := range timeframes {
+		if _, running := s.tickers[timeframe]; !running {
+			s.startTickerLocked(timeframe)
+			added = append(added, timeframe)
+		}
+	}
+	s.timeframes = timeframes
+	s.mu.Unlock()
+
+	for _, timeframe := range added {
+		utils.Log.Infof("启动数据收集，时间框架: %s", timeframe)
+		go s.collectAndStoreHistoricalData(timeframe)
+	}
+}
+
+// Timeframes 返回当前启用的时间框架
+func (s *DataCollectionService) Timeframes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.timeframes...)
+}
+
+// SetPriceMoveAlert 设置最新价格变动告警阈值（百分比），0 表示关闭
+func (s *DataCollectionService) SetPriceMoveAlert(percent float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.priceMovePercent = percent
+}
+
+// OnBars 注册回调，每次成功采集某个时间框架的K线后调用
+func (s *DataCollectionService) OnBars(fn func(timeframe string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.barListeners = append(s.barListeners, fn)
+}
+
+// OnPrice 注册回调，每次成功采集最新价格后调用
+func (s *DataCollectionService) OnPrice(fn func(bar models.Bar)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.priceListeners = append(s.priceListeners, fn)
+}
+
+// OnPriceAlert 注册回调，最新价格变动超过告警阈值时调用
+func (s *DataCollectionService) OnPriceAlert(fn func(bar models.Bar, previous, changePercent float64)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alertListeners = append(s.alertListeners, fn)
+}
+
 func (s *DataCollectionService) initializeData() {
 	s.initializeHistoricalData()
 	s.collectAndStoreLatestPrice()
 }
 
 func (s *DataCollectionService) initializeHistoricalData() {
-	timeframes := []string{"5Min", "15Min", "1Hour", "4Hour", "1Day"}
-	for _, timeframe := range timeframes {
+	for _, timeframe := range s.Timeframes() {
 		utils.Log.Infof("初始化历史数据，时间框架: %s", timeframe)
 		s.collectAndStoreHistoricalData(timeframe)
 	}
 }
 
-func (s *DataCollectionService) startTicker(timeframe string, interval time.Duration) {
+func (s *DataCollectionService) startTickerLocked(timeframe string) {
+	interval, ok := models.TimeframeDuration(timeframe)
+	if !ok {
+		utils.Log.Errorf("不支持的时间框架: %s", timeframe)
+		return
+	}
+	stop := make(chan struct{})
+	s.tickers[timeframe] = stop
+	go s.startTicker(timeframe, interval, stop)
+}
+
+func (s *DataCollectionService) stopTickerLocked(timeframe string) {
+	if stop, ok := s.tickers[timeframe]; ok {
+		close(stop)
+		delete(s.tickers, timeframe)
+	}
+}
+
+func (s *DataCollectionService) startTicker(timeframe string, interval time.Duration, stop <-chan struct{}) {
 	ticker := time.NewTicker(interval)
-	for range ticker.C {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
 		utils.Log.Infof("收集并存储数据，时间框架: %s", timeframe)
-		if timeframe == "1Min" {
+		if timeframe == latestPriceTimeframe {
 			s.collectAndStoreLatestPrice()
 			continue
 		}
@@ -59,6 +168,49 @@ func (s *DataCollectionService) collectAndStoreLatestPrice() {
 	err := s.dataManager.CollectAndStoreLatestPrice()
 	if err != nil {
 		utils.Log.WithError(err).Error("收集并存储最新价格失败")
+		return
+	}
+	bar, err := s.dataManager.GetLatestPrice()
+	if err != nil {
+		return
+	}
+	s.checkPriceMove(bar)
+
+	s.mu.Lock()
+	listeners := append([]func(models.Bar){}, s.priceListeners...)
+	s.mu.Unlock()
+	for _, fn := range listeners {
+		fn(*bar)
+	}
+}
+
+// checkPriceMove 比较本次与上次采集的最新价格，变动超过阈值时记录告警
+func (s *DataCollectionService) checkPriceMove(bar *models.Bar) {
+	s.mu.Lock()
+	lastPrice, threshold := s.lastPrice, s.priceMovePercent
+	s.lastPrice = bar.Close
+	s.mu.Unlock()
+
+	if threshold <= 0 || lastPrice == 0 {
+		return
+	}
+
+	changePercent := (bar.Close - lastPrice) / lastPrice * 100
+	if math.Abs(changePercent) < threshold {
+		return
+	}
+	utils.Log.WithFields(map[string]interface{}{
+		"previous":  lastPrice,
+		"price":     bar.Close,
+		"change":    changePercent,
+		"threshold": threshold,
+	}).Warn("最新价格变动超过告警阈值")
+
+	s.mu.Lock()
+	listeners := append([]func(models.Bar, float64, float64){}, s.alertListeners...)
+	s.mu.Unlock()
+	for _, fn := range listeners {
+		fn(*bar, lastPrice, changePercent)
 	}
 }
 
@@ -68,6 +220,14 @@ func (s *DataCollectionService) collectAndStoreHistoricalData(timeframe string)
 	err := s.dataManager.CollectAndStoreHistoricalData(timeframe, start, end)
 	if err != nil {
 		utils.Log.WithError(err).Errorf("收集并存储历史数据失败，时间框架: %s", timeframe)
+		return
+	}
+
+	s.mu.Lock()
+	listeners := append([]func(string){}, s.barListeners...)
+	s.mu.Unlock()
+	for _, fn := range listeners {
+		fn(timeframe)
 	}
 }
 
@@ -1,12 +1,17 @@
 package services
 
 import (
+	"sync"
+
 	"github.com/qqqq/eth-trading-system/internal/models"
 	"github.com/qqqq/eth-trading-system/internal/strategy"
 )
 
 type StrategyService struct {
+	mu         sync.RWMutex
 	strategies []strategy.Strategy
+	// scripted 脚本策略，与配置中的策略分开保存，配置热更新不影响已加载的脚本
+	scripted []strategy.Strategy
 }
 
 func NewStrategyService(strategies ...strategy.Strategy) *StrategyService {
@@ -16,14 +21,76 @@ func NewStrategyService(strategies ...strategy.Strategy) *StrategyService {
 }
 
 func (s *StrategyService) AddStrategy(strategy strategy.Strategy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.strategies = append(s.strategies, strategy)
 }
 
-func (s *StrategyService) EvaluateStrategies(data []models.Bar, analysisResult *models.AnalysisResult) []*models.TradeSignal {
+// SetStrategies 替换全部策略，用于配置热更新
+func (s *StrategyService) SetStrategies(strategies ...strategy.Strategy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.strategies = strategies
+}
+
+// SetScriptStrategies 替换全部脚本策略，用于脚本目录热加载
+func (s *StrategyService) SetScriptStrategies(strategies ...strategy.Strategy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripted = strategies
+}
+
+// applicable 返回适用于该时间框架的策略，未绑定时间框架的策略对所有时间框架生效
+func (s *StrategyService) applicable(timeframe string) []strategy.Strategy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var strategies []strategy.Strategy
+	for _, list := range [][]strategy.Strategy{s.strategies, s.scripted} {
+		for _, st := range list {
+			if st.Timeframe() == "" || st.Timeframe() == timeframe {
+				strategies = append(strategies, st)
+			}
+		}
+	}
+	return strategies
+}
+
+// RequiredTimeframes 返回评估该时间框架的策略时还需要的其他时间框架
+func (s *StrategyService) RequiredTimeframes(timeframe string) []string {
+	var timeframes []string
+	for _, tf := range strategy.RequiredTimeframes(s.applicable(timeframe)) {
+		if tf != timeframe {
+			timeframes = append(timeframes, tf)
+		}
+	}
+	return timeframes
+}
+
+// EvaluateStrategies 在多时间框架上下文中评估适用于主时间框架的策略
+func (s *StrategyService) EvaluateStrategies(ctx *strategy.MultiTimeframeContext) []*models.TradeSignal {
 	var signals []*models.TradeSignal
-	for _, strategy := range s.strategies {
-		signal := strategy.Evaluate(data, analysisResult)
+	for _, st := range s.applicable(ctx.Timeframe) {
+		signal := strategy.EvaluateInContext(st, ctx)
 		signals = append(signals, signal)
 	}
 	return signals
 }
+
+// OnFill 将成交通知给需要按实际成交更新状态的策略
+func (s *StrategyService) OnFill(fill models.Fill) {
+	s.mu.RLock()
+	var listeners []strategy.FillListener
+	for _, list := range [][]strategy.Strategy{s.strategies, s.scripted} {
+		for _, st := range list {
+			if l, ok := st.(strategy.FillListener); ok {
+				listeners = append(listeners, l)
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, l := range listeners {
+		l.OnFill(fill)
+	}
+}
@@ -0,0 +1,520 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/config"
+	"github.com/qqqq/eth-trading-system/internal/models"
+	"github.com/qqqq/eth-trading-system/internal/portfolio"
+	"github.com/qqqq/eth-trading-system/internal/protection"
+	"github.com/qqqq/eth-trading-system/internal/risk"
+	"github.com/qqqq/eth-trading-system/internal/sizing"
+	"github.com/qqqq/eth-trading-system/internal/storage"
+	"github.com/qqqq/eth-trading-system/internal/utils"
+)
+
+// maxBookReturns 每个策略保留的已平仓交易收益率数量，用于凯利公式
+const maxBookReturns = 200
+
+// TradingService 将策略信号转换为订单，所有订单先经过风控检查再提交给经纪商
+type TradingService struct {
+	broker Broker
+	risk   *risk.Manager
+	repo   storage.ProtectionRepository
+
+	mu      sync.Mutex
+	enabled bool
+	sizer   *sizing.Sizer
+	planner *protection.Planner
+	// lastSignals 记录每个策略在每个时间框架上已处理的最新信号K线，避免重复下单
+	lastSignals map[string]time.Time
+	// seeds 交易开始时各时间框架已保存的最新K线，不晚于它的信号视为已处理
+	seeds map[string]time.Time
+	// books 记录每个策略自己开的仓位及已平仓交易的收益率
+	books map[string]*strategyBook
+	// brackets 每个策略持仓的保护性订单
+	brackets      map[string]*protection.Bracket
+	orderSeq      int64
+	fillListeners []func(fill models.Fill)
+	// rejectListeners 和 killListeners 在订单被风控拒绝和熔断触发时调用
+	rejectListeners []func(order models.Order, rejection *risk.Rejection)
+	killListeners   []func(reason string)
+}
+
+type strategyBook struct {
+	portfolio.Holding
+	returns []float64
+}
+
+func NewTradingService(broker Broker, riskManager *risk.Manager, repo storage.ProtectionRepository) *TradingService {
+	return &TradingService{
+		broker:      broker,
+		risk:        riskManager,
+		repo:        repo,
+		lastSignals: make(map[string]time.Time),
+		seeds:       make(map[string]time.Time),
+		books:       make(map[string]*strategyBook),
+		brackets:    make(map[string]*protection.Bracket),
+	}
+}
+
+// SetConfig 更新交易开关、仓位计算和保护性订单设置
+func (s *TradingService) SetConfig(cfg config.TradingConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enabled = cfg.Enabled
+	s.sizer = sizing.NewSizer(cfg.Sizing)
+	s.planner = protection.NewPlanner(cfg.Protection)
+}
+
+// SetRiskConfig 更新风控限制，配置开启熔断开关且设置了 flattenOnKill 时平掉全部持仓
+func (s *TradingService) SetRiskConfig(cfg config.RiskConfig) {
+	if !s.risk.SetConfig(cfg) {
+		return
+	}
+	s.notifyKill("配置开启熔断开关")
+	if cfg.FlattenOnKill {
+		s.flatten("配置开启熔断开关")
+	}
+}
+
+// OnFill 注册回调，每笔订单成交后调用
+func (s *TradingService) OnFill(fn func(fill models.Fill)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fillListeners = append(s.fillListeners, fn)
+}
+
+// OnRejection 注册回调，订单被风控拒绝时调用
+func (s *TradingService) OnRejection(fn func(order models.Order, rejection *risk.Rejection)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejectListeners = append(s.rejectListeners, fn)
+}
+
+// OnKill 注册回调，熔断由配置或手动触发时调用
+func (s *TradingService) OnKill(fn func(reason string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.killListeners = append(s.killListeners, fn)
+}
+
+func (s *TradingService) notifyKill(reason string) {
+	s.mu.Lock()
+	listeners := append([]func(string){}, s.killListeners...)
+	s.mu.Unlock()
+	for _, fn := range listeners {
+		fn(reason)
+	}
+}
+
+// Restore 重放历史成交，恢复风控和各策略的持仓记录，并恢复已保存的保护性订单。
+// 有持仓但没有保存保护性订单的策略按当前配置和恢复的平均成本重新生成
+func (s *TradingService) Restore(fills []models.Fill) error {
+	symbols := make(map[string]string)
+	for _, fill := range fills {
+		s.risk.RecordFill(fill)
+		s.recordBook(fill)
+		symbols[fill.StrategyName] = fill.Symbol
+	}
+	saved, err := s.repo.Brackets()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.brackets = make(map[string]*protection.Bracket)
+	for i := range saved {
+		bracket := &saved[i]
+		book := s.book(bracket.StrategyName)
+		if book.Quantity <= 0 {
+			s.deleteBracket(bracket.StrategyName)
+			continue
+		}
+		bracket.Quantity = book.Quantity
+		s.brackets[bracket.StrategyName] = bracket
+	}
+	if len(s.brackets) > 0 {
+		utils.Log.Infof("已恢复 %d 个保护性订单", len(s.brackets))
+	}
+
+	for name, book := range s.books {
+		if book.Quantity <= 0 || s.brackets[name] != nil {
+			continue
+		}
+		bracket := s.planner.Attach(symbols[name], name, book.Quantity, book.AvgCost, time.Now().UTC(), nil, nil)
+		if bracket == nil {
+			continue
+		}
+		s.brackets[name] = bracket
+		s.saveBracket(bracket)
+		utils.Log.WithFields(map[string]interface{}{
+			"strategy":    name,
+			"entry":       bracket.Entry,
+			"stop_loss":   bracket.StopLoss,
+			"take_profit": bracket.TakeProfit,
+			"trailing":    bracket.TrailingStop(),
+		}).Warn("持仓没有已保存的保护性订单，已按当前配置和平均成本重新生成")
+	}
+	return nil
+}
+
+// SeedSignals 设置时间框架的信号基准，在交易开始时以已保存的最新K线时间调用，
+// 之后只对更新的K线产生的信号下单，以免重启或重新开启交易后对已经处理过的K线重复交易
+func (s *TradingService) SeedSignals(timeframe string, latest time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seeds[timeframe] = latest
+}
+
+// Enabled 返回是否按策略信号下单
+func (s *TradingService) Enabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enabled
+}
+
+// HandleSignals 处理一批策略信号，bars 为产生信号的K线，用于计算仓位
+func (s *TradingService) HandleSignals(signals []*models.TradeSignal, bars []models.Bar) {
+	for _, signal := range signals {
+		if signal == nil || !signal.Action.IsTrade() {
+			continue
+		}
+		order, ok := s.orderFor(signal, bars)
+		if !ok {
+			continue
+		}
+		fill, err := s.submit(order, true)
+		if err != nil {
+			if _, rejected := err.(*risk.Rejection); !rejected {
+				utils.Log.WithError(err).WithField("strategy", signal.StrategyName).Error("提交订单失败")
+			}
+			continue
+		}
+		if fill.Side == models.SideBuy {
+			s.protect(signal, fill, bars)
+		}
+	}
+}
+
+// protect 按策略加仓后的持仓数量和平均成本重新生成保护性订单
+func (s *TradingService) protect(signal *models.TradeSignal, fill *models.Fill, bars []models.Bar) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	book := s.book(signal.StrategyName)
+	bracket := s.planner.Attach(fill.Symbol, signal.StrategyName, book.Quantity, book.AvgCost, fill.Timestamp, signal, bars)
+	if bracket == nil {
+		s.deleteBracket(signal.StrategyName)
+		return
+	}
+	s.brackets[signal.StrategyName] = bracket
+	s.saveBracket(bracket)
+	utils.Log.WithFields(map[string]interface{}{
+		"strategy":    signal.StrategyName,
+		"quantity":    bracket.Quantity,
+		"entry":       bracket.Entry,
+		"stop_loss":   bracket.StopLoss,
+		"take_profit": bracket.TakeProfit,
+		"trailing":    bracket.TrailingStop(),
+	}).Info("已设置保护性订单")
+}
+
+// EvaluateBar 用新K线检查标的所有持仓的保护性订单，触发时平仓
+func (s *TradingService) EvaluateBar(symbol string, bar models.Bar) {
+	s.checkProtection(symbol, func(b *protection.Bracket) *protection.Exit { return b.OnBar(bar) })
+}
+
+// checkProtection 对标的的每个保护性订单调用 evaluate，并为触发的订单提交平仓单
+func (s *TradingService) checkProtection(symbol string, evaluate func(b *protection.Bracket) *protection.Exit) {
+	position, _ := s.risk.Position(symbol)
+
+	s.mu.Lock()
+	var orders []models.Order
+	for name, bracket := range s.brackets {
+		if bracket.Symbol != symbol {
+			continue
+		}
+		before := *bracket
+		exit := evaluate(bracket)
+		if exit == nil {
+			// 最高价、移动止损或保本止损有变化时保存，重启后从当前状态继续
+			if *bracket != before {
+				s.saveBracket(bracket)
+			}
+			continue
+		}
+		quantity := math.Min(bracket.Quantity, math.Min(s.book(name).Quantity, position))
+		if quantity <= 0 {
+			s.deleteBracket(name)
+			continue
+		}
+		position -= quantity
+		orders = append(orders, models.Order{
+			Symbol:       symbol,
+			Side:         models.SideSell,
+			Quantity:     quantity,
+			Price:        exit.Price,
+			StrategyName: name,
+			Reason:       exit.Reason,
+			CreatedAt:    time.Now().UTC(),
+		})
+	}
+	s.mu.Unlock()
+
+	for _, order := range orders {
+		if _, err := s.submit(order, true); err != nil {
+			if _, rejected := err.(*risk.Rejection); !rejected {
+				utils.Log.WithError(err).WithField("strategy", order.StrategyName).Error("提交保护性平仓订单失败")
+			}
+		}
+	}
+}
+
+// ProtectiveOrder 一个持仓的保护性订单及其对应的 Alpaca 原生订单。
+// 标的不支持原生保护性订单时 Native 为空，NativeUnsupported 说明原因，订单由系统按价格和K线执行
+type ProtectiveOrder struct {
+	*protection.Bracket
+	Native            []AlpacaOrderRequest `json:"native"`
+	NativeUnsupported string               `json:"native_unsupported,omitempty"`
+}
+
+// ProtectiveOrders 返回当前所有持仓的保护性订单
+func (s *TradingService) ProtectiveOrders() []ProtectiveOrder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orders := make([]ProtectiveOrder, 0, len(s.brackets))
+	for name, bracket := range s.brackets {
+		b := *bracket
+		order := ProtectiveOrder{Bracket: &b}
+		native, err := AlpacaProtectiveOrders(&b, fmt.Sprintf("%s-%d", name, b.OpenedAt.Unix()))
+		if err != nil {
+			order.NativeUnsupported = err.Error()
+		}
+		order.Native = native
+		orders = append(orders, order)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].StrategyName < orders[j].StrategyName })
+	return orders
+}
+
+// orderFor 根据信号生成订单，信号已处理过或无需下单时返回 false
+func (s *TradingService) orderFor(signal *models.TradeSignal, bars []models.Bar) (models.Order, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.enabled {
+		return models.Order{}, false
+	}
+
+	key := signal.StrategyName + "/" + signal.Timeframe
+	last := s.seeds[signal.Timeframe]
+	if processed, ok := s.lastSignals[key]; ok && processed.After(last) {
+		last = processed
+	}
+	if !signal.Timestamp.After(last) {
+		return models.Order{}, false
+	}
+	s.lastSignals[key] = signal.Timestamp
+
+	order := models.Order{
+		Symbol:       signal.Symbol,
+		Price:        signal.Price,
+		StrategyName: signal.StrategyName,
+		Reason:       signal.Reason,
+		CreatedAt:    time.Now().UTC(),
+	}
+	book := s.book(signal.StrategyName)
+	if signal.Action == models.ActionBuy {
+		quantity, err := s.sizer.Size(sizing.Request{
+			Symbol:   signal.Symbol,
+			Price:    signal.Price,
+			Equity:   s.risk.Equity(),
+			Bars:     bars,
+			Stats:    sizing.NewStats(book.returns),
+			Quantity: signal.Size,
+		})
+		if err != nil {
+			utils.Log.WithField("strategy", signal.StrategyName).Infof("跳过买入信号: %v", err)
+			return models.Order{}, false
+		}
+		order.Side = models.SideBuy
+		order.Quantity = quantity
+	} else {
+		// 现货不做空，卖出信号只平掉该策略自己的多头持仓
+		position, _ := s.risk.Position(signal.Symbol)
+		quantity := math.Min(book.Quantity, position)
+		if quantity <= 0 {
+			return models.Order{}, false
+		}
+		order.Side = models.SideSell
+		order.Quantity = quantity
+		if signal.Size > 0 && signal.Size < quantity {
+			order.Quantity = signal.Size
+		}
+	}
+	return order, true
+}
+
+// book 返回策略的持仓记录，调用方需持有 s.mu
+func (s *TradingService) book(strategyName string) *strategyBook {
+	b, ok := s.books[strategyName]
+	if !ok {
+		b = &strategyBook{}
+		s.books[strategyName] = b
+	}
+	return b
+}
+
+// StrategyPosition 返回策略自己开仓并仍持有的数量
+func (s *TradingService) StrategyPosition(strategyName string) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b, ok := s.books[strategyName]; ok {
+		return b.Quantity
+	}
+	return 0
+}
+
+// recordBook 根据成交更新策略的持仓记录，熔断平仓（无策略）时清空所有策略的持仓
+func (s *TradingService) recordBook(fill models.Fill) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if fill.StrategyName == "" {
+		for _, b := range s.books {
+			b.Quantity, b.AvgCost = 0, 0
+		}
+		for name := range s.brackets {
+			s.deleteBracket(name)
+		}
+		return
+	}
+
+	b := s.book(fill.StrategyName)
+	closing := b.Apply(fill)
+	if closing.Cost > 0 {
+		b.returns = append(b.returns, closing.Net(fill.Fee)/closing.Cost*100)
+		if len(b.returns) > maxBookReturns {
+			b.returns = b.returns[len(b.returns)-maxBookReturns:]
+		}
+	}
+	bracket, ok := s.brackets[fill.StrategyName]
+	switch {
+	case !ok:
+	case b.Quantity <= 0:
+		s.deleteBracket(fill.StrategyName)
+	default:
+		bracket.Quantity = b.Quantity
+		s.saveBracket(bracket)
+	}
+}
+
+// saveBracket 保存保护性订单，调用方需持有 s.mu
+func (s *TradingService) saveBracket(bracket *protection.Bracket) {
+	if err := s.repo.SaveBracket(bracket); err != nil {
+		utils.Log.WithError(err).WithField("strategy", bracket.StrategyName).Error("保存保护性订单失败")
+	}
+}
+
+// deleteBracket 撤销并删除策略的保护性订单，调用方需持有 s.mu
+func (s *TradingService) deleteBracket(strategyName string) {
+	delete(s.brackets, strategyName)
+	if err := s.repo.DeleteBracket(strategyName); err != nil {
+		utils.Log.WithError(err).WithField("strategy", strategyName).Error("删除保护性订单失败")
+	}
+}
+
+// submit 提交订单并记录成交，checkRisk 为 false 时跳过风控检查（仅用于熔断平仓）
+func (s *TradingService) submit(order models.Order, checkRisk bool) (*models.Fill, error) {
+	s.mu.Lock()
+	s.orderSeq++
+	order.ID = fmt.Sprintf("paper-%d-%d", order.CreatedAt.UnixNano(), s.orderSeq)
+	s.mu.Unlock()
+
+	if checkRisk {
+		if err := s.risk.Check(order); err != nil {
+			if rejection, ok := err.(*risk.Rejection); ok {
+				s.mu.Lock()
+				listeners := append([]func(models.Order, *risk.Rejection){}, s.rejectListeners...)
+				s.mu.Unlock()
+				for _, fn := range listeners {
+					fn(order, rejection)
+				}
+			}
+			return nil, err
+		}
+	}
+	fill, err := s.broker.SubmitOrder(order)
+	if err != nil {
+		return nil, err
+	}
+	s.risk.RecordFill(*fill)
+	s.recordBook(*fill)
+	utils.Log.WithFields(map[string]interface{}{
+		"order":    order.ID,
+		"strategy": order.StrategyName,
+		"symbol":   fill.Symbol,
+		"side":     fill.Side,
+		"quantity": fill.Quantity,
+		"price":    fill.Price,
+		"fee":      fill.Fee,
+	}).Infof("订单已成交: %s", order.Reason)
+
+	s.mu.Lock()
+	listeners := append([]func(models.Fill){}, s.fillListeners...)
+	s.mu.Unlock()
+	for _, fn := range listeners {
+		fn(*fill)
+	}
+	return fill, nil
+}
+
+// UpdatePrice 更新标的最新价格并检查保护性订单
+func (s *TradingService) UpdatePrice(symbol string, price float64, at time.Time) {
+	s.risk.UpdatePrice(symbol, price, at)
+	s.checkProtection(symbol, func(b *protection.Bracket) *protection.Exit { return b.OnPrice(price, at) })
+}
+
+// Kill 触发熔断停止所有交易，flatten 为 true 时同时平掉全部持仓
+func (s *TradingService) Kill(reason string, flatten bool) {
+	s.risk.Kill(reason)
+	s.notifyKill(reason)
+	if flatten {
+		s.flatten(reason)
+	}
+}
+
+// Resume 解除手动触发的熔断
+func (s *TradingService) Resume() error {
+	return s.risk.Resume()
+}
+
+// RiskStatus 返回当前的风控状态
+func (s *TradingService) RiskStatus() risk.Status {
+	return s.risk.Status(time.Now().UTC())
+}
+
+// flatten 按最新价格平掉全部持仓，不经过风控检查
+func (s *TradingService) flatten(reason string) {
+	for symbol, quantity := range s.risk.Positions() {
+		_, price := s.risk.Position(symbol)
+		order := models.Order{
+			Symbol:    symbol,
+			Side:      models.SideSell,
+			Quantity:  quantity,
+			Price:     price,
+			Reason:    "熔断平仓: " + reason,
+			CreatedAt: time.Now().UTC(),
+		}
+		if quantity < 0 {
+			order.Side = models.SideBuy
+			order.Quantity = -quantity
+		}
+		if _, err := s.submit(order, false); err != nil {
+			utils.Log.WithError(err).WithField("symbol", symbol).Error("熔断平仓失败")
+		}
+	}
+}
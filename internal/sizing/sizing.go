@@ -0,0 +1,163 @@
+// Package sizing 将交易信号转换为下单数量
+package sizing
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/qqqq/eth-trading-system/internal/analysis/indicators"
+	"github.com/qqqq/eth-trading-system/internal/config"
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// 仓位计算方式
+const (
+	MethodFixedQuote    = "fixed_quote"
+	MethodFixedFraction = "fixed_fraction"
+	MethodKelly         = "kelly"
+	MethodVolatility    = "volatility"
+)
+
+// Stats 策略已平仓交易的统计，用于凯利公式
+type Stats struct {
+	Trades int
+	// WinRate 盈利交易占比，取值 [0, 1]
+	WinRate float64
+	// Payoff 平均盈利与平均亏损之比
+	Payoff float64
+}
+
+// NewStats 根据逐笔交易收益率计算统计值
+func NewStats(returns []float64) Stats {
+	stats := Stats{Trades: len(returns)}
+	if len(returns) == 0 {
+		return stats
+	}
+	var wins, gain, loss float64
+	for _, r := range returns {
+		if r > 0 {
+			wins++
+			gain += r
+		} else {
+			loss -= r
+		}
+	}
+	losses := float64(len(returns)) - wins
+	stats.WinRate = wins / float64(len(returns))
+	switch {
+	case losses == 0:
+		stats.Payoff = math.Inf(1)
+	case wins > 0 && loss > 0:
+		stats.Payoff = (gain / wins) / (loss / losses)
+	}
+	return stats
+}
+
+// Kelly 返回凯利公式给出的最优仓位比例 W - (1-W)/R，可能为负数
+func (s Stats) Kelly() float64 {
+	if s.Payoff <= 0 {
+		return -1
+	}
+	return s.WinRate - (1-s.WinRate)/s.Payoff
+}
+
+// Request 一次仓位计算的输入
+type Request struct {
+	Symbol string
+	Price  float64
+	Equity float64
+	// Bars 波动率目标方式用于计算 ATR 的K线
+	Bars  []models.Bar
+	Stats Stats
+	// Quantity 策略建议的数量，大于 0 时代替按方式计算的数量，但仍受上限、最小下单量和步长约束
+	Quantity float64
+}
+
+// Sizer 按配置计算下单数量
+type Sizer struct {
+	cfg config.SizingConfig
+}
+
+func NewSizer(cfg config.SizingConfig) *Sizer {
+	return &Sizer{cfg: cfg}
+}
+
+// Size 返回买入数量，数量为 0 时 error 说明原因
+func (s *Sizer) Size(req Request) (float64, error) {
+	if req.Price <= 0 {
+		return 0, fmt.Errorf("价格必须大于 0")
+	}
+	if req.Equity <= 0 {
+		return 0, fmt.Errorf("权益为 %.2f，无法开仓", req.Equity)
+	}
+
+	quantity := req.Quantity
+	if quantity <= 0 {
+		var err error
+		if quantity, err = s.raw(req); err != nil {
+			return 0, err
+		}
+	}
+
+	cfg := s.cfg
+	if cfg.MaxFraction > 0 {
+		quantity = math.Min(quantity, req.Equity*cfg.MaxFraction/req.Price)
+	}
+	if cfg.MaxQuote > 0 {
+		quantity = math.Min(quantity, cfg.MaxQuote/req.Price)
+	}
+
+	rules := s.rules(req.Symbol)
+	if rules.LotStep > 0 {
+		// 加上微小偏移，避免浮点误差把恰好整数倍的数量向下多舍一个步长
+		quantity = math.Floor(quantity/rules.LotStep+1e-9) * rules.LotStep
+	}
+	if quantity <= 0 {
+		return 0, fmt.Errorf("计算得到的数量为 0")
+	}
+	if quantity < rules.MinQuantity {
+		return 0, fmt.Errorf("数量 %.8f 低于最小下单量 %.8f", quantity, rules.MinQuantity)
+	}
+	if notional := quantity * req.Price; notional < rules.MinNotional {
+		return 0, fmt.Errorf("名义价值 %.2f 低于最小下单金额 %.2f", notional, rules.MinNotional)
+	}
+	return quantity, nil
+}
+
+// raw 按配置的方式计算未经约束的数量
+func (s *Sizer) raw(req Request) (float64, error) {
+	cfg := s.cfg
+	switch cfg.Method {
+	case MethodFixedQuote:
+		return cfg.QuoteAmount / req.Price, nil
+	case MethodFixedFraction:
+		return req.Equity * cfg.Fraction / req.Price, nil
+	case MethodKelly:
+		if req.Stats.Trades < cfg.KellyMinTrades {
+			return req.Equity * cfg.Fraction / req.Price, nil
+		}
+		fraction := req.Stats.Kelly() * cfg.KellyFraction
+		if fraction <= 0 {
+			return 0, fmt.Errorf("凯利比例为 %.3f（胜率 %.1f%%，盈亏比 %.2f），不开仓", fraction, req.Stats.WinRate*100, req.Stats.Payoff)
+		}
+		return req.Equity * fraction / req.Price, nil
+	case MethodVolatility:
+		series := indicators.NewAverageTrueRange(cfg.ATRPeriod).Series(req.Bars)
+		if len(series) == 0 || math.IsNaN(series[len(series)-1]) || series[len(series)-1] <= 0 {
+			return 0, fmt.Errorf("K线数量 %d 不足以计算 ATR(%d)", len(req.Bars), cfg.ATRPeriod)
+		}
+		return req.Equity * cfg.TargetVolatility / series[len(series)-1], nil
+	}
+	return 0, fmt.Errorf("不支持的仓位计算方式 %q", cfg.Method)
+}
+
+// rules 返回标的的下单限制，配置的键不区分大小写
+func (s *Sizer) rules(symbol string) config.SymbolRules {
+	for key, rules := range s.cfg.Symbols {
+		if strings.EqualFold(key, symbol) {
+			return rules
+		}
+	}
+	return config.SymbolRules{}
+}
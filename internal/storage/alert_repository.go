@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// AlertRepository 保存告警规则及其触发记录
+type AlertRepository interface {
+	CreateRule(rule *models.AlertRule) error
+	UpdateRule(rule *models.AlertRule) error
+	// DeleteRule 删除规则，规则不存在时返回 sql.ErrNoRows；触发记录保留
+	DeleteRule(id int64) error
+	// GetRule 规则不存在时返回 sql.ErrNoRows
+	GetRule(id int64) (*models.AlertRule, error)
+	ListRules() ([]models.AlertRule, error)
+	// MarkTriggered 记录触发时间，disable 为 true 时同时停用规则
+	MarkTriggered(id int64, at time.Time, disable bool) error
+	RecordTrigger(trigger *models.AlertTrigger) error
+	// Triggers 返回最近 limit 条触发记录，ruleID 为 0 时返回全部规则的记录
+	Triggers(ruleID int64, limit int) ([]models.AlertTrigger, error)
+}
+
+type SQLiteAlertRepository struct {
+	db *sql.DB
+}
+
+func NewAlertRepository(db *sql.DB) AlertRepository {
+	return &SQLiteAlertRepository{db: db}
+}
+
+const alertRuleColumns = `id, name, type, symbol, timeframe, condition, value, indicator, period,
+	compare_indicator, compare_period, window_duration, mode, cooldown, enabled, last_triggered_at, created_at, updated_at`
+
+func (r *SQLiteAlertRepository) CreateRule(rule *models.AlertRule) error {
+	result, err := r.db.Exec(`
+		INSERT INTO alert_rules (name, type, symbol, timeframe, condition, value, indicator, period,
+			compare_indicator, compare_period, window_duration, mode, cooldown, enabled, last_triggered_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rule.Name, rule.Type, rule.Symbol, rule.Timeframe, rule.Condition, rule.Value, rule.Indicator, rule.Period,
+		rule.CompareIndicator, rule.ComparePeriod, rule.Window, rule.Mode, rule.Cooldown, rule.Enabled,
+		rule.LastTriggeredAt, rule.CreatedAt, rule.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	rule.ID, err = result.LastInsertId()
+	return err
+}
+
+func (r *SQLiteAlertRepository) UpdateRule(rule *models.AlertRule) error {
+	result, err := r.db.Exec(`
+		UPDATE alert_rules SET name = ?, type = ?, symbol = ?, timeframe = ?, condition = ?, value = ?,
+			indicator = ?, period = ?, compare_indicator = ?, compare_period = ?, window_duration = ?, mode = ?,
+			cooldown = ?, enabled = ?, last_triggered_at = ?, updated_at = ?
+		WHERE id = ?
+	`, rule.Name, rule.Type, rule.Symbol, rule.Timeframe, rule.Condition, rule.Value, rule.Indicator, rule.Period,
+		rule.CompareIndicator, rule.ComparePeriod, rule.Window, rule.Mode, rule.Cooldown, rule.Enabled,
+		rule.LastTriggeredAt, rule.UpdatedAt, rule.ID)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+func (r *SQLiteAlertRepository) DeleteRule(id int64) error {
+	result, err := r.db.Exec(`DELETE FROM alert_rules WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(result)
+}
+
+func requireAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *SQLiteAlertRepository) GetRule(id int64) (*models.AlertRule, error) {
+	row := r.db.QueryRow(`SELECT `+alertRuleColumns+` FROM alert_rules WHERE id = ?`, id)
+	return scanAlertRule(row)
+}
+
+func (r *SQLiteAlertRepository) ListRules() ([]models.AlertRule, error) {
+	rows, err := r.db.Query(`SELECT ` + alertRuleColumns + ` FROM alert_rules ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.AlertRule
+	for rows.Next() {
+		rule, err := scanAlertRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *rule)
+	}
+	return rules, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAlertRule(row rowScanner) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	var lastTriggered sql.NullTime
+	err := row.Scan(&rule.ID, &rule.Name, &rule.Type, &rule.Symbol, &rule.Timeframe, &rule.Condition, &rule.Value,
+		&rule.Indicator, &rule.Period, &rule.CompareIndicator, &rule.ComparePeriod, &rule.Window, &rule.Mode,
+		&rule.Cooldown, &rule.Enabled, &lastTriggered, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if lastTriggered.Valid {
+		t := lastTriggered.Time
+		rule.LastTriggeredAt = &t
+	}
+	return &rule, nil
+}
+
+func (r *SQLiteAlertRepository) MarkTriggered(id int64, at time.Time, disable bool) error {
+	_, err := r.db.Exec(`
+		UPDATE alert_rules SET last_triggered_at = ?, enabled = CASE WHEN ? THEN 0 ELSE enabled END
+		WHERE id = ?
+	`, at, disable, id)
+	return err
+}
+
+func (r *SQLiteAlertRepository) RecordTrigger(trigger *models.AlertTrigger) error {
+	result, err := r.db.Exec(`
+		INSERT INTO alert_triggers (rule_id, rule_name, symbol, price, value, message, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, trigger.RuleID, trigger.RuleName, trigger.Symbol, trigger.Price, trigger.Value, trigger.Message, trigger.Timestamp)
+	if err != nil {
+		return err
+	}
+	trigger.ID, err = result.LastInsertId()
+	return err
+}
+
+func (r *SQLiteAlertRepository) Triggers(ruleID int64, limit int) ([]models.AlertTrigger, error) {
+	rows, err := r.db.Query(`
+		SELECT id, rule_id, rule_name, symbol, price, value, message, timestamp
+		FROM alert_triggers
+		WHERE ? = 0 OR rule_id = ?
+		ORDER BY timestamp DESC, id DESC
+		LIMIT ?
+	`, ruleID, ruleID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var triggers []models.AlertTrigger
+	for rows.Next() {
+		var t models.AlertTrigger
+		if err := rows.Scan(&t.ID, &t.RuleID, &t.RuleName, &t.Symbol, &t.Price, &t.Value, &t.Message, &t.Timestamp); err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, t)
+	}
+	return triggers, rows.Err()
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// PortfolioRepository 保存成交记录和账户权益快照
+type PortfolioRepository interface {
+	RecordFill(fill *models.Fill) error
+	// Fills 返回全部成交记录，按成交时间升序排列
+	Fills() ([]models.Fill, error)
+	RecordSnapshot(snapshot *models.EquitySnapshot) error
+	EquitySnapshots(start, end time.Time) ([]models.EquitySnapshot, error)
+}
+
+type SQLitePortfolioRepository struct {
+	db *sql.DB
+}
+
+func NewPortfolioRepository(db *sql.DB) PortfolioRepository {
+	return &SQLitePortfolioRepository{db: db}
+}
+
+func (r *SQLitePortfolioRepository) RecordFill(fill *models.Fill) error {
+	_, err := r.db.Exec(`
+		INSERT INTO fills (order_id, strategy, symbol, side, quantity, price, fee, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, fill.OrderID, fill.StrategyName, fill.Symbol, string(fill.Side), fill.Quantity, fill.Price, fill.Fee, fill.Timestamp)
+	return err
+}
+
+func (r *SQLitePortfolioRepository) Fills() ([]models.Fill, error) {
+	rows, err := r.db.Query(`
+		SELECT order_id, strategy, symbol, side, quantity, price, fee, timestamp
+		FROM fills
+		ORDER BY timestamp ASC, id ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fills []models.Fill
+	for rows.Next() {
+		var fill models.Fill
+		var side string
+		if err := rows.Scan(&fill.OrderID, &fill.StrategyName, &fill.Symbol, &side, &fill.Quantity, &fill.Price, &fill.Fee, &fill.Timestamp); err != nil {
+			return nil, err
+		}
+		fill.Side = models.OrderSide(side)
+		fills = append(fills, fill)
+	}
+	return fills, rows.Err()
+}
+
+// RecordSnapshot 保存权益快照，同一时刻的快照会被覆盖
+func (r *SQLitePortfolioRepository) RecordSnapshot(snapshot *models.EquitySnapshot) error {
+	_, err := r.db.Exec(`
+		INSERT INTO equity_snapshots (timestamp, cash, positions_value, equity, realized_pnl, unrealized_pnl, fees)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(timestamp) DO UPDATE SET
+		cash=excluded.cash, positions_value=excluded.positions_value, equity=excluded.equity,
+		realized_pnl=excluded.realized_pnl, unrealized_pnl=excluded.unrealized_pnl, fees=excluded.fees
+	`, snapshot.Timestamp, snapshot.Cash, snapshot.PositionsValue, snapshot.Equity, snapshot.RealizedPnL, snapshot.UnrealizedPnL, snapshot.Fees)
+	return err
+}
+
+func (r *SQLitePortfolioRepository) EquitySnapshots(start, end time.Time) ([]models.EquitySnapshot, error) {
+	rows, err := r.db.Query(`
+		SELECT timestamp, cash, positions_value, equity, realized_pnl, unrealized_pnl, fees
+		FROM equity_snapshots
+		WHERE timestamp BETWEEN ? AND ?
+		ORDER BY timestamp ASC
+	`, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []models.EquitySnapshot
+	for rows.Next() {
+		var s models.EquitySnapshot
+		if err := rows.Scan(&s.Timestamp, &s.Cash, &s.PositionsValue, &s.Equity, &s.RealizedPnL, &s.UnrealizedPnL, &s.Fees); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"database/sql"
+
+	"github.com/qqqq/eth-trading-system/internal/protection"
+)
+
+// ProtectionRepository 保存各策略持仓的保护性订单，每个策略最多一条，重启后用于恢复
+type ProtectionRepository interface {
+	// SaveBracket 保存策略的保护性订单，已存在时覆盖
+	SaveBracket(bracket *protection.Bracket) error
+	DeleteBracket(strategyName string) error
+	Brackets() ([]protection.Bracket, error)
+}
+
+type SQLiteProtectionRepository struct {
+	db *sql.DB
+}
+
+func NewProtectionRepository(db *sql.DB) ProtectionRepository {
+	return &SQLiteProtectionRepository{db: db}
+}
+
+func (r *SQLiteProtectionRepository) SaveBracket(b *protection.Bracket) error {
+	_, err := r.db.Exec(`
+		INSERT INTO protective_orders (strategy, symbol, quantity, entry, opened_at, stop_loss, initial_risk,
+		take_profit, trail_distance, trail_percent, high_water, breakeven_r, breakeven)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(strategy) DO UPDATE SET
+		symbol=excluded.symbol, quantity=excluded.quantity, entry=excluded.entry, opened_at=excluded.opened_at,
+		stop_loss=excluded.stop_loss, initial_risk=excluded.initial_risk, take_profit=excluded.take_profit,
+		trail_distance=excluded.trail_distance, trail_percent=excluded.trail_percent, high_water=excluded.high_water,
+		breakeven_r=excluded.breakeven_r, breakeven=excluded.breakeven
+	`, b.StrategyName, b.Symbol, b.Quantity, b.Entry, b.OpenedAt, b.StopLoss, b.InitialRisk,
+		b.TakeProfit, b.TrailDistance, b.TrailPercent, b.HighWater, b.BreakevenR, b.Breakeven)
+	return err
+}
+
+func (r *SQLiteProtectionRepository) DeleteBracket(strategyName string) error {
+	_, err := r.db.Exec(`DELETE FROM protective_orders WHERE strategy = ?`, strategyName)
+	return err
+}
+
+func (r *SQLiteProtectionRepository) Brackets() ([]protection.Bracket, error) {
+	rows, err := r.db.Query(`
+		SELECT strategy, symbol, quantity, entry, opened_at, stop_loss, initial_risk,
+		take_profit, trail_distance, trail_percent, high_water, breakeven_r, breakeven
+		FROM protective_orders
+		ORDER BY strategy ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var brackets []protection.Bracket
+	for rows.Next() {
+		var b protection.Bracket
+		if err := rows.Scan(&b.StrategyName, &b.Symbol, &b.Quantity, &b.Entry, &b.OpenedAt, &b.StopLoss, &b.InitialRisk,
+			&b.TakeProfit, &b.TrailDistance, &b.TrailPercent, &b.HighWater, &b.BreakevenR, &b.Breakeven); err != nil {
+			return nil, err
+		}
+		brackets = append(brackets, b)
+	}
+	return brackets, rows.Err()
+}
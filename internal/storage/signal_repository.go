@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// SignalRepository 保存策略信号及其事后验证结果
+type SignalRepository interface {
+	RecordSignal(record *models.SignalRecord) error
+	PendingSignals(strategyName, timeframe string, since time.Time) ([]models.SignalRecord, error)
+	ResolveSignal(id int64, correct bool) error
+	Accuracy(strategyName, timeframe string, limit int) (correct, total int, err error)
+}
+
+type SQLiteSignalRepository struct {
+	db *sql.DB
+}
+
+func NewSignalRepository(db *sql.DB) SignalRepository {
+	return &SQLiteSignalRepository{db: db}
+}
+
+// RecordSignal 记录信号，同一策略在同一时间框架的同一根K线上只保留第一条
+func (r *SQLiteSignalRepository) RecordSignal(record *models.SignalRecord) error {
+	_, err := r.db.Exec(`
+		INSERT INTO strategy_signals (strategy, symbol, timeframe, action, price, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(strategy, timeframe, timestamp) DO NOTHING
+	`, record.StrategyName, record.Symbol, record.Timeframe, string(record.Action), record.Price, record.Timestamp)
+	return err
+}
+
+// PendingSignals 返回 since 之后尚未验证的信号，按时间升序排列
+func (r *SQLiteSignalRepository) PendingSignals(strategyName, timeframe string, since time.Time) ([]models.SignalRecord, error) {
+	rows, err := r.db.Query(`
+		SELECT id, strategy, symbol, timeframe, action, price, timestamp
+		FROM strategy_signals
+		WHERE strategy = ? AND timeframe = ? AND resolved = 0 AND timestamp >= ?
+		ORDER BY timestamp ASC
+	`, strategyName, timeframe, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []models.SignalRecord
+	for rows.Next() {
+		var record models.SignalRecord
+		var action string
+		if err := rows.Scan(&record.ID, &record.StrategyName, &record.Symbol, &record.Timeframe, &action, &record.Price, &record.Timestamp); err != nil {
+			return nil, err
+		}
+		record.Action = models.SignalAction(action)
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (r *SQLiteSignalRepository) ResolveSignal(id int64, correct bool) error {
+	_, err := r.db.Exec(`UPDATE strategy_signals SET resolved = 1, correct = ? WHERE id = ?`, correct, id)
+	return err
+}
+
+// Accuracy 返回最近 limit 条已验证信号中方向正确的数量和总数
+func (r *SQLiteSignalRepository) Accuracy(strategyName, timeframe string, limit int) (int, int, error) {
+	var correct, total sql.NullInt64
+	err := r.db.QueryRow(`
+		SELECT SUM(correct), COUNT(*) FROM (
+			SELECT correct FROM strategy_signals
+			WHERE strategy = ? AND timeframe = ? AND resolved = 1
+			ORDER BY timestamp DESC
+			LIMIT ?
+		)
+	`, strategyName, timeframe, limit).Scan(&correct, &total)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(correct.Int64), int(total.Int64), nil
+}
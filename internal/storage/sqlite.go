@@ -63,5 +63,131 @@ func createTables(db *sql.DB) error {
 		return err
 	}
 
+	_, err = db.Exec(`
+        CREATE TABLE IF NOT EXISTS strategy_signals (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            strategy TEXT NOT NULL,
+            symbol TEXT NOT NULL,
+            timeframe TEXT NOT NULL,
+            action TEXT NOT NULL,
+            price REAL NOT NULL,
+            timestamp DATETIME NOT NULL,
+            resolved INTEGER NOT NULL DEFAULT 0,
+            correct INTEGER NOT NULL DEFAULT 0,
+            UNIQUE(strategy, timeframe, timestamp)
+        )
+    `)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+        CREATE TABLE IF NOT EXISTS strategy_state (
+            key TEXT PRIMARY KEY,
+            state TEXT NOT NULL,
+            updated_at DATETIME NOT NULL
+        )
+    `)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+        CREATE TABLE IF NOT EXISTS fills (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            order_id TEXT NOT NULL,
+            strategy TEXT NOT NULL,
+            symbol TEXT NOT NULL,
+            side TEXT NOT NULL,
+            quantity REAL NOT NULL,
+            price REAL NOT NULL,
+            fee REAL NOT NULL,
+            timestamp DATETIME NOT NULL
+        )
+    `)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+        CREATE TABLE IF NOT EXISTS protective_orders (
+            strategy TEXT PRIMARY KEY,
+            symbol TEXT NOT NULL,
+            quantity REAL NOT NULL,
+            entry REAL NOT NULL,
+            opened_at DATETIME NOT NULL,
+            stop_loss REAL NOT NULL,
+            initial_risk REAL NOT NULL,
+            take_profit REAL NOT NULL,
+            trail_distance REAL NOT NULL,
+            trail_percent REAL NOT NULL,
+            high_water REAL NOT NULL,
+            breakeven_r REAL NOT NULL,
+            breakeven INTEGER NOT NULL
+        )
+    `)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+        CREATE TABLE IF NOT EXISTS equity_snapshots (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            timestamp DATETIME NOT NULL,
+            cash REAL NOT NULL,
+            positions_value REAL NOT NULL,
+            equity REAL NOT NULL,
+            realized_pnl REAL NOT NULL,
+            unrealized_pnl REAL NOT NULL,
+            fees REAL NOT NULL,
+            UNIQUE(timestamp)
+        )
+    `)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+        CREATE TABLE IF NOT EXISTS alert_rules (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            name TEXT NOT NULL,
+            type TEXT NOT NULL,
+            symbol TEXT NOT NULL,
+            timeframe TEXT NOT NULL,
+            condition TEXT NOT NULL,
+            value REAL NOT NULL,
+            indicator TEXT NOT NULL,
+            period INTEGER NOT NULL,
+            compare_indicator TEXT NOT NULL,
+            compare_period INTEGER NOT NULL,
+            window_duration TEXT NOT NULL,
+            mode TEXT NOT NULL,
+            cooldown TEXT NOT NULL,
+            enabled INTEGER NOT NULL,
+            last_triggered_at DATETIME,
+            created_at DATETIME NOT NULL,
+            updated_at DATETIME NOT NULL
+        )
+    `)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`
+        CREATE TABLE IF NOT EXISTS alert_triggers (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            rule_id INTEGER NOT NULL,
+            rule_name TEXT NOT NULL,
+            symbol TEXT NOT NULL,
+            price REAL NOT NULL,
+            value REAL NOT NULL,
+            message TEXT NOT NULL,
+            timestamp DATETIME NOT NULL
+        )
+    `)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
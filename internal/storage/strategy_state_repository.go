@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// StrategyStateRepository 以 JSON 保存策略的运行状态，如网格各格的持仓
+type StrategyStateRepository interface {
+	LoadState(key string, state interface{}) (bool, error)
+	SaveState(key string, state interface{}) error
+}
+
+type SQLiteStrategyStateRepository struct {
+	db *sql.DB
+}
+
+func NewStrategyStateRepository(db *sql.DB) StrategyStateRepository {
+	return &SQLiteStrategyStateRepository{db: db}
+}
+
+func (r *SQLiteStrategyStateRepository) LoadState(key string, state interface{}) (bool, error) {
+	var data string
+	err := r.db.QueryRow(`SELECT state FROM strategy_state WHERE key = ?`, key).Scan(&data)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, json.Unmarshal([]byte(data), state)
+}
+
+func (r *SQLiteStrategyStateRepository) SaveState(key string, state interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO strategy_state (key, state, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET state=excluded.state, updated_at=excluded.updated_at
+	`, key, string(data), time.Now().UTC())
+	return err
+}
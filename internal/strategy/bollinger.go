@@ -0,0 +1,265 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/qqqq/eth-trading-system/internal/analysis/indicators"
+	"github.com/qqqq/eth-trading-system/internal/config"
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+const (
+	BollingerModeBreakout      = "breakout"
+	BollingerModeMeanReversion = "mean_reversion"
+)
+
+// BollingerStrategy 实现了布林带策略，支持两种模式：
+//
+//   - breakout: 带宽处于回溯区间的低分位（挤压）后，收盘价突破上轨/下轨且成交量放大时顺势交易
+//   - mean_reversion: 收盘价触及下轨时买入、触及上轨时卖出，押注价格回归中轨
+type BollingerStrategy struct {
+	BaseStrategy
+	Mode    string
+	Period  int
+	StdDevs float64
+
+	// SqueezeLookback 计算带宽分位数的回溯K线数量
+	SqueezeLookback int
+	// SqueezePercentile 带宽分位数不高于该值（0-100）时视为挤压
+	SqueezePercentile float64
+	// SqueezeWindow 突破需发生在最近一次挤压后的该数量K线内
+	SqueezeWindow int
+
+	// VolumeMultiplier 突破K线的成交量需达到均量的该倍数，0 表示不做成交量确认
+	VolumeMultiplier float64
+	VolumePeriod     int
+}
+
+func NewBollingerStrategy(mode string, period int, stdDevs float64) *BollingerStrategy {
+	return &BollingerStrategy{
+		BaseStrategy:      BaseStrategy{name: "Bollinger"},
+		Mode:              mode,
+		Period:            period,
+		StdDevs:           stdDevs,
+		SqueezeLookback:   120,
+		SqueezePercentile: 20,
+		SqueezeWindow:     5,
+		VolumeMultiplier:  1.5,
+		VolumePeriod:      20,
+	}
+}
+
+type bollingerParams struct {
+	Mode              string  `mapstructure:"mode"`
+	Period            int     `mapstructure:"period"`
+	StdDevs           float64 `mapstructure:"stdDevs"`
+	SqueezeLookback   int     `mapstructure:"squeezeLookback"`
+	SqueezePercentile float64 `mapstructure:"squeezePercentile"`
+	SqueezeWindow     int     `mapstructure:"squeezeWindow"`
+	VolumeMultiplier  float64 `mapstructure:"volumeMultiplier"`
+	VolumePeriod      int     `mapstructure:"volumePeriod"`
+}
+
+func newBollingerFromConfig(cfg config.StrategyConfig, _ *Registry) (Strategy, error) {
+	defaults := NewBollingerStrategy(BollingerModeBreakout, 20, 2)
+	params := bollingerParams{
+		Mode:              defaults.Mode,
+		Period:            defaults.Period,
+		StdDevs:           defaults.StdDevs,
+		SqueezeLookback:   defaults.SqueezeLookback,
+		SqueezePercentile: defaults.SqueezePercentile,
+		SqueezeWindow:     defaults.SqueezeWindow,
+		VolumeMultiplier:  defaults.VolumeMultiplier,
+		VolumePeriod:      defaults.VolumePeriod,
+	}
+	if err := decodeParams(cfg.Params, &params); err != nil {
+		return nil, err
+	}
+
+	if params.Mode != BollingerModeBreakout && params.Mode != BollingerModeMeanReversion {
+		return nil, fmt.Errorf("mode 必须为 %s 或 %s，当前为 %q", BollingerModeBreakout, BollingerModeMeanReversion, params.Mode)
+	}
+	if params.Period < 2 || params.StdDevs <= 0 {
+		return nil, fmt.Errorf("period 必须不小于 2 且 stdDevs 必须大于 0")
+	}
+	if params.SqueezeLookback < 2 || params.SqueezeWindow < 1 {
+		return nil, fmt.Errorf("squeezeLookback 必须不小于 2 且 squeezeWindow 必须不小于 1")
+	}
+	if params.SqueezePercentile <= 0 || params.SqueezePercentile > 100 {
+		return nil, fmt.Errorf("squeezePercentile 必须在 (0, 100] 范围内")
+	}
+	if params.VolumeMultiplier < 0 || (params.VolumeMultiplier > 0 && params.VolumePeriod < 1) {
+		return nil, fmt.Errorf("volumeMultiplier 不能为负数，启用成交量确认时 volumePeriod 必须大于 0")
+	}
+
+	s := NewBollingerStrategy(params.Mode, params.Period, params.StdDevs)
+	s.SqueezeLookback = params.SqueezeLookback
+	s.SqueezePercentile = params.SqueezePercentile
+	s.SqueezeWindow = params.SqueezeWindow
+	s.VolumeMultiplier = params.VolumeMultiplier
+	s.VolumePeriod = params.VolumePeriod
+	return s, nil
+}
+
+func (s *BollingerStrategy) Evaluate(data []models.Bar, analysisResult *models.AnalysisResult) *models.TradeSignal {
+	if len(data) < s.Period+1 {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "不足够的数据"}
+	}
+
+	bb := indicators.NewBollingerBands(s.Period, s.StdDevs)
+	upper, middle, lower := bb.Series(data)
+
+	if s.Mode == BollingerModeMeanReversion {
+		return s.evaluateMeanReversion(data, upper, middle, lower)
+	}
+	return s.evaluateBreakout(data, upper, middle, lower)
+}
+
+// bandValues 返回第 i 根K线的布林带取值，用于填充信号的 Indicators
+func bandValues(upper, middle, lower []float64, i int) map[string]float64 {
+	return map[string]float64{"BBUpper": upper[i], "BBMiddle": middle[i], "BBLower": lower[i]}
+}
+
+// evaluateMeanReversion 以中轨作为止盈价，信号强度随收盘价超出轨道的幅度（相对带宽）增加
+func (s *BollingerStrategy) evaluateMeanReversion(data []models.Bar, upper, middle, lower []float64) *models.TradeSignal {
+	last := len(data) - 1
+	cur, prev := data[last].Close, data[last-1].Close
+	values := bandValues(upper, middle, lower, last)
+	width := upper[last] - lower[last]
+
+	// 仅在首次触及时发出信号，避免价格沿轨道运行时重复发出
+	if cur <= lower[last] && prev > lower[last-1] {
+		return &models.TradeSignal{
+			StrategyName: s.Name(),
+			Action:       models.ActionBuy,
+			Price:        cur,
+			Reason:       fmt.Sprintf("收盘价 %.2f 触及布林带下轨 %.2f，预期回归中轨 %.2f", cur, lower[last], middle[last]),
+			Strength:     clampStrength(0.5 + 2*(lower[last]-cur)/width),
+			TakeProfit:   middle[last],
+			Indicators:   values,
+		}
+	}
+	if cur >= upper[last] && prev < upper[last-1] {
+		return &models.TradeSignal{
+			StrategyName: s.Name(),
+			Action:       models.ActionSell,
+			Price:        cur,
+			Reason:       fmt.Sprintf("收盘价 %.2f 触及布林带上轨 %.2f，预期回归中轨 %.2f", cur, upper[last], middle[last]),
+			Strength:     clampStrength(0.5 + 2*(cur-upper[last])/width),
+			TakeProfit:   middle[last],
+			Indicators:   values,
+		}
+	}
+
+	return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "价格位于布林带内", Indicators: values}
+}
+
+// evaluateBreakout 以中轨作为止损价，启用成交量确认时信号强度随成交量倍数增加
+func (s *BollingerStrategy) evaluateBreakout(data []models.Bar, upper, middle, lower []float64) *models.TradeSignal {
+	last := len(data) - 1
+	if len(data) < s.Period+s.SqueezeLookback {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "不足够的数据计算带宽分位数"}
+	}
+
+	bandwidth := make([]float64, len(data))
+	for i := range data {
+		bandwidth[i] = (upper[i] - lower[i]) / middle[i]
+	}
+	values := bandValues(upper, middle, lower, last)
+	values["BBBandwidth"] = bandwidth[last]
+
+	// 在突破K线之前的 SqueezeWindow 根K线内寻找挤压
+	squeezeIndex, squeezePct := -1, 0.0
+	for i := last - 1; i >= last-s.SqueezeWindow && i >= s.Period-1+s.SqueezeLookback-1; i-- {
+		pct := percentileRank(bandwidth[i-s.SqueezeLookback+1:i+1], bandwidth[i])
+		if pct <= s.SqueezePercentile {
+			squeezeIndex, squeezePct = i, pct
+			break
+		}
+	}
+	if squeezeIndex < 0 {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "近期未出现布林带挤压", Indicators: values}
+	}
+	values["BBSqueezePercentile"] = squeezePct
+
+	cur, prev := data[last].Close, data[last-1].Close
+	var action models.SignalAction
+	var direction string
+	// 只在首根收于轨道外的K线上发出信号
+	switch {
+	case cur > upper[last] && prev <= upper[last-1]:
+		action, direction = models.ActionBuy, fmt.Sprintf("向上突破上轨 %.2f", upper[last])
+	case cur < lower[last] && prev >= lower[last-1]:
+		action, direction = models.ActionSell, fmt.Sprintf("向下跌破下轨 %.2f", lower[last])
+	default:
+		return &models.TradeSignal{
+			StrategyName: s.Name(),
+			Action:       models.ActionHold,
+			Reason:       fmt.Sprintf("布林带挤压中（带宽分位 %.0f%%），等待突破", squeezePct),
+			Indicators:   values,
+		}
+	}
+
+	volumeNote, strength := "", 0.5
+	if s.VolumeMultiplier > 0 {
+		avgVolume := averageVolume(data, last, s.VolumePeriod)
+		if avgVolume <= 0 || data[last].Volume < avgVolume*s.VolumeMultiplier {
+			return &models.TradeSignal{
+				StrategyName: s.Name(),
+				Action:       models.ActionHold,
+				Reason:       fmt.Sprintf("收盘价%s，但成交量 %.2f 未达到均量的 %.1f 倍", direction, data[last].Volume, s.VolumeMultiplier),
+				Indicators:   values,
+			}
+		}
+		ratio := data[last].Volume / avgVolume
+		values["VolumeRatio"] = ratio
+		volumeNote = fmt.Sprintf("，成交量为均量的 %.1f 倍", ratio)
+		strength = clampStrength(ratio / (2 * s.VolumeMultiplier))
+	}
+
+	return &models.TradeSignal{
+		StrategyName: s.Name(),
+		Action:       action,
+		Price:        cur,
+		Reason: fmt.Sprintf("布林带挤压（%d 根K线前带宽分位 %.0f%%）后收盘价 %.2f %s%s",
+			last-squeezeIndex, squeezePct, cur, direction, volumeNote),
+		Strength:   strength,
+		StopLoss:   middle[last],
+		Indicators: values,
+	}
+}
+
+// percentileRank 返回 value 在 window 中的百分位（0-100），NaN 值被忽略
+func percentileRank(window []float64, value float64) float64 {
+	count, below := 0, 0
+	for _, v := range window {
+		if math.IsNaN(v) {
+			continue
+		}
+		count++
+		if v <= value {
+			below++
+		}
+	}
+	if count == 0 {
+		return 100
+	}
+	return float64(below) / float64(count) * 100
+}
+
+// averageVolume 返回第 index 根K线之前 period 根K线的平均成交量
+func averageVolume(data []models.Bar, index, period int) float64 {
+	start := index - period
+	if start < 0 {
+		start = 0
+	}
+	if start >= index {
+		return 0
+	}
+	sum := 0.0
+	for i := start; i < index; i++ {
+		sum += data[i].Volume
+	}
+	return sum / float64(index-start)
+}
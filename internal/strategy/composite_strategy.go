@@ -1,66 +1,340 @@
 package strategy
 
 import (
+	"fmt"
+	"sort"
+
+	"github.com/qqqq/eth-trading-system/internal/config"
 	"github.com/qqqq/eth-trading-system/internal/models"
+	"github.com/qqqq/eth-trading-system/internal/utils"
+)
+
+const (
+	// CompositeModeWeighted 按权重投票，买入（卖出）权重占比超过阈值时发出信号
+	CompositeModeWeighted = "weighted"
+	// CompositeModeMajority 按票数投票，买入（卖出）票数占比超过阈值时发出信号，忽略权重
+	CompositeModeMajority = "majority"
+	// CompositeModeUnanimous 所有子策略给出相同方向且平均强度不低于阈值时发出信号
+	CompositeModeUnanimous = "unanimous"
+	// CompositeModeStrength 按权重对带方向的信号强度求平均，绝对值超过阈值时发出信号
+	CompositeModeStrength = "strength"
 )
 
+// compositeDefaultThresholds 各聚合模式的默认阈值
+var compositeDefaultThresholds = map[string]float64{
+	CompositeModeWeighted:  0.6,
+	CompositeModeMajority:  0.5,
+	CompositeModeUnanimous: 0,
+	CompositeModeStrength:  0.3,
+}
+
 // CompositeStrategy 组合多个策略的结果
+//
+// 参与投票的子策略按 Mode 聚合为一个信号；Vetoes 中的子策略不参与投票，
+// 只在其信号与聚合结果方向相反时否决该结果。
+// 启用 Adaptive 时，子策略的权重乘以其最近 AdaptiveLookback 条信号的实际准确率，
+// 信号是否准确以 AdaptiveHorizon 根K线后的收盘价是否朝信号方向移动来判断，结果保存在 SignalHistory 中。
 type CompositeStrategy struct {
 	BaseStrategy
 	strategies []Strategy
-	weights    map[string]float64
+	weights    []float64
+	Vetoes     []Strategy
+	Mode       string
+	Threshold  float64
+
+	Adaptive           bool
+	AdaptiveLookback   int
+	AdaptiveHorizon    int
+	AdaptiveMinSamples int
+	history            SignalHistory
 }
 
-func NewCompositeStrategy(strategies []Strategy, weights map[string]float64) *CompositeStrategy {
+// NewCompositeStrategy 创建按权重投票的组合策略，weights 与 strategies 一一对应，
+// weights 较短（或为 nil）时缺少的权重默认为 1，多余的权重被忽略
+func NewCompositeStrategy(strategies []Strategy, weights []float64) *CompositeStrategy {
+	normalized := make([]float64, len(strategies))
+	for i := range normalized {
+		normalized[i] = 1
+		if i < len(weights) {
+			normalized[i] = weights[i]
+		}
+	}
 	return &CompositeStrategy{
-		BaseStrategy: BaseStrategy{name: "Composite"},
-		strategies:   strategies,
-		weights:      weights,
+		BaseStrategy:       BaseStrategy{name: "Composite"},
+		strategies:         strategies,
+		weights:            normalized,
+		Mode:               CompositeModeWeighted,
+		Threshold:          compositeDefaultThresholds[CompositeModeWeighted],
+		AdaptiveLookback:   20,
+		AdaptiveHorizon:    5,
+		AdaptiveMinSamples: 5,
 	}
 }
 
-func (cs *CompositeStrategy) Evaluate(data []models.Bar, analysisResult *models.AnalysisResult) *models.TradeSignal {
-	var buyScore, sellScore float64
-	totalWeight := 0.0
+type compositeParams struct {
+	Mode string `mapstructure:"mode"`
+	// Threshold 未设置时使用所选模式的默认阈值
+	Threshold          *float64 `mapstructure:"threshold"`
+	Adaptive           bool     `mapstructure:"adaptive"`
+	AdaptiveLookback   int      `mapstructure:"adaptiveLookback"`
+	AdaptiveHorizon    int      `mapstructure:"adaptiveHorizon"`
+	AdaptiveMinSamples int      `mapstructure:"adaptiveMinSamples"`
+}
+
+// newCompositeFromConfig 按 children 构建子策略，子策略的 weight 未设置时默认为 1
+func newCompositeFromConfig(cfg config.StrategyConfig, registry *Registry) (Strategy, error) {
+	defaults := NewCompositeStrategy(nil, nil)
+	params := compositeParams{
+		Mode:               CompositeModeWeighted,
+		AdaptiveLookback:   defaults.AdaptiveLookback,
+		AdaptiveHorizon:    defaults.AdaptiveHorizon,
+		AdaptiveMinSamples: defaults.AdaptiveMinSamples,
+	}
+	if err := decodeParams(cfg.Params, &params); err != nil {
+		return nil, err
+	}
+
+	threshold, ok := compositeDefaultThresholds[params.Mode]
+	if !ok {
+		return nil, fmt.Errorf("mode 必须为 %s、%s、%s 或 %s，当前为 %q",
+			CompositeModeWeighted, CompositeModeMajority, CompositeModeUnanimous, CompositeModeStrength, params.Mode)
+	}
+	if params.Threshold != nil {
+		threshold = *params.Threshold
+	}
+	if threshold < 0 || threshold > 1 || (threshold == 1 && params.Mode != CompositeModeUnanimous) {
+		return nil, fmt.Errorf("%s 模式的 threshold 必须在 [0, 1) 范围内（unanimous 为 [0, 1]），当前为 %v", params.Mode, threshold)
+	}
+	if params.Adaptive {
+		if params.AdaptiveLookback < 1 || params.AdaptiveHorizon < 1 || params.AdaptiveMinSamples < 1 {
+			return nil, fmt.Errorf("adaptiveLookback、adaptiveHorizon 和 adaptiveMinSamples 必须不小于 1")
+		}
+		if registry.history == nil {
+			return nil, fmt.Errorf("启用 adaptive 需要信号历史存储")
+		}
+	}
 
-	for _, strategy := range cs.strategies {
-		signal := strategy.Evaluate(data, analysisResult)
-		weight := cs.weights[strategy.Name()]
-		totalWeight += weight
+	children, err := registry.BuildChildren(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-		switch signal.Action {
-		case "BUY":
-			buyScore += weight
-		case "SELL":
-			sellScore += weight
+	// BuildChildren 跳过未启用的子策略，按同样的顺序取出对应配置
+	var voters, vetoes []Strategy
+	var weights []float64
+	enabled := 0
+	for _, child := range cfg.Children {
+		if !child.IsEnabled() {
+			continue
+		}
+		s := children[enabled]
+		enabled++
+		if child.Veto {
+			vetoes = append(vetoes, s)
+			continue
 		}
+		weight := child.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		voters = append(voters, s)
+		weights = append(weights, weight)
+	}
+	if len(voters) == 0 {
+		return nil, fmt.Errorf("组合策略至少需要一个启用的非否决子策略")
+	}
+
+	s := NewCompositeStrategy(voters, weights)
+	s.Vetoes = vetoes
+	s.Mode = params.Mode
+	s.Threshold = threshold
+	s.Adaptive = params.Adaptive
+	s.AdaptiveLookback = params.AdaptiveLookback
+	s.AdaptiveHorizon = params.AdaptiveHorizon
+	s.AdaptiveMinSamples = params.AdaptiveMinSamples
+	s.history = registry.history
+	return s, nil
+}
+
+func (cs *CompositeStrategy) RequiredTimeframes() []string {
+	return RequiredTimeframes(append(append([]Strategy{}, cs.strategies...), cs.Vetoes...))
+}
+
+func (cs *CompositeStrategy) Evaluate(data []models.Bar, analysisResult *models.AnalysisResult) *models.TradeSignal {
+	return cs.EvaluateMulti(NewMultiTimeframeContext(cs.Timeframe(), data, analysisResult))
+}
+
+// vote 一个子策略的投票
+type vote struct {
+	name   string
+	signal *models.TradeSignal
+	weight float64
+}
+
+func (cs *CompositeStrategy) EvaluateMulti(ctx *MultiTimeframeContext) *models.TradeSignal {
+	data := ctx.Primary().Bars
+	if len(data) == 0 {
+		return &models.TradeSignal{StrategyName: cs.Name(), Action: models.ActionHold, Reason: "不足够的数据"}
 	}
 
-	// 归一化得分
-	buyScore /= totalWeight
-	sellScore /= totalWeight
+	votes := make([]vote, len(cs.strategies))
+	for i, strategy := range cs.strategies {
+		signal := EvaluateInContext(strategy, ctx)
+		weight := cs.weights[i]
+		if cs.Adaptive {
+			weight = cs.adaptiveWeight(strategy, signal, ctx.Timeframe, data, weight)
+		}
+		votes[i] = vote{name: strategy.Name(), signal: signal, weight: weight}
+	}
 
-	threshold := 0.6 // 可以根据需要调整这个阈值
+	action, strength, reason, values := cs.aggregate(votes)
+	for _, v := range votes {
+		values[fmt.Sprintf("Weight(%s)", v.name)] = v.weight
+	}
 
-	if buyScore > threshold {
+	if action.IsTrade() {
+		for _, veto := range cs.Vetoes {
+			signal := EvaluateInContext(veto, ctx)
+			if signal.Action.IsTrade() && signal.Action != action {
+				return &models.TradeSignal{
+					StrategyName: cs.Name(),
+					Action:       models.ActionHold,
+					Reason:       fmt.Sprintf("%s被 %s 否决：%s", reason, veto.Name(), signal.Reason),
+					Indicators:   values,
+				}
+			}
+		}
 		return &models.TradeSignal{
 			StrategyName: cs.Name(),
-			Action:       "BUY",
+			Action:       action,
 			Price:        data[len(data)-1].Close,
-			Reason:       "综合策略买入信号强度高",
+			Reason:       reason,
+			Strength:     strength,
+			Indicators:   values,
 		}
-	} else if sellScore > threshold {
-		return &models.TradeSignal{
-			StrategyName: cs.Name(),
-			Action:       "SELL",
+	}
+
+	return &models.TradeSignal{StrategyName: cs.Name(), Action: models.ActionHold, Reason: reason, Indicators: values}
+}
+
+// aggregate 按 Mode 聚合投票，返回动作、强度、原因和得分
+func (cs *CompositeStrategy) aggregate(votes []vote) (models.SignalAction, float64, string, map[string]float64) {
+	var buyWeight, sellWeight, totalWeight, netStrength float64
+	var buyCount, sellCount int
+	var buyStrength, sellStrength float64
+	for _, v := range votes {
+		totalWeight += v.weight
+		switch v.signal.Action {
+		case models.ActionBuy:
+			buyWeight += v.weight
+			buyCount++
+			buyStrength += v.signal.Strength
+			netStrength += v.weight * v.signal.Strength
+		case models.ActionSell:
+			sellWeight += v.weight
+			sellCount++
+			sellStrength += v.signal.Strength
+			netStrength -= v.weight * v.signal.Strength
+		}
+	}
+
+	values := make(map[string]float64)
+	switch cs.Mode {
+	case CompositeModeMajority:
+		n := float64(len(votes))
+		buy, sell := float64(buyCount)/n, float64(sellCount)/n
+		values["BuyScore"], values["SellScore"] = buy, sell
+		return decide(buy, sell, cs.Threshold, fmt.Sprintf("%d/%d 个子策略", buyCount, len(votes)), fmt.Sprintf("%d/%d 个子策略", sellCount, len(votes)), values)
+
+	case CompositeModeUnanimous:
+		values["BuyScore"], values["SellScore"] = float64(buyCount)/float64(len(votes)), float64(sellCount)/float64(len(votes))
+		switch {
+		case buyCount == len(votes) && buyStrength/float64(buyCount) >= cs.Threshold:
+			return models.ActionBuy, buyStrength / float64(buyCount), "所有子策略一致买入", values
+		case sellCount == len(votes) && sellStrength/float64(sellCount) >= cs.Threshold:
+			return models.ActionSell, sellStrength / float64(sellCount), "所有子策略一致卖出", values
+		}
+		return models.ActionHold, 0, "子策略未达成一致", values
+
+	case CompositeModeStrength:
+		net := 0.0
+		if totalWeight > 0 {
+			net = netStrength / totalWeight
+		}
+		values["NetStrength"] = net
+		switch {
+		case net > cs.Threshold:
+			return models.ActionBuy, clampStrength(net), fmt.Sprintf("加权信号强度 %.2f 超过阈值 %.2f，买入", net, cs.Threshold), values
+		case net < -cs.Threshold:
+			return models.ActionSell, clampStrength(-net), fmt.Sprintf("加权信号强度 %.2f 低于阈值 -%.2f，卖出", net, cs.Threshold), values
+		}
+		return models.ActionHold, 0, fmt.Sprintf("加权信号强度 %.2f 未超过阈值 %.2f", net, cs.Threshold), values
+
+	default:
+		buy, sell := 0.0, 0.0
+		if totalWeight > 0 {
+			buy, sell = buyWeight/totalWeight, sellWeight/totalWeight
+		}
+		values["BuyScore"], values["SellScore"] = buy, sell
+		return decide(buy, sell, cs.Threshold, fmt.Sprintf("权重占比 %.2f", buy), fmt.Sprintf("权重占比 %.2f", sell), values)
+	}
+}
+
+// decide 在买入/卖出得分中选出超过阈值的一方
+func decide(buy, sell, threshold float64, buyNote, sellNote string, values map[string]float64) (models.SignalAction, float64, string, map[string]float64) {
+	switch {
+	case buy > threshold && buy >= sell:
+		return models.ActionBuy, buy, fmt.Sprintf("综合策略买入（%s 超过阈值 %.2f）", buyNote, threshold), values
+	case sell > threshold:
+		return models.ActionSell, sell, fmt.Sprintf("综合策略卖出（%s 超过阈值 %.2f）", sellNote, threshold), values
+	}
+	return models.ActionHold, 0, "无明显信号", values
+}
+
+// adaptiveWeight 验证子策略到期的历史信号、记录本次信号，并按最近的准确率调整权重。
+// 已验证的信号不足 AdaptiveMinSamples 条或读取历史失败时使用配置的权重
+func (cs *CompositeStrategy) adaptiveWeight(child Strategy, signal *models.TradeSignal, timeframe string, data []models.Bar, weight float64) float64 {
+	key := cs.Name() + "/" + child.Name()
+	log := utils.Log.WithField("strategy", key)
+
+	pending, err := cs.history.PendingSignals(key, timeframe, data[0].Timestamp)
+	if err != nil {
+		log.WithError(err).Warn("读取待验证信号失败")
+		return weight
+	}
+	for _, record := range pending {
+		i := sort.Search(len(data), func(i int) bool { return !data[i].Timestamp.Before(record.Timestamp) })
+		if i >= len(data) || !data[i].Timestamp.Equal(record.Timestamp) || i+cs.AdaptiveHorizon >= len(data) {
+			continue
+		}
+		later := data[i+cs.AdaptiveHorizon].Close
+		correct := (record.Action == models.ActionBuy && later > record.Price) || (record.Action == models.ActionSell && later < record.Price)
+		if err := cs.history.ResolveSignal(record.ID, correct); err != nil {
+			log.WithError(err).Warn("保存信号验证结果失败")
+		}
+	}
+
+	if signal.Action.IsTrade() {
+		err := cs.history.RecordSignal(&models.SignalRecord{
+			StrategyName: key,
+			Symbol:       signal.Symbol,
+			Timeframe:    timeframe,
+			Action:       signal.Action,
 			Price:        data[len(data)-1].Close,
-			Reason:       "综合策略卖出信号强度高",
+			Timestamp:    data[len(data)-1].Timestamp,
+		})
+		if err != nil {
+			log.WithError(err).Warn("记录信号失败")
 		}
 	}
 
-	return &models.TradeSignal{
-		StrategyName: cs.Name(),
-		Action:       "HOLD",
-		Reason:       "无明显信号",
+	correct, total, err := cs.history.Accuracy(key, timeframe, cs.AdaptiveLookback)
+	if err != nil {
+		log.WithError(err).Warn("读取信号准确率失败")
+		return weight
+	}
+	if total < cs.AdaptiveMinSamples {
+		return weight
 	}
+	return weight * float64(correct) / float64(total)
 }
@@ -0,0 +1,408 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/analysis/indicators"
+	"github.com/qqqq/eth-trading-system/internal/config"
+	"github.com/qqqq/eth-trading-system/internal/models"
+	"github.com/qqqq/eth-trading-system/internal/utils"
+)
+
+const (
+	DCAModeFixed          = "dca"
+	DCAModeValueAveraging = "value_averaging"
+
+	DCAScheduleDaily  = "daily"
+	DCAScheduleWeekly = "weekly"
+)
+
+// DCAStrategy 实现了定投策略，按日历在每个周期的第一根K线上发出买入信号：
+//
+//   - dca: 每期买入固定金额 QuoteAmount；价格低于 SMA 或 RSI 超卖时乘以 DipMultiplier
+//   - value_averaging: 持仓市值目标每期增加 TargetIncrement，每期买入（或在 AllowSell 时卖出）
+//     与目标市值的差额，单期金额不超过 MaxQuotePerPeriod
+//
+// 价值平均模式的期数从 StartDate 之后的第一个周期（未设置时为数据中第一根K线之后的周期）按日历计算；
+// 当前持仓取自策略实际持有的数量，没有持仓来源时（如回测）在K线上重放推导。
+//
+// 设置了状态存储时，已定投的最新周期保存在存储中，每个周期在第一次评估到该周期的K线时定投一次，
+// 周期开始时的K线缺失或错过采集也不会跳过该期；没有状态存储时（如回测）在每个周期的第一根K线上定投。
+type DCAStrategy struct {
+	BaseStrategy
+	Mode     string
+	Schedule string
+	Weekday  time.Weekday
+	// Hour 每个周期从 UTC 的该小时开始
+	Hour        int
+	QuoteAmount float64
+
+	DipMultiplier  float64
+	DipSMAPeriod   int
+	DipRSIPeriod   int
+	DipRSIOversold float64
+
+	TargetIncrement   float64
+	MaxQuotePerPeriod float64
+	AllowSell         bool
+	StartDate         time.Time
+
+	positions PositionSource
+	states    StateStore
+
+	mu sync.Mutex
+	// state 已定投的最新周期，lastSignal 和 lastBar 为最新一根已处理K线的信号
+	state      *dcaState
+	lastSignal *models.TradeSignal
+	lastBar    time.Time
+}
+
+// dcaState 已发出定投信号的最新周期编号
+type dcaState struct {
+	LastPeriod int64 `json:"last_period"`
+}
+
+func NewDCAStrategy(mode, schedule string, quoteAmount float64) *DCAStrategy {
+	return &DCAStrategy{
+		BaseStrategy:    BaseStrategy{name: "DCA"},
+		Mode:            mode,
+		Schedule:        schedule,
+		Weekday:         time.Monday,
+		QuoteAmount:     quoteAmount,
+		DipMultiplier:   1,
+		DipRSIOversold:  30,
+		TargetIncrement: quoteAmount,
+	}
+}
+
+type dcaParams struct {
+	Mode              string  `mapstructure:"mode"`
+	Schedule          string  `mapstructure:"schedule"`
+	Weekday           string  `mapstructure:"weekday"`
+	Hour              int     `mapstructure:"hour"`
+	QuoteAmount       float64 `mapstructure:"quoteAmount"`
+	DipMultiplier     float64 `mapstructure:"dipMultiplier"`
+	DipSMAPeriod      int     `mapstructure:"dipSMAPeriod"`
+	DipRSIPeriod      int     `mapstructure:"dipRSIPeriod"`
+	DipRSIOversold    float64 `mapstructure:"dipRSIOversold"`
+	TargetIncrement   float64 `mapstructure:"targetIncrement"`
+	MaxQuotePerPeriod float64 `mapstructure:"maxQuotePerPeriod"`
+	AllowSell         bool    `mapstructure:"allowSell"`
+	StartDate         string  `mapstructure:"startDate"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+func newDCAFromConfig(cfg config.StrategyConfig, registry *Registry) (Strategy, error) {
+	params := dcaParams{
+		Mode:           DCAModeFixed,
+		Schedule:       DCAScheduleWeekly,
+		Weekday:        "monday",
+		QuoteAmount:    100,
+		DipMultiplier:  1,
+		DipRSIOversold: 30,
+	}
+	if err := decodeParams(cfg.Params, &params); err != nil {
+		return nil, err
+	}
+
+	if params.Mode != DCAModeFixed && params.Mode != DCAModeValueAveraging {
+		return nil, fmt.Errorf("mode 必须为 %s 或 %s，当前为 %q", DCAModeFixed, DCAModeValueAveraging, params.Mode)
+	}
+	if params.Schedule != DCAScheduleDaily && params.Schedule != DCAScheduleWeekly {
+		return nil, fmt.Errorf("schedule 必须为 %s 或 %s，当前为 %q", DCAScheduleDaily, DCAScheduleWeekly, params.Schedule)
+	}
+	weekday, ok := weekdays[strings.ToLower(params.Weekday)]
+	if !ok {
+		return nil, fmt.Errorf("无效的 weekday %q", params.Weekday)
+	}
+	if params.Hour < 0 || params.Hour > 23 {
+		return nil, fmt.Errorf("hour 必须在 0-23 之间")
+	}
+	if params.QuoteAmount <= 0 {
+		return nil, fmt.Errorf("quoteAmount 必须大于 0")
+	}
+	if params.DipMultiplier < 1 {
+		return nil, fmt.Errorf("dipMultiplier 不能小于 1")
+	}
+	if params.DipSMAPeriod < 0 || params.DipRSIPeriod < 0 {
+		return nil, fmt.Errorf("dipSMAPeriod 和 dipRSIPeriod 不能为负数")
+	}
+	if params.TargetIncrement < 0 || params.MaxQuotePerPeriod < 0 {
+		return nil, fmt.Errorf("targetIncrement 和 maxQuotePerPeriod 不能为负数")
+	}
+
+	s := NewDCAStrategy(params.Mode, params.Schedule, params.QuoteAmount)
+	s.Weekday = weekday
+	s.Hour = params.Hour
+	s.DipMultiplier = params.DipMultiplier
+	s.DipSMAPeriod = params.DipSMAPeriod
+	s.DipRSIPeriod = params.DipRSIPeriod
+	s.DipRSIOversold = params.DipRSIOversold
+	if params.TargetIncrement > 0 {
+		s.TargetIncrement = params.TargetIncrement
+	}
+	s.MaxQuotePerPeriod = params.MaxQuotePerPeriod
+	s.AllowSell = params.AllowSell
+	if params.StartDate != "" {
+		start, err := time.Parse("2006-01-02", params.StartDate)
+		if err != nil {
+			return nil, fmt.Errorf("startDate 格式应为 2006-01-02: %v", err)
+		}
+		s.StartDate = start
+	}
+	if registry.states != nil && cfg.Timeframe == "" {
+		return nil, fmt.Errorf("定投策略必须指定 timeframe，否则会在每个时间框架上重复定投")
+	}
+	s.positions = registry.positions
+	s.states = registry.states
+	return s, nil
+}
+
+// period 返回K线所属的定投周期编号，周期边界为每天（或每周 Weekday）UTC 的 Hour 点
+func (s *DCAStrategy) period(t time.Time) int64 {
+	shifted := t.UTC().Add(-time.Duration(s.Hour) * time.Hour)
+	days := shifted.Unix() / 86400
+	if s.Schedule == DCAScheduleDaily {
+		return days
+	}
+	// 1970-01-01 是星期四，对齐到 Weekday 开始的一周
+	offset := (int64(time.Thursday) - int64(s.Weekday) + 7) % 7
+	return int64(math.Floor(float64(days+offset) / 7))
+}
+
+func (s *DCAStrategy) Evaluate(data []models.Bar, analysisResult *models.AnalysisResult) *models.TradeSignal {
+	if len(data) < 2 {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "不足够的数据"}
+	}
+
+	if s.states != nil {
+		return s.evaluateSaved(data)
+	}
+	if s.Mode == DCAModeValueAveraging {
+		return s.evaluateValueAveraging(data)
+	}
+
+	last := len(data) - 1
+	if s.period(data[last].Timestamp) == s.period(data[last-1].Timestamp) {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "未到定投时间"}
+	}
+	return s.fixedBuy(data)
+}
+
+// evaluateSaved 最新K线所在周期晚于已定投的最新周期时定投，并保存该周期。
+// 最新K线已处理过时返回当时的信号，供同一根K线上的其他调用方使用
+func (s *DCAStrategy) evaluateSaved(data []models.Bar) *models.TradeSignal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	last := len(data) - 1
+	if s.state == nil {
+		s.state = s.loadState(data)
+	}
+	if s.lastSignal != nil && s.lastBar.Equal(data[last].Timestamp) {
+		signal := *s.lastSignal
+		return &signal
+	}
+
+	current := s.period(data[last].Timestamp)
+	signal := &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "未到定投时间"}
+	switch {
+	case current <= s.state.LastPeriod:
+	case s.Mode == DCAModeValueAveraging:
+		if first := s.firstPeriod(data); current >= first {
+			var units float64
+			if s.positions != nil {
+				units = s.positions.StrategyPosition(s.Name())
+			}
+			signal, _ = s.valueAveragingStep(int(current-first)+1, units, data[last].Close)
+			s.savePeriod(current)
+		}
+	default:
+		signal = s.fixedBuy(data)
+		s.savePeriod(current)
+	}
+
+	s.lastSignal, s.lastBar = signal, data[last].Timestamp
+	copied := *signal
+	return &copied
+}
+
+func (s *DCAStrategy) stateKey() string {
+	return "dca/" + s.Name() + "/" + s.Timeframe()
+}
+
+// loadState 读取已定投的最新周期；不存在或读取失败时视为已在上一根K线所在的周期定投，
+// 避免第一次运行时在周期中途补投
+func (s *DCAStrategy) loadState(data []models.Bar) *dcaState {
+	state := &dcaState{}
+	found, err := s.states.LoadState(s.stateKey(), state)
+	if err != nil {
+		utils.Log.WithError(err).WithField("strategy", s.Name()).Warn("读取定投周期失败")
+	}
+	if err != nil || !found {
+		state.LastPeriod = s.period(data[len(data)-2].Timestamp)
+	}
+	return state
+}
+
+func (s *DCAStrategy) savePeriod(period int64) {
+	s.state.LastPeriod = period
+	if err := s.states.SaveState(s.stateKey(), s.state); err != nil {
+		utils.Log.WithError(err).WithField("strategy", s.Name()).Error("保存定投周期失败")
+	}
+}
+
+// fixedBuy 按固定金额（回调时加倍）在最新K线上定投买入
+func (s *DCAStrategy) fixedBuy(data []models.Bar) *models.TradeSignal {
+	last := len(data) - 1
+	amount, strength := s.QuoteAmount, 0.5
+	var dipReason string
+	var values map[string]float64
+	if s.DipMultiplier > 1 {
+		dipReason, values = s.dip(data)
+	}
+	reason := fmt.Sprintf("%s定投买入 %.2f", scheduleLabel(s.Schedule), amount)
+	if dipReason != "" {
+		amount *= s.DipMultiplier
+		strength = 1
+		reason = fmt.Sprintf("%s定投，%s，加倍买入 %.2f（%.1f 倍）", scheduleLabel(s.Schedule), dipReason, amount, s.DipMultiplier)
+	}
+
+	price := data[last].Close
+	return &models.TradeSignal{
+		StrategyName: s.Name(),
+		Action:       models.ActionBuy,
+		Price:        price,
+		Reason:       reason,
+		Strength:     strength,
+		Size:         amount / price,
+		Indicators:   values,
+	}
+}
+
+// dip 判断当前是否处于回调，返回触发的条件描述和计算出的指标值
+func (s *DCAStrategy) dip(data []models.Bar) (string, map[string]float64) {
+	price := data[len(data)-1].Close
+	values := make(map[string]float64)
+	reason := ""
+
+	if s.DipSMAPeriod > 0 {
+		indicator := indicators.NewSimpleMovingAverage(s.DipSMAPeriod)
+		if v, err := indicator.Calculate(data); err == nil {
+			sma := v.(float64)
+			values[indicator.Name()] = sma
+			if price < sma {
+				reason = fmt.Sprintf("价格 %.2f 低于 SMA%d %.2f", price, s.DipSMAPeriod, sma)
+			}
+		}
+	}
+	if s.DipRSIPeriod > 0 {
+		indicator := indicators.NewRelativeStrengthIndex(s.DipRSIPeriod)
+		if v, err := indicator.Calculate(data); err == nil {
+			rsi := v.(float64)
+			values[indicator.Name()] = rsi
+			if reason == "" && rsi < s.DipRSIOversold {
+				reason = fmt.Sprintf("RSI(%d) %.2f 处于超卖", s.DipRSIPeriod, rsi)
+			}
+		}
+	}
+	return reason, values
+}
+
+// firstPeriod 返回价值平均第一期的周期编号
+func (s *DCAStrategy) firstPeriod(data []models.Bar) int64 {
+	if !s.StartDate.IsZero() {
+		return s.period(s.StartDate.Add(-time.Nanosecond)) + 1
+	}
+	return s.period(data[0].Timestamp) + 1
+}
+
+func (s *DCAStrategy) evaluateValueAveraging(data []models.Bar) *models.TradeSignal {
+	first := s.firstPeriod(data)
+	if s.positions == nil {
+		return s.replayValueAveraging(data, first)
+	}
+
+	last := len(data) - 1
+	current := s.period(data[last].Timestamp)
+	if current < first || current == s.period(data[last-1].Timestamp) {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "未到定投时间"}
+	}
+	signal, _ := s.valueAveragingStep(int(current-first)+1, s.positions.StrategyPosition(s.Name()), data[last].Close)
+	return signal
+}
+
+// replayValueAveraging 假设每期信号都按收盘价成交，在K线上重放推导持仓，first 为第一期的周期编号
+func (s *DCAStrategy) replayValueAveraging(data []models.Bar, first int64) *models.TradeSignal {
+	var units float64
+	signal := &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "未到定投时间"}
+
+	for i := 1; i < len(data); i++ {
+		current := s.period(data[i].Timestamp)
+		if current < first || current == s.period(data[i-1].Timestamp) {
+			signal = &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "未到定投时间"}
+			continue
+		}
+		var delta float64
+		signal, delta = s.valueAveragingStep(int(current-first)+1, units, data[i].Close)
+		units += delta
+	}
+	return signal
+}
+
+// valueAveragingStep 计算第 periods 期的价值平均信号，units 为当前持有数量，返回信号和持有数量的变化
+func (s *DCAStrategy) valueAveragingStep(periods int, units, price float64) (*models.TradeSignal, float64) {
+	target := s.TargetIncrement * float64(periods)
+	value := units * price
+	diff := target - value
+	if s.MaxQuotePerPeriod > 0 {
+		diff = math.Max(-s.MaxQuotePerPeriod, math.Min(diff, s.MaxQuotePerPeriod))
+	}
+
+	values := map[string]float64{"TargetValue": target, "PortfolioValue": value}
+	// 偏离目标一个 TargetIncrement 时信号强度为 0.5
+	strength := clampStrength(math.Abs(diff) / (2 * s.TargetIncrement))
+
+	switch {
+	case diff > 0:
+		return &models.TradeSignal{
+			StrategyName: s.Name(),
+			Action:       models.ActionBuy,
+			Price:        price,
+			Reason:       fmt.Sprintf("第 %d 期价值平均：目标市值 %.2f，当前市值 %.2f，买入 %.2f", periods, target, value, diff),
+			Strength:     strength,
+			Size:         diff / price,
+			Indicators:   values,
+		}, diff / price
+	case diff < 0 && s.AllowSell:
+		return &models.TradeSignal{
+			StrategyName: s.Name(),
+			Action:       models.ActionSell,
+			Price:        price,
+			Reason:       fmt.Sprintf("第 %d 期价值平均：当前市值 %.2f 超过目标 %.2f，卖出 %.2f", periods, value, target, -diff),
+			Strength:     strength,
+			Size:         -diff / price,
+			Indicators:   values,
+		}, diff / price
+	}
+	return &models.TradeSignal{
+		StrategyName: s.Name(),
+		Action:       models.ActionHold,
+		Reason:       fmt.Sprintf("第 %d 期价值平均：当前市值 %.2f 已达到目标 %.2f", periods, value, target),
+		Indicators:   values,
+	}, 0
+}
+
+func scheduleLabel(schedule string) string {
+	if schedule == DCAScheduleDaily {
+		return "每日"
+	}
+	return "每周"
+}
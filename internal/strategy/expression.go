@@ -0,0 +1,149 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/qqqq/eth-trading-system/internal/config"
+	"github.com/qqqq/eth-trading-system/internal/dsl"
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// ExpressionStrategy 按配置中的规则表达式产生信号，无需为新想法编写 Go 代码，例如
+//
+//	buy when crossover(sma(10), sma(30)) and rsi(14) < 70
+//	sell when crossunder(sma(10), sma(30))
+//
+// 规则在加载时编译，评估时在整段K线上计算条件序列，并像 RSIStrategy 一样重放历史以推导当前持仓：
+// 持仓期间重复满足的开仓条件不会重复产生信号。同一方向有多条规则时任一满足即可。
+// 不允许做空时，sell 规则只用于多头平仓
+type ExpressionStrategy struct {
+	BaseStrategy
+	Rules []dsl.Rule
+	// AllowShort 为 true 时，空仓状态下满足 sell 规则会开空，满足 buy 规则平空
+	AllowShort bool
+	// Strength 开仓信号的强度，平仓信号强度为 1
+	Strength float64
+}
+
+func NewExpressionStrategy(rules []dsl.Rule) *ExpressionStrategy {
+	return &ExpressionStrategy{
+		BaseStrategy: BaseStrategy{name: "Expression"},
+		Rules:        rules,
+		Strength:     0.5,
+	}
+}
+
+type expressionParams struct {
+	Rules      string  `mapstructure:"rules"`
+	AllowShort bool    `mapstructure:"allowShort"`
+	Strength   float64 `mapstructure:"strength"`
+}
+
+func newExpressionFromConfig(cfg config.StrategyConfig, _ *Registry) (Strategy, error) {
+	params := expressionParams{Strength: 0.5}
+	if err := decodeParams(cfg.Params, &params); err != nil {
+		return nil, err
+	}
+	rules, err := dsl.CompileRules(params.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("rules: %v", err)
+	}
+	hasBuy := false
+	for _, rule := range rules {
+		hasBuy = hasBuy || rule.Action == dsl.ActionBuy
+	}
+	if !hasBuy && !params.AllowShort {
+		return nil, fmt.Errorf("不允许做空时至少需要一条 buy 规则")
+	}
+	if params.Strength <= 0 || params.Strength > 1 {
+		return nil, fmt.Errorf("strength 必须在 (0, 1] 范围内")
+	}
+
+	s := NewExpressionStrategy(rules)
+	s.AllowShort = params.AllowShort
+	s.Strength = params.Strength
+	return s, nil
+}
+
+func (s *ExpressionStrategy) Evaluate(data []models.Bar, analysisResult *models.AnalysisResult) *models.TradeSignal {
+	if len(data) < 2 {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "不足够的数据"}
+	}
+
+	results := make([]dsl.Result, len(s.Rules))
+	values := make(map[string]float64)
+	for i, rule := range s.Rules {
+		results[i] = rule.Condition.Evaluate(data)
+		for label, v := range results[i].Values {
+			values[label] = v
+		}
+	}
+
+	// position: 1 持多，-1 持空，0 空仓
+	position := 0
+	var action models.SignalAction
+	var reason string
+	var strength float64
+	for i := 1; i < len(data); i++ {
+		action, reason, strength = s.step(results, i, position)
+		switch {
+		case action == models.ActionBuy && position == -1, action == models.ActionSell && position == 1:
+			position = 0
+		case action == models.ActionBuy:
+			position = 1
+		case action == models.ActionSell:
+			position = -1
+		}
+	}
+
+	if action == models.ActionHold {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: reason, Indicators: values}
+	}
+	return &models.TradeSignal{
+		StrategyName: s.Name(),
+		Action:       action,
+		Price:        data[len(data)-1].Close,
+		Reason:       reason,
+		Strength:     strength,
+		Indicators:   values,
+	}
+}
+
+// step 计算第 i 根K线上的动作，先判断当前持仓的平仓规则，再判断开仓规则
+func (s *ExpressionStrategy) step(results []dsl.Result, i, position int) (models.SignalAction, string, float64) {
+	buy := s.matched(results, i, dsl.ActionBuy)
+	sell := s.matched(results, i, dsl.ActionSell)
+
+	switch position {
+	case 1:
+		if sell != "" {
+			return models.ActionSell, fmt.Sprintf("满足卖出规则 %s，多头平仓", sell), 1
+		}
+		return models.ActionHold, "持有多头，未满足卖出规则", 0
+	case -1:
+		if buy != "" {
+			return models.ActionBuy, fmt.Sprintf("满足买入规则 %s，空头平仓", buy), 1
+		}
+		return models.ActionHold, "持有空头，未满足买入规则", 0
+	}
+
+	switch {
+	case buy != "" && sell != "":
+		return models.ActionHold, fmt.Sprintf("买入规则 %s 与卖出规则 %s 同时满足，不开仓", buy, sell), 0
+	case buy != "":
+		return models.ActionBuy, fmt.Sprintf("满足买入规则 %s", buy), s.Strength
+	case sell != "" && s.AllowShort:
+		return models.ActionSell, fmt.Sprintf("满足卖出规则 %s，开空", sell), s.Strength
+	}
+	return models.ActionHold, "未满足任何开仓规则", 0
+}
+
+// matched 返回第 i 根K线上第一条满足的指定动作规则的条件，没有满足的规则时返回空字符串
+func (s *ExpressionStrategy) matched(results []dsl.Result, i int, action string) string {
+	for r, rule := range s.Rules {
+		if rule.Action == action && results[r].Series[i] != 0 {
+			return rule.Condition.Source()
+		}
+	}
+	return ""
+}
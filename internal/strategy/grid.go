@@ -0,0 +1,432 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/analysis/indicators"
+	"github.com/qqqq/eth-trading-system/internal/analysis/trend"
+	"github.com/qqqq/eth-trading-system/internal/config"
+	"github.com/qqqq/eth-trading-system/internal/models"
+	"github.com/qqqq/eth-trading-system/internal/utils"
+)
+
+const (
+	GridSpacingArithmetic = "arithmetic"
+	GridSpacingGeometric  = "geometric"
+)
+
+// GridStrategy 实现了区间网格策略：
+// 在 [Lower, Upper] 之间按等差或等比间距划分 GridCount 格，
+// 价格下探到某一格时买入一份，之后价格上涨到上一格时卖出该份。
+// 收盘价离开区间，或趋势强度超过 MaxTrendStrength 时暂停成交，等待行情回到震荡。
+//
+// 使用K线最高/最低价判断是否触及网格。设置了状态存储时，各格持仓保存在存储中，
+// 每根已收盘的K线只处理一次，信号对应的买卖在实际成交（OnFill）后才计入持仓，
+// 重启和配置热更新后从保存的持仓继续；没有状态存储时（如回测）在传入的K线上重放推导，信号视为全部成交。
+type GridStrategy struct {
+	BaseStrategy
+	Lower     float64
+	Upper     float64
+	GridCount int
+	Spacing   string
+	// QuotePerGrid 每格买入的计价货币金额
+	QuotePerGrid float64
+
+	// TrendAnalyzer 不为 nil 时，趋势强度绝对值超过 MaxTrendStrength（百分比）即暂停
+	TrendAnalyzer    *trend.TrendAnalyzer
+	MaxTrendStrength float64
+
+	levels []float64
+	states StateStore
+
+	mu sync.Mutex
+	// state 已保存的各格持仓，lastSignal 和 lastBar 为最新一根已处理K线的信号
+	state      *gridState
+	lastSignal *models.TradeSignal
+	lastBar    time.Time
+}
+
+func NewGridStrategy(lower, upper float64, gridCount int, spacing string) *GridStrategy {
+	s := &GridStrategy{
+		BaseStrategy: BaseStrategy{name: "Grid"},
+		Lower:        lower,
+		Upper:        upper,
+		GridCount:    gridCount,
+		Spacing:      spacing,
+		QuotePerGrid: 100,
+	}
+	s.levels = gridLevels(lower, upper, gridCount, spacing)
+	return s
+}
+
+// Levels 返回从低到高的网格价位
+func (s *GridStrategy) Levels() []float64 {
+	return s.levels
+}
+
+func gridLevels(lower, upper float64, gridCount int, spacing string) []float64 {
+	levels := make([]float64, gridCount+1)
+	for k := range levels {
+		ratio := float64(k) / float64(gridCount)
+		if spacing == GridSpacingGeometric {
+			levels[k] = lower * math.Pow(upper/lower, ratio)
+		} else {
+			levels[k] = lower + (upper-lower)*ratio
+		}
+	}
+	return levels
+}
+
+type gridParams struct {
+	Lower            float64 `mapstructure:"lower"`
+	Upper            float64 `mapstructure:"upper"`
+	GridCount        int     `mapstructure:"gridCount"`
+	Spacing          string  `mapstructure:"spacing"`
+	QuotePerGrid     float64 `mapstructure:"quotePerGrid"`
+	TrendFilter      bool    `mapstructure:"trendFilter"`
+	TrendShortPeriod int     `mapstructure:"trendShortPeriod"`
+	TrendLongPeriod  int     `mapstructure:"trendLongPeriod"`
+	TrendMAType      string  `mapstructure:"trendMaType"`
+	MaxTrendStrength float64 `mapstructure:"maxTrendStrength"`
+}
+
+func newGridFromConfig(cfg config.StrategyConfig, registry *Registry) (Strategy, error) {
+	params := gridParams{
+		GridCount:        10,
+		Spacing:          GridSpacingArithmetic,
+		QuotePerGrid:     100,
+		TrendFilter:      true,
+		TrendShortPeriod: 10,
+		TrendLongPeriod:  30,
+		TrendMAType:      "sma",
+		MaxTrendStrength: 3,
+	}
+	if err := decodeParams(cfg.Params, &params); err != nil {
+		return nil, err
+	}
+
+	if params.Lower <= 0 || params.Upper <= params.Lower {
+		return nil, fmt.Errorf("需要满足 0 < lower(%v) < upper(%v)", params.Lower, params.Upper)
+	}
+	if params.GridCount < 2 {
+		return nil, fmt.Errorf("gridCount 必须不小于 2")
+	}
+	if params.Spacing != GridSpacingArithmetic && params.Spacing != GridSpacingGeometric {
+		return nil, fmt.Errorf("spacing 必须为 %s 或 %s，当前为 %q", GridSpacingArithmetic, GridSpacingGeometric, params.Spacing)
+	}
+	if params.QuotePerGrid <= 0 {
+		return nil, fmt.Errorf("quotePerGrid 必须大于 0")
+	}
+
+	s := NewGridStrategy(params.Lower, params.Upper, params.GridCount, params.Spacing)
+	s.QuotePerGrid = params.QuotePerGrid
+	if params.TrendFilter {
+		if params.TrendShortPeriod <= 0 || params.TrendShortPeriod >= params.TrendLongPeriod {
+			return nil, fmt.Errorf("需要满足 0 < trendShortPeriod(%d) < trendLongPeriod(%d)", params.TrendShortPeriod, params.TrendLongPeriod)
+		}
+		if params.MaxTrendStrength <= 0 {
+			return nil, fmt.Errorf("maxTrendStrength 必须大于 0")
+		}
+		analyzer, err := trend.NewTrendAnalyzerWithType(indicators.MovingAverageType(params.TrendMAType), params.TrendShortPeriod, params.TrendLongPeriod)
+		if err != nil {
+			return nil, fmt.Errorf("trendMaType: %v", err)
+		}
+		s.TrendAnalyzer = analyzer
+		s.MaxTrendStrength = params.MaxTrendStrength
+	}
+	if registry.states != nil && cfg.Timeframe == "" {
+		return nil, fmt.Errorf("网格策略必须指定 timeframe，各格持仓按策略和时间框架保存")
+	}
+	s.states = registry.states
+	return s, nil
+}
+
+// gridState 各格的持仓：Filled[k] 表示在第 k 格买入、待在第 k+1 格卖出的已成交数量。
+// Levels 为保存时的网格价位，LastBar 为已处理的最新已收盘K线，Pending 为等待成交的最近一次信号
+type gridState struct {
+	Levels  []float64  `json:"levels"`
+	Filled  []float64  `json:"filled"`
+	LastBar time.Time  `json:"last_bar"`
+	Pending *gridOrder `json:"pending,omitempty"`
+}
+
+// gridOrder 一次网格信号对应的订单：Target 为全部成交后的各格持仓，Quantity 为带方向的净数量（卖出为负）
+type gridOrder struct {
+	Target   []float64 `json:"target"`
+	Quantity float64   `json:"quantity"`
+}
+
+func (s *GridStrategy) Evaluate(data []models.Bar, analysisResult *models.AnalysisResult) *models.TradeSignal {
+	if len(data) < 2 {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "不足够的数据"}
+	}
+	if s.states != nil {
+		return s.evaluateSaved(data)
+	}
+
+	state := gridState{Filled: make([]float64, len(s.levels)-1)}
+	var signal *models.TradeSignal
+	for i := 1; i < len(data); i++ {
+		signal = s.step(&state, data, i)
+	}
+	return signal
+}
+
+// evaluateSaved 从保存的各格持仓继续，只处理尚未处理过的已收盘K线，尚未收盘的K线留到收盘后处理。
+// 一次处理多根K线（如重启后补采）时合并各根K线的买卖数量；最新已收盘K线已处理过时返回当时的信号，
+// 供同一根K线上的其他调用方使用。上一次信号到处理下一根K线时仍未成交（未开启交易、被风控拒绝等）则作废
+func (s *GridStrategy) evaluateSaved(data []models.Bar) *models.TradeSignal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == nil {
+		s.state = s.loadState()
+	}
+	data = ClosedBarsAsOf(data, s.Timeframe(), time.Now())
+	if len(data) < 2 {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "不足够的已收盘K线"}
+	}
+	last := len(data) - 1
+	if s.state.LastBar.IsZero() {
+		// 第一次运行只处理最新K线，窗口中更早的K线没有实际成交
+		s.state.LastBar = data[last-1].Timestamp
+	}
+	if !data[last].Timestamp.After(s.state.LastBar) {
+		if s.lastSignal != nil && s.lastBar.Equal(data[last].Timestamp) {
+			signal := *s.lastSignal
+			return &signal
+		}
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: fmt.Sprintf("K线已处理，当前持有 %d/%d 格", s.state.holding(), len(s.state.Filled))}
+	}
+
+	if s.state.Pending != nil {
+		utils.Log.WithField("strategy", s.Name()).Info("上一次网格信号未成交，已作废")
+		s.state.Pending = nil
+	}
+	work := &gridState{Filled: append([]float64(nil), s.state.Filled...)}
+	var signals []*models.TradeSignal
+	for i := 1; i < len(data); i++ {
+		if data[i].Timestamp.After(s.state.LastBar) {
+			signals = append(signals, s.step(work, data, i))
+		}
+	}
+	s.state.LastBar = data[last].Timestamp
+	if quantity := sum(work.Filled) - sum(s.state.Filled); math.Abs(quantity) > 1e-12 {
+		s.state.Pending = &gridOrder{Target: work.Filled, Quantity: quantity}
+	} else {
+		// 买卖数量相抵时不会下单，直接换格
+		s.state.Filled = work.Filled
+	}
+	s.saveState()
+
+	signal := mergeGridSignals(signals, data[last].Close)
+	s.lastSignal, s.lastBar = signal, data[last].Timestamp
+	copied := *signal
+	return &copied
+}
+
+// OnFill 按实际成交更新各格持仓：与等待成交的信号同向的成交按成交比例计入，
+// 其他卖出（如保护性订单平仓）从最高一格开始扣减，熔断平仓清空全部持仓
+func (s *GridStrategy) OnFill(fill models.Fill) {
+	if s.states == nil || (fill.StrategyName != "" && fill.StrategyName != s.Name()) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == nil {
+		s.state = s.loadState()
+	}
+
+	signed := fill.Side.Sign() * fill.Quantity
+	pending := s.state.Pending
+	switch {
+	case fill.StrategyName == "":
+		s.state.Filled = make([]float64, len(s.levels)-1)
+		s.state.Pending = nil
+	case pending != nil && pending.Quantity*signed > 0:
+		ratio := math.Min(signed/pending.Quantity, 1)
+		for k, target := range pending.Target {
+			s.state.Filled[k] += (target - s.state.Filled[k]) * ratio
+		}
+		s.state.Pending = nil
+	case signed < 0:
+		remaining := -signed
+		for k := len(s.state.Filled) - 1; k >= 0 && remaining > 1e-12; k-- {
+			reduced := math.Min(s.state.Filled[k], remaining)
+			s.state.Filled[k] -= reduced
+			remaining -= reduced
+		}
+	default:
+		return
+	}
+	for k, size := range s.state.Filled {
+		if size < 1e-12 {
+			s.state.Filled[k] = 0
+		}
+	}
+	s.saveState()
+}
+
+func (s *GridStrategy) saveState() {
+	if err := s.states.SaveState(s.stateKey(), s.state); err != nil {
+		utils.Log.WithError(err).WithField("strategy", s.Name()).Error("保存网格持仓失败")
+	}
+}
+
+func (s *GridStrategy) stateKey() string {
+	return "grid/" + s.Name() + "/" + s.Timeframe()
+}
+
+// loadState 读取保存的各格持仓，不存在、读取失败或网格价位已变化时从空仓开始
+func (s *GridStrategy) loadState() *gridState {
+	log := utils.Log.WithField("strategy", s.Name())
+	state := &gridState{}
+	found, err := s.states.LoadState(s.stateKey(), state)
+	switch {
+	case err != nil:
+		log.WithError(err).Warn("读取网格持仓失败，从空仓开始")
+	case found && !sameLevels(state.Levels, s.levels):
+		log.Warn("网格价位已变化，保存的各格持仓作废，从空仓开始")
+	case found:
+		return state
+	}
+	return &gridState{Levels: s.levels, Filled: make([]float64, len(s.levels)-1)}
+}
+
+func (st *gridState) holding() int {
+	n := 0
+	for _, size := range st.Filled {
+		if size > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func sameLevels(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeGridSignals 合并多根K线的网格信号，按买卖数量的净值下单
+func mergeGridSignals(signals []*models.TradeSignal, price float64) *models.TradeSignal {
+	last := signals[len(signals)-1]
+	if len(signals) == 1 {
+		return last
+	}
+	net, takeProfit := 0.0, 0.0
+	var reasons []string
+	for _, signal := range signals {
+		switch signal.Action {
+		case models.ActionBuy:
+			net += signal.Size
+			takeProfit = signal.TakeProfit
+		case models.ActionSell:
+			net -= signal.Size
+		default:
+			continue
+		}
+		reasons = append(reasons, signal.Reason)
+	}
+	if len(reasons) == 0 {
+		return last
+	}
+	reason := fmt.Sprintf("合并 %d 根K线的网格成交：%s", len(signals), strings.Join(reasons, "；"))
+	switch {
+	case net > 0:
+		return &models.TradeSignal{StrategyName: last.StrategyName, Action: models.ActionBuy, Price: price, Reason: reason, Strength: 1, Size: net, TakeProfit: takeProfit}
+	case net < 0:
+		return &models.TradeSignal{StrategyName: last.StrategyName, Action: models.ActionSell, Price: price, Reason: reason, Strength: 1, Size: -net}
+	}
+	return &models.TradeSignal{StrategyName: last.StrategyName, Action: models.ActionHold, Reason: reason + "，买卖数量相抵"}
+}
+
+func (s *GridStrategy) step(state *gridState, data []models.Bar, i int) *models.TradeSignal {
+	bar := data[i]
+
+	if paused, reason := s.paused(data, i); paused {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: reason}
+	}
+
+	var sold, bought []string
+	var sellSize, buySize float64
+	takeProfit := 0.0
+
+	// 先处理卖出：持仓格的上一格被触及
+	for k, size := range state.Filled {
+		if size > 0 && bar.High >= s.levels[k+1] {
+			sold = append(sold, fmt.Sprintf("%.2f", s.levels[k+1]))
+			sellSize += size
+			state.Filled[k] = 0
+		}
+	}
+	// 再处理买入：价格自上一根收盘价下探到空仓格，且收盘未回到上一格（否则应在同一根K线内卖出）
+	prevClose := data[i-1].Close
+	for k := range state.Filled {
+		if state.Filled[k] == 0 && s.levels[k] <= prevClose && bar.Low <= s.levels[k] && bar.Close < s.levels[k+1] {
+			size := s.QuotePerGrid / s.levels[k]
+			bought = append(bought, fmt.Sprintf("%.2f", s.levels[k]))
+			buySize += size
+			state.Filled[k] = size
+			takeProfit = s.levels[k+1]
+		}
+	}
+
+	holding := state.holding()
+
+	net := buySize - sellSize
+	var parts []string
+	if len(bought) > 0 {
+		parts = append(parts, "网格买入 "+strings.Join(bought, ", "))
+	}
+	if len(sold) > 0 {
+		parts = append(parts, "网格卖出 "+strings.Join(sold, ", "))
+	}
+	reason := fmt.Sprintf("%s，当前持有 %d/%d 格", strings.Join(parts, "；"), holding, len(state.Filled))
+
+	// 网格成交是机械执行的，信号强度固定为 1；买入以最高一格买入的上一格作为止盈价
+	switch {
+	case net > 0:
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionBuy, Price: bar.Close, Reason: reason, Strength: 1, Size: net, TakeProfit: takeProfit}
+	case net < 0:
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionSell, Price: bar.Close, Reason: reason, Strength: 1, Size: -net}
+	case len(parts) > 0:
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: reason + "，买卖数量相抵"}
+	}
+	return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: fmt.Sprintf("价格未触及新的网格，当前持有 %d/%d 格", holding, len(state.Filled))}
+}
+
+// paused 判断第 i 根K线上网格是否暂停
+func (s *GridStrategy) paused(data []models.Bar, i int) (bool, string) {
+	price := data[i].Close
+	if price < s.Lower || price > s.Upper {
+		return true, fmt.Sprintf("收盘价 %.2f 离开网格区间 [%.2f, %.2f]，网格暂停", price, s.Lower, s.Upper)
+	}
+
+	if s.TrendAnalyzer != nil {
+		strength, err := s.TrendAnalyzer.Strength(data[:i+1])
+		if err == nil && math.Abs(strength) > s.MaxTrendStrength {
+			return true, fmt.Sprintf("趋势强度 %.2f%% 超过 %.2f%%，网格暂停", strength, s.MaxTrendStrength)
+		}
+	}
+
+	return false, ""
+}
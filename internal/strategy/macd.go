@@ -2,6 +2,8 @@ package strategy
 
 import (
 	"github.com/qqqq/eth-trading-system/internal/analysis"
+	"github.com/qqqq/eth-trading-system/internal/analysis/indicators"
+	"github.com/qqqq/eth-trading-system/internal/config"
 	"github.com/qqqq/eth-trading-system/internal/models"
 )
 
@@ -16,38 +18,59 @@ func NewMACDStrategy() *MACDStrategy {
 	}
 }
 
-func (s *MACDStrategy) Evaluate(data []models.Bar, analysisResult *models.AnalysisResult) *models.TradeSignal {
-	macdLine, ok := analysisResult.Indicators[analysis.IndicatorMACD].([]float64)
-	if !ok {
-		return &models.TradeSignal{StrategyName: s.Name(), Action: "HOLD", Reason: "MACD指标不可用"}
+func newMACDFromConfig(cfg config.StrategyConfig, _ *Registry) (Strategy, error) {
+	// MACD 策略没有可配置参数，但仍拒绝未知参数以便及时发现拼写错误
+	if err := decodeParams(cfg.Params, &struct{}{}); err != nil {
+		return nil, err
 	}
+	return NewMACDStrategy(), nil
+}
 
-	signalLine, ok := analysisResult.Indicators[analysis.IndicatorMACDSignal].([]float64)
-	if !ok {
-		return &models.TradeSignal{StrategyName: s.Name(), Action: "HOLD", Reason: "MACD信号线不可用"}
+// Evaluate 优先使用分析结果中的 MACD 序列，缺失时按 12/26/9 根据K线自行计算
+func (s *MACDStrategy) Evaluate(data []models.Bar, analysisResult *models.AnalysisResult) *models.TradeSignal {
+	macdLine, signalLine := s.lines(data, analysisResult)
+	if len(macdLine) < 2 || len(signalLine) < 2 || len(data) < 26+9 {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "不足够的数据"}
 	}
 
-	if len(macdLine) < 2 || len(signalLine) < 2 {
-		return &models.TradeSignal{StrategyName: s.Name(), Action: "HOLD", Reason: "不足够的数据"}
+	macd, signal := macdLine[len(macdLine)-1], signalLine[len(signalLine)-1]
+	price := data[len(data)-1].Close
+	values := map[string]float64{
+		analysis.IndicatorMACD:          macd,
+		analysis.IndicatorMACDSignal:    signal,
+		analysis.IndicatorMACDHistogram: macd - signal,
 	}
 
-	if macdLine[len(macdLine)-1] > signalLine[len(signalLine)-1] &&
-		macdLine[len(macdLine)-2] <= signalLine[len(signalLine)-2] {
+	if macd > signal && macdLine[len(macdLine)-2] <= signalLine[len(signalLine)-2] {
 		return &models.TradeSignal{
 			StrategyName: s.Name(),
-			Action:       "BUY",
-			Price:        data[len(data)-1].Close,
+			Action:       models.ActionBuy,
+			Price:        price,
 			Reason:       "MACD线上穿信号线",
+			Strength:     crossoverStrength(macd-signal, price),
+			Indicators:   values,
 		}
-	} else if macdLine[len(macdLine)-1] < signalLine[len(signalLine)-1] &&
-		macdLine[len(macdLine)-2] >= signalLine[len(signalLine)-2] {
+	} else if macd < signal && macdLine[len(macdLine)-2] >= signalLine[len(signalLine)-2] {
 		return &models.TradeSignal{
 			StrategyName: s.Name(),
-			Action:       "SELL",
-			Price:        data[len(data)-1].Close,
+			Action:       models.ActionSell,
+			Price:        price,
 			Reason:       "MACD线下穿信号线",
+			Strength:     crossoverStrength(macd-signal, price),
+			Indicators:   values,
 		}
 	}
 
-	return &models.TradeSignal{StrategyName: s.Name(), Action: "HOLD", Reason: "无显著变化"}
+	return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "无显著变化", Indicators: values}
+}
+
+func (s *MACDStrategy) lines(data []models.Bar, analysisResult *models.AnalysisResult) ([]float64, []float64) {
+	if analysisResult != nil {
+		macdLine, okMACD := analysisResult.Indicators[analysis.IndicatorMACD].([]float64)
+		signalLine, okSignal := analysisResult.Indicators[analysis.IndicatorMACDSignal].([]float64)
+		if okMACD && okSignal {
+			return macdLine, signalLine
+		}
+	}
+	return indicators.NewMACD(12, 26, 9).Series(data)
 }
@@ -0,0 +1,113 @@
+package strategy
+
+import (
+	"sort"
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// TimeframeData 某个时间框架上的K线及其分析结果
+type TimeframeData struct {
+	Bars     []models.Bar
+	Analysis *models.AnalysisResult
+}
+
+// MultiTimeframeContext 多时间框架评估上下文
+//
+// Timeframe 是触发评估的主时间框架，AsOf 是主时间框架最后一根K线的收盘时刻，
+// 该K线尚未收盘时为当前时刻。其余时间框架的数据只包含在 AsOf 之前已经收盘的K线，避免未来数据泄露。
+type MultiTimeframeContext struct {
+	Timeframe string
+	AsOf      time.Time
+	Data      map[string]*TimeframeData
+}
+
+// NewMultiTimeframeContext 创建仅包含主时间框架数据的上下文
+func NewMultiTimeframeContext(timeframe string, bars []models.Bar, analysisResult *models.AnalysisResult) *MultiTimeframeContext {
+	return &MultiTimeframeContext{
+		Timeframe: timeframe,
+		AsOf:      notAfterNow(BarCloseTime(bars, timeframe)),
+		Data: map[string]*TimeframeData{
+			timeframe: {Bars: bars, Analysis: analysisResult},
+		},
+	}
+}
+
+// Primary 返回主时间框架的数据
+func (c *MultiTimeframeContext) Primary() *TimeframeData {
+	return c.Data[c.Timeframe]
+}
+
+// Get 返回指定时间框架的数据，不存在时返回 nil
+func (c *MultiTimeframeContext) Get(timeframe string) *TimeframeData {
+	return c.Data[timeframe]
+}
+
+// MultiTimeframeStrategy 由需要多个时间框架数据的策略实现
+type MultiTimeframeStrategy interface {
+	Strategy
+	// RequiredTimeframes 返回除主时间框架外还需要的时间框架
+	RequiredTimeframes() []string
+	EvaluateMulti(ctx *MultiTimeframeContext) *models.TradeSignal
+}
+
+// EvaluateInContext 根据策略是否支持多时间框架选择评估方式，并补充信号的标的、时间框架和K线时间
+func EvaluateInContext(s Strategy, ctx *MultiTimeframeContext) *models.TradeSignal {
+	if m, ok := s.(MultiTimeframeStrategy); ok {
+		return annotateSignal(m.EvaluateMulti(ctx), s, ctx)
+	}
+	primary := ctx.Primary()
+	return annotateSignal(s.Evaluate(primary.Bars, primary.Analysis), s, ctx)
+}
+
+// RequiredTimeframes 汇总一组策略需要的额外时间框架
+func RequiredTimeframes(strategies []Strategy) []string {
+	seen := make(map[string]bool)
+	var timeframes []string
+	for _, s := range strategies {
+		m, ok := s.(MultiTimeframeStrategy)
+		if !ok {
+			continue
+		}
+		for _, tf := range m.RequiredTimeframes() {
+			if !seen[tf] {
+				seen[tf] = true
+				timeframes = append(timeframes, tf)
+			}
+		}
+	}
+	sort.Strings(timeframes)
+	return timeframes
+}
+
+// BarCloseTime 返回最后一根K线的收盘时刻（K线时间戳为开盘时间）
+func BarCloseTime(bars []models.Bar, timeframe string) time.Time {
+	if len(bars) == 0 {
+		return time.Time{}
+	}
+	d, _ := models.TimeframeDuration(timeframe)
+	return bars[len(bars)-1].Timestamp.Add(d)
+}
+
+// ClosedBarsAsOf 返回在 asOf 时刻之前已经收盘的K线，bars 需按时间升序排列。
+// asOf 晚于当前时刻时按当前时刻计算，尚未收盘的K线不会被返回
+func ClosedBarsAsOf(bars []models.Bar, timeframe string, asOf time.Time) []models.Bar {
+	d, ok := models.TimeframeDuration(timeframe)
+	if !ok {
+		return nil
+	}
+	asOf = notAfterNow(asOf)
+	n := sort.Search(len(bars), func(i int) bool {
+		return bars[i].Timestamp.Add(d).After(asOf)
+	})
+	return bars[:n]
+}
+
+// notAfterNow 将晚于当前时刻的时间截断为当前时刻
+func notAfterNow(t time.Time) time.Time {
+	if now := time.Now(); t.After(now) {
+		return now
+	}
+	return t
+}
@@ -0,0 +1,15 @@
+package strategy
+
+import "github.com/qqqq/eth-trading-system/internal/models"
+
+// PositionSource 提供策略自己实际持有的仓位，由交易服务按策略记录的持仓实现
+type PositionSource interface {
+	// StrategyPosition 返回策略当前持有的数量
+	StrategyPosition(strategyName string) float64
+}
+
+// FillListener 由需要按实际成交更新状态的策略实现，交易服务的每笔成交都会通知当前的策略
+type FillListener interface {
+	// OnFill 收到一笔成交，StrategyName 为空表示熔断平仓等非策略订单
+	OnFill(fill models.Fill)
+}
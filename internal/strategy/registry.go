@@ -0,0 +1,135 @@
+package strategy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/qqqq/eth-trading-system/internal/config"
+)
+
+// Factory 根据配置构建策略实例，registry 用于构建子策略
+type Factory func(cfg config.StrategyConfig, registry *Registry) (Strategy, error)
+
+// Registry 保存策略类型到构建函数的映射
+type Registry struct {
+	factories map[string]Factory
+	history   SignalHistory
+	positions PositionSource
+	states    StateStore
+}
+
+// NewRegistry 创建包含所有内置策略类型的注册表
+func NewRegistry() *Registry {
+	r := &Registry{factories: make(map[string]Factory)}
+	r.Register("simple_ma", newSimpleMAFromConfig)
+	r.Register("macd", newMACDFromConfig)
+	r.Register("composite", newCompositeFromConfig)
+	r.Register("trend_filter", newTrendFilterFromConfig)
+	r.Register("rsi", newRSIFromConfig)
+	r.Register("bollinger", newBollingerFromConfig)
+	r.Register("support_resistance", newSupportResistanceFromConfig)
+	r.Register("turtle", newTurtleFromConfig)
+	r.Register("grid", newGridFromConfig)
+	r.Register("dca", newDCAFromConfig)
+	r.Register("expression", newExpressionFromConfig)
+	return r
+}
+
+func (r *Registry) Register(strategyType string, factory Factory) {
+	r.factories[strategyType] = factory
+}
+
+// SetSignalHistory 设置策略信号历史的存储，供自适应组合策略使用
+func (r *Registry) SetSignalHistory(history SignalHistory) {
+	r.history = history
+}
+
+// SetPositions 设置策略实际持仓的来源，供价值平均定投使用；未设置时（如回测）由K线重放推导持仓
+func (r *Registry) SetPositions(positions PositionSource) {
+	r.positions = positions
+}
+
+// SetStateStore 设置策略运行状态的存储，供网格策略保存各格持仓；未设置时（如回测）由K线重放推导
+func (r *Registry) SetStateStore(states StateStore) {
+	r.states = states
+}
+
+// Types 返回已注册的策略类型
+func (r *Registry) Types() []string {
+	types := make([]string, 0, len(r.factories))
+	for t := range r.factories {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// BuildAll 构建所有启用的策略，错误信息中包含出错条目的位置和名称
+func (r *Registry) BuildAll(configs []config.StrategyConfig) ([]Strategy, error) {
+	return r.buildList(configs, "strategies")
+}
+
+func (r *Registry) buildList(configs []config.StrategyConfig, path string) ([]Strategy, error) {
+	var strategies []Strategy
+	for i, cfg := range configs {
+		if !cfg.IsEnabled() {
+			continue
+		}
+		s, err := r.build(cfg, fmt.Sprintf("%s[%d]", path, i))
+		if err != nil {
+			return nil, err
+		}
+		strategies = append(strategies, s)
+	}
+	return strategies, nil
+}
+
+func (r *Registry) build(cfg config.StrategyConfig, path string) (Strategy, error) {
+	factory, ok := r.factories[cfg.Type]
+	if !ok {
+		return nil, fmt.Errorf("%s (%s): 未知的策略类型 %q，可选值: %v", path, cfg.Name, cfg.Type, r.Types())
+	}
+
+	if cfg.Symbol != "" && cfg.Symbol != DefaultSymbol {
+		return nil, fmt.Errorf("%s (%s): 不支持的标的 %q，当前仅支持 %s", path, cfg.Name, cfg.Symbol, DefaultSymbol)
+	}
+
+	s, err := factory(cfg, r)
+	if err != nil {
+		return nil, fmt.Errorf("%s (%s): %v", path, cfg.Name, err)
+	}
+
+	if c, ok := s.(configurable); ok {
+		b := c.base()
+		b.name = cfg.Name
+		b.symbol = cfg.Symbol
+		b.timeframe = cfg.Timeframe
+	}
+	return s, nil
+}
+
+// BuildChildren 构建组合策略的子策略，供 Factory 使用
+func (r *Registry) BuildChildren(cfg config.StrategyConfig) ([]Strategy, error) {
+	return r.buildList(cfg.Children, "children")
+}
+
+// decodeParams 将配置中的 params 解码到参数结构体，未知参数视为错误
+func decodeParams(params map[string]interface{}, out interface{}) error {
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           out,
+		ErrorUnused:      true,
+		WeaklyTypedInput: true,
+	})
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(params); err != nil {
+		if merr, ok := err.(*mapstructure.Error); ok {
+			return fmt.Errorf("参数错误: %s", strings.Join(merr.Errors, "; "))
+		}
+		return fmt.Errorf("参数错误: %v", err)
+	}
+	return nil
+}
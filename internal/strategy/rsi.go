@@ -0,0 +1,194 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/qqqq/eth-trading-system/internal/analysis/indicators"
+	"github.com/qqqq/eth-trading-system/internal/analysis/trend"
+	"github.com/qqqq/eth-trading-system/internal/config"
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// RSIStrategy 实现了 RSI 均值回归策略：
+// RSI 从超卖区上穿超卖线时买入，从超买区下穿超买线时卖出。
+// 可选按趋势过滤逆势开仓、RSI 回到中轴时平仓，以及持仓 N 根K线后的时间止损。
+//
+// 策略本身不保存持仓状态，每次评估都会在历史K线上重放信号以推导当前持仓，
+// 因此实盘评估和回测得到的信号一致。
+type RSIStrategy struct {
+	BaseStrategy
+	Period     int
+	Oversold   float64
+	Overbought float64
+
+	// TrendAnalyzer 不为 nil 时，下降趋势中不开多，上升趋势中不开空
+	TrendAnalyzer *trend.TrendAnalyzer
+	// ExitAtMidline 为 true 时，多头在 RSI 上穿 Midline 时平仓，空头在下穿时平仓
+	ExitAtMidline bool
+	Midline       float64
+	// TimeStopBars 大于 0 时，持仓超过该数量的K线仍未退出则强制平仓
+	TimeStopBars int
+}
+
+func NewRSIStrategy(period int, oversold, overbought float64) *RSIStrategy {
+	return &RSIStrategy{
+		BaseStrategy: BaseStrategy{name: "RSI"},
+		Period:       period,
+		Oversold:     oversold,
+		Overbought:   overbought,
+		Midline:      50,
+	}
+}
+
+type rsiParams struct {
+	Period           int     `mapstructure:"period"`
+	Oversold         float64 `mapstructure:"oversold"`
+	Overbought       float64 `mapstructure:"overbought"`
+	TrendFilter      bool    `mapstructure:"trendFilter"`
+	TrendShortPeriod int     `mapstructure:"trendShortPeriod"`
+	TrendLongPeriod  int     `mapstructure:"trendLongPeriod"`
+	TrendMAType      string  `mapstructure:"trendMaType"`
+	ExitAtMidline    bool    `mapstructure:"exitAtMidline"`
+	Midline          float64 `mapstructure:"midline"`
+	TimeStopBars     int     `mapstructure:"timeStopBars"`
+}
+
+func newRSIFromConfig(cfg config.StrategyConfig, _ *Registry) (Strategy, error) {
+	params := rsiParams{
+		Period:           14,
+		Oversold:         30,
+		Overbought:       70,
+		TrendShortPeriod: 50,
+		TrendLongPeriod:  200,
+		TrendMAType:      "sma",
+		Midline:          50,
+	}
+	if err := decodeParams(cfg.Params, &params); err != nil {
+		return nil, err
+	}
+	if params.Period <= 0 {
+		return nil, fmt.Errorf("period 必须大于 0")
+	}
+	if params.Oversold <= 0 || params.Overbought >= 100 || params.Oversold >= params.Overbought {
+		return nil, fmt.Errorf("需要满足 0 < oversold(%v) < overbought(%v) < 100", params.Oversold, params.Overbought)
+	}
+	if params.ExitAtMidline && (params.Midline <= params.Oversold || params.Midline >= params.Overbought) {
+		return nil, fmt.Errorf("midline(%v) 必须位于 oversold 与 overbought 之间", params.Midline)
+	}
+	if params.TimeStopBars < 0 {
+		return nil, fmt.Errorf("timeStopBars 不能为负数")
+	}
+
+	s := NewRSIStrategy(params.Period, params.Oversold, params.Overbought)
+	s.ExitAtMidline = params.ExitAtMidline
+	s.Midline = params.Midline
+	s.TimeStopBars = params.TimeStopBars
+	if params.TrendFilter {
+		if params.TrendShortPeriod <= 0 || params.TrendShortPeriod >= params.TrendLongPeriod {
+			return nil, fmt.Errorf("需要满足 0 < trendShortPeriod(%d) < trendLongPeriod(%d)", params.TrendShortPeriod, params.TrendLongPeriod)
+		}
+		analyzer, err := trend.NewTrendAnalyzerWithType(indicators.MovingAverageType(params.TrendMAType), params.TrendShortPeriod, params.TrendLongPeriod)
+		if err != nil {
+			return nil, fmt.Errorf("trendMaType: %v", err)
+		}
+		s.TrendAnalyzer = analyzer
+	}
+	return s, nil
+}
+
+func (s *RSIStrategy) Evaluate(data []models.Bar, analysisResult *models.AnalysisResult) *models.TradeSignal {
+	if len(data) < s.Period+2 {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "不足够的数据"}
+	}
+
+	indicator := indicators.NewRelativeStrengthIndex(s.Period)
+	rsi := indicator.Series(data)
+
+	// position: 1 持多，-1 持空，0 空仓
+	position, entryIndex := 0, 0
+	var action models.SignalAction
+	var reason string
+	var strength float64
+	for i := s.Period + 1; i < len(data); i++ {
+		action, reason, strength = s.step(data, rsi, i, position, entryIndex)
+		switch {
+		case action == models.ActionBuy && position == -1, action == models.ActionSell && position == 1:
+			position = 0
+		case action == models.ActionBuy:
+			position, entryIndex = 1, i
+		case action == models.ActionSell:
+			position, entryIndex = -1, i
+		}
+	}
+
+	values := map[string]float64{indicator.Name(): rsi[len(rsi)-1]}
+	if action == models.ActionHold {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: reason, Indicators: values}
+	}
+	return &models.TradeSignal{
+		StrategyName: s.Name(),
+		Action:       action,
+		Price:        data[len(data)-1].Close,
+		Reason:       reason,
+		Strength:     strength,
+		Indicators:   values,
+	}
+}
+
+// step 计算第 i 根K线上的动作和信号强度，先判断持仓的退出条件，再判断开仓条件。
+// 平仓信号强度为 1，开仓信号强度随前一根K线 RSI 深入超买/超卖区的程度增加
+func (s *RSIStrategy) step(data []models.Bar, rsi []float64, i, position, entryIndex int) (models.SignalAction, string, float64) {
+	prev, cur := rsi[i-1], rsi[i]
+
+	switch position {
+	case 1:
+		if s.ExitAtMidline && prev < s.Midline && cur >= s.Midline {
+			return models.ActionSell, fmt.Sprintf("RSI(%d) 由 %.2f 回升至中轴 %.0f 上方（%.2f），多头平仓", s.Period, prev, s.Midline, cur), 1
+		}
+		if s.TimeStopBars > 0 && i-entryIndex >= s.TimeStopBars {
+			return models.ActionSell, fmt.Sprintf("多头持仓已达 %d 根K线仍未触发退出条件，时间止损", i-entryIndex), 1
+		}
+		if prev >= s.Overbought && cur < s.Overbought {
+			return models.ActionSell, fmt.Sprintf("RSI(%d) 由 %.2f 下穿超买线 %.0f（%.2f），多头平仓", s.Period, prev, s.Overbought, cur), 1
+		}
+	case -1:
+		if s.ExitAtMidline && prev > s.Midline && cur <= s.Midline {
+			return models.ActionBuy, fmt.Sprintf("RSI(%d) 由 %.2f 回落至中轴 %.0f 下方（%.2f），空头平仓", s.Period, prev, s.Midline, cur), 1
+		}
+		if s.TimeStopBars > 0 && i-entryIndex >= s.TimeStopBars {
+			return models.ActionBuy, fmt.Sprintf("空头持仓已达 %d 根K线仍未触发退出条件，时间止损", i-entryIndex), 1
+		}
+		if prev <= s.Oversold && cur > s.Oversold {
+			return models.ActionBuy, fmt.Sprintf("RSI(%d) 由 %.2f 上穿超卖线 %.0f（%.2f），空头平仓", s.Period, prev, s.Oversold, cur), 1
+		}
+	default:
+		if prev <= s.Oversold && cur > s.Oversold {
+			if trendState := s.trendAt(data, i); trendState == "Downtrend" {
+				return models.ActionHold, fmt.Sprintf("RSI(%d) 上穿超卖线 %.0f，但处于下降趋势，不开多", s.Period, s.Oversold), 0
+			}
+			strength := clampStrength(0.5 + (s.Oversold-prev)/s.Oversold)
+			return models.ActionBuy, fmt.Sprintf("RSI(%d) 由 %.2f 上穿超卖线 %.0f（%.2f），超卖反弹", s.Period, prev, s.Oversold, cur), strength
+		}
+		if prev >= s.Overbought && cur < s.Overbought {
+			if trendState := s.trendAt(data, i); trendState == "Uptrend" {
+				return models.ActionHold, fmt.Sprintf("RSI(%d) 下穿超买线 %.0f，但处于上升趋势，不开空", s.Period, s.Overbought), 0
+			}
+			strength := clampStrength(0.5 + (prev-s.Overbought)/(100-s.Overbought))
+			return models.ActionSell, fmt.Sprintf("RSI(%d) 由 %.2f 下穿超买线 %.0f（%.2f），超买回落", s.Period, prev, s.Overbought, cur), strength
+		}
+	}
+
+	return models.ActionHold, fmt.Sprintf("RSI(%d) 为 %.2f，无显著变化", s.Period, cur), 0
+}
+
+// trendAt 返回截至第 i 根K线的趋势，未启用趋势过滤或数据不足时返回空字符串
+func (s *RSIStrategy) trendAt(data []models.Bar, i int) string {
+	if s.TrendAnalyzer == nil {
+		return ""
+	}
+	trendState, err := s.TrendAnalyzer.AnalyzeTrend(data[:i+1])
+	if err != nil {
+		return ""
+	}
+	return trendState
+}
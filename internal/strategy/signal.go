@@ -0,0 +1,44 @@
+package strategy
+
+import (
+	"math"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// clampStrength 将信号强度限制在 [0, 1] 范围内
+func clampStrength(v float64) float64 {
+	if math.IsNaN(v) {
+		return 0
+	}
+	return math.Max(0, math.Min(1, v))
+}
+
+// crossoverStrength 计算两线交叉信号的强度：基础强度 0.5，
+// 两线距离每达到价格的 0.1% 增加 0.1
+func crossoverStrength(gap, price float64) float64 {
+	if price <= 0 {
+		return 0.5
+	}
+	return clampStrength(0.5 + 100*math.Abs(gap)/price)
+}
+
+// annotateSignal 补充信号的标的、时间框架和K线时间，策略已设置的字段不会被覆盖
+func annotateSignal(signal *models.TradeSignal, s Strategy, ctx *MultiTimeframeContext) *models.TradeSignal {
+	if signal == nil {
+		return nil
+	}
+	if signal.Symbol == "" {
+		signal.Symbol = s.Symbol()
+	}
+	if signal.Timeframe == "" {
+		signal.Timeframe = ctx.Timeframe
+	}
+	if primary := ctx.Primary(); signal.Timestamp.IsZero() && primary != nil && len(primary.Bars) > 0 {
+		signal.Timestamp = primary.Bars[len(primary.Bars)-1].Timestamp
+	}
+	if signal.Action == models.ActionHold {
+		signal.Strength = 0
+	}
+	return signal
+}
@@ -0,0 +1,19 @@
+package strategy
+
+import (
+	"time"
+
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// SignalHistory 保存策略发出的信号及其实际结果，由 storage.SignalRepository 实现
+type SignalHistory interface {
+	// RecordSignal 记录一条待验证的信号，同一策略、时间框架和K线的信号只记录一次
+	RecordSignal(record *models.SignalRecord) error
+	// PendingSignals 返回 since 之后尚未验证结果的信号
+	PendingSignals(strategyName, timeframe string, since time.Time) ([]models.SignalRecord, error)
+	// ResolveSignal 记录信号方向是否与之后的价格走势一致
+	ResolveSignal(id int64, correct bool) error
+	// Accuracy 返回最近 limit 条已验证信号中正确的数量和总数
+	Accuracy(strategyName, timeframe string, limit int) (correct, total int, err error)
+}
@@ -1,14 +1,21 @@
 package strategy
 
 import (
-	"github.com/qqqq/eth-trading-system/internal/analysis"
+	"fmt"
+	"math"
+
+	"github.com/qqqq/eth-trading-system/internal/analysis/indicators"
+	"github.com/qqqq/eth-trading-system/internal/config"
 	"github.com/qqqq/eth-trading-system/internal/models"
 )
 
+// SimpleMAStrategy 短期均线上穿长期均线时买入，下穿时卖出。均线类型默认为 SMA，
+// 可通过 MAType 换成 EMA、HMA 等以减小滞后
 type SimpleMAStrategy struct {
 	BaseStrategy
 	ShortPeriod int
 	LongPeriod  int
+	MAType      indicators.MovingAverageType
 }
 
 func NewSimpleMAStrategy(shortPeriod, longPeriod int) *SimpleMAStrategy {
@@ -16,41 +23,102 @@ func NewSimpleMAStrategy(shortPeriod, longPeriod int) *SimpleMAStrategy {
 		BaseStrategy: BaseStrategy{name: "SimpleMA"},
 		ShortPeriod:  shortPeriod,
 		LongPeriod:   longPeriod,
+		MAType:       indicators.MovingAverageSMA,
 	}
 }
 
-func (s *SimpleMAStrategy) Evaluate(data []models.Bar, analysisResult *models.AnalysisResult) *models.TradeSignal {
-	shortMA, ok := analysisResult.Indicators[analysis.IndicatorSMA(s.ShortPeriod)].([]float64)
-	if !ok {
-		return &models.TradeSignal{StrategyName: s.Name(), Action: "HOLD", Reason: "短期MA指标不可用"}
+type simpleMAParams struct {
+	ShortPeriod int    `mapstructure:"shortPeriod"`
+	LongPeriod  int    `mapstructure:"longPeriod"`
+	MAType      string `mapstructure:"maType"`
+}
+
+func newSimpleMAFromConfig(cfg config.StrategyConfig, _ *Registry) (Strategy, error) {
+	params := simpleMAParams{ShortPeriod: 10, LongPeriod: 30, MAType: string(indicators.MovingAverageSMA)}
+	if err := decodeParams(cfg.Params, &params); err != nil {
+		return nil, err
+	}
+	if params.ShortPeriod <= 0 || params.LongPeriod <= 0 {
+		return nil, fmt.Errorf("shortPeriod 和 longPeriod 必须大于 0")
+	}
+	if params.ShortPeriod >= params.LongPeriod {
+		return nil, fmt.Errorf("shortPeriod(%d) 必须小于 longPeriod(%d)", params.ShortPeriod, params.LongPeriod)
+	}
+	if _, err := indicators.NewMovingAverage(indicators.MovingAverageType(params.MAType), params.ShortPeriod); err != nil {
+		return nil, fmt.Errorf("maType: %v", err)
 	}
 
-	longMA, ok := analysisResult.Indicators[analysis.IndicatorSMA(s.LongPeriod)].([]float64)
-	if !ok {
-		return &models.TradeSignal{StrategyName: s.Name(), Action: "HOLD", Reason: "长期MA指标不可用"}
+	s := NewSimpleMAStrategy(params.ShortPeriod, params.LongPeriod)
+	s.MAType = indicators.MovingAverageType(params.MAType)
+	return s, nil
+}
+
+// Evaluate 优先使用分析结果中的均线序列，缺失时根据K线自行计算
+func (s *SimpleMAStrategy) Evaluate(data []models.Bar, analysisResult *models.AnalysisResult) *models.TradeSignal {
+	shortIndicator, longIndicator := s.newMovingAverages()
+	if shortIndicator == nil {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: fmt.Sprintf("不支持的均线类型 %q", s.MAType)}
+	}
+	shortMA, longMA := s.movingAverages(data, analysisResult, shortIndicator, longIndicator)
+	if len(shortMA) < 2 || len(longMA) < 2 || len(data) < s.LongPeriod+1 || math.IsNaN(longMA[len(longMA)-2]) {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "不足够的数据"}
 	}
 
-	if len(shortMA) < 2 || len(longMA) < 2 {
-		return &models.TradeSignal{StrategyName: s.Name(), Action: "HOLD", Reason: "不足够的数据"}
+	short, long := shortMA[len(shortMA)-1], longMA[len(longMA)-1]
+	price := data[len(data)-1].Close
+	values := map[string]float64{
+		shortIndicator.Name(): short,
+		longIndicator.Name():  long,
 	}
 
-	if shortMA[len(shortMA)-1] > longMA[len(longMA)-1] &&
-		shortMA[len(shortMA)-2] <= longMA[len(longMA)-2] {
+	if short > long && shortMA[len(shortMA)-2] <= longMA[len(longMA)-2] {
 		return &models.TradeSignal{
 			StrategyName: s.Name(),
-			Action:       "BUY",
-			Price:        data[len(data)-1].Close,
+			Action:       models.ActionBuy,
+			Price:        price,
 			Reason:       "短期均线上穿长期均线",
+			Strength:     crossoverStrength(short-long, price),
+			Indicators:   values,
 		}
-	} else if shortMA[len(shortMA)-1] < longMA[len(longMA)-1] &&
-		shortMA[len(shortMA)-2] >= longMA[len(longMA)-2] {
+	} else if short < long && shortMA[len(shortMA)-2] >= longMA[len(longMA)-2] {
 		return &models.TradeSignal{
 			StrategyName: s.Name(),
-			Action:       "SELL",
-			Price:        data[len(data)-1].Close,
+			Action:       models.ActionSell,
+			Price:        price,
 			Reason:       "短期均线下穿长期均线",
+			Strength:     crossoverStrength(short-long, price),
+			Indicators:   values,
 		}
 	}
 
-	return &models.TradeSignal{StrategyName: s.Name(), Action: "HOLD", Reason: "无显著变化"}
+	return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "无显著变化", Indicators: values}
+}
+
+// newMovingAverages 按 MAType 创建短期和长期均线，类型不受支持时返回 nil
+func (s *SimpleMAStrategy) newMovingAverages() (indicators.MovingAverage, indicators.MovingAverage) {
+	maType := s.MAType
+	if maType == "" {
+		maType = indicators.MovingAverageSMA
+	}
+	shortMA, err := indicators.NewMovingAverage(maType, s.ShortPeriod)
+	if err != nil {
+		return nil, nil
+	}
+	longMA, err := indicators.NewMovingAverage(maType, s.LongPeriod)
+	if err != nil {
+		return nil, nil
+	}
+	return shortMA, longMA
+}
+
+// movingAverages 分析结果中的均线序列以指标名（如 SMA10、EMA30）为键
+func (s *SimpleMAStrategy) movingAverages(data []models.Bar, analysisResult *models.AnalysisResult, short, long indicators.MovingAverage) ([]float64, []float64) {
+	if analysisResult != nil {
+		shortMA, okShort := analysisResult.Indicators[short.Name()].([]float64)
+		longMA, okLong := analysisResult.Indicators[long.Name()].([]float64)
+		if okShort && okLong {
+			return shortMA, longMA
+		}
+	}
+	return short.Series(data), long.Series(data)
 }
@@ -0,0 +1,9 @@
+package strategy
+
+// StateStore 保存需要跨重启保留的策略状态，由 storage.StrategyStateRepository 实现
+type StateStore interface {
+	// LoadState 读取 key 对应的状态到 state，不存在时返回 false
+	LoadState(key string, state interface{}) (bool, error)
+	// SaveState 保存 key 对应的状态，已存在时覆盖
+	SaveState(key string, state interface{}) error
+}
@@ -0,0 +1,271 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/qqqq/eth-trading-system/internal/analysis/indicators"
+	"github.com/qqqq/eth-trading-system/internal/analysis/support_resistance"
+	"github.com/qqqq/eth-trading-system/internal/config"
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+const (
+	LevelModeBreakout = "breakout"
+	LevelModeBounce   = "bounce"
+)
+
+// SupportResistanceStrategy 基于支撑/阻力位交易，支持两种模式：
+//
+//   - breakout: 连续 ConfirmBars 根K线收于最近阻力位上方时买入，收于最近支撑位下方时卖出；
+//     启用 Retest 时，突破后首次回踩到该价位附近并守住时才发出信号
+//   - bounce: 价格触及支撑位附近后收阳并收于支撑位上方时买入，触及阻力位附近后收阴时卖出
+//
+// "附近" 的范围由 ATR 的倍数决定，以适应不同波动率。
+// 支撑/阻力位始终按策略自己的 windowSize 计算，不使用分析结果中按固定窗口计算的 Supports/Resistances，
+// 以保证实盘与回测（没有分析结果）一致。
+type SupportResistanceStrategy struct {
+	BaseStrategy
+	Mode string
+	// ToleranceATR 判断是否处于价位附近的容差（ATR 倍数）
+	ToleranceATR float64
+	// BufferATR 突破需超过价位的幅度（ATR 倍数）
+	BufferATR    float64
+	ConfirmBars  int
+	Retest       bool
+	RetestWindow int
+
+	atr      *indicators.AverageTrueRange
+	analyzer *support_resistance.SupportResistanceAnalyzer
+}
+
+func NewSupportResistanceStrategy(mode string, atrPeriod, windowSize int) *SupportResistanceStrategy {
+	return &SupportResistanceStrategy{
+		BaseStrategy: BaseStrategy{name: "SupportResistance"},
+		Mode:         mode,
+		ToleranceATR: 0.5,
+		ConfirmBars:  1,
+		RetestWindow: 10,
+		atr:          indicators.NewAverageTrueRange(atrPeriod),
+		analyzer:     support_resistance.NewSupportResistanceAnalyzer(windowSize),
+	}
+}
+
+type supportResistanceParams struct {
+	Mode         string  `mapstructure:"mode"`
+	ATRPeriod    int     `mapstructure:"atrPeriod"`
+	WindowSize   int     `mapstructure:"windowSize"`
+	ToleranceATR float64 `mapstructure:"toleranceATR"`
+	BufferATR    float64 `mapstructure:"bufferATR"`
+	ConfirmBars  int     `mapstructure:"confirmBars"`
+	Retest       bool    `mapstructure:"retest"`
+	RetestWindow int     `mapstructure:"retestWindow"`
+}
+
+func newSupportResistanceFromConfig(cfg config.StrategyConfig, _ *Registry) (Strategy, error) {
+	params := supportResistanceParams{
+		Mode:         LevelModeBreakout,
+		ATRPeriod:    14,
+		WindowSize:   20,
+		ToleranceATR: 0.5,
+		ConfirmBars:  1,
+		RetestWindow: 10,
+	}
+	if err := decodeParams(cfg.Params, &params); err != nil {
+		return nil, err
+	}
+
+	if params.Mode != LevelModeBreakout && params.Mode != LevelModeBounce {
+		return nil, fmt.Errorf("mode 必须为 %s 或 %s，当前为 %q", LevelModeBreakout, LevelModeBounce, params.Mode)
+	}
+	if params.ATRPeriod <= 0 || params.WindowSize <= 0 {
+		return nil, fmt.Errorf("atrPeriod 和 windowSize 必须大于 0")
+	}
+	if params.ToleranceATR < 0 || params.BufferATR < 0 {
+		return nil, fmt.Errorf("toleranceATR 和 bufferATR 不能为负数")
+	}
+	if params.ConfirmBars < 1 {
+		return nil, fmt.Errorf("confirmBars 必须不小于 1")
+	}
+	if params.Retest && params.RetestWindow < 2 {
+		return nil, fmt.Errorf("启用 retest 时 retestWindow 必须不小于 2")
+	}
+
+	s := NewSupportResistanceStrategy(params.Mode, params.ATRPeriod, params.WindowSize)
+	s.ToleranceATR = params.ToleranceATR
+	s.BufferATR = params.BufferATR
+	s.ConfirmBars = params.ConfirmBars
+	s.Retest = params.Retest
+	s.RetestWindow = params.RetestWindow
+	return s, nil
+}
+
+func (s *SupportResistanceStrategy) Evaluate(data []models.Bar, analysisResult *models.AnalysisResult) *models.TradeSignal {
+	if len(data) < s.ConfirmBars+2 {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "不足够的数据"}
+	}
+
+	atrValue, err := s.atr.Calculate(data)
+	if err != nil {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "ATR指标不可用"}
+	}
+	atr := atrValue.(float64)
+
+	supports, resistances := s.analyzer.FindLevels(data)
+	if len(supports) == 0 && len(resistances) == 0 {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "未找到支撑/阻力位"}
+	}
+
+	if s.Mode == LevelModeBounce {
+		return s.evaluateBounce(data, supports, resistances, atr)
+	}
+	if s.Retest {
+		return s.evaluateRetest(data, supports, resistances, atr)
+	}
+	return s.evaluateBreakout(data, supports, resistances, atr)
+}
+
+// levelSignal 构造价位信号：止损设在价位另一侧 ToleranceATR 倍 ATR 处，
+// 信号强度随收盘价与价位的距离（ATR 倍数）增加
+func (s *SupportResistanceStrategy) levelSignal(action models.SignalAction, price, level, atr float64, reason string) *models.TradeSignal {
+	tolerance := s.ToleranceATR * atr
+	stop := level - tolerance
+	if action == models.ActionSell {
+		stop = level + tolerance
+	}
+	return &models.TradeSignal{
+		StrategyName: s.Name(),
+		Action:       action,
+		Price:        price,
+		Reason:       reason,
+		Strength:     clampStrength(0.5 + math.Abs(price-level)/atr/2),
+		StopLoss:     stop,
+		Indicators:   map[string]float64{s.atr.Name(): atr, "Level": level},
+	}
+}
+
+func (s *SupportResistanceStrategy) evaluateBreakout(data []models.Bar, supports, resistances []float64, atr float64) *models.TradeSignal {
+	last := len(data) - 1
+	before := data[last-s.ConfirmBars].Close
+	buffer := s.BufferATR * atr
+
+	if r, ok := nearestAbove(resistances, before); ok && closesAll(data[last-s.ConfirmBars+1:], func(c float64) bool { return c > r+buffer }) {
+		return s.levelSignal(models.ActionBuy, data[last].Close, r, atr,
+			fmt.Sprintf("连续 %d 根K线收于阻力位 %.2f 上方，确认突破", s.ConfirmBars, r))
+	}
+	if sup, ok := nearestBelow(supports, before); ok && closesAll(data[last-s.ConfirmBars+1:], func(c float64) bool { return c < sup-buffer }) {
+		return s.levelSignal(models.ActionSell, data[last].Close, sup, atr,
+			fmt.Sprintf("连续 %d 根K线收于支撑位 %.2f 下方，确认跌破", s.ConfirmBars, sup))
+	}
+
+	return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "未突破支撑/阻力位"}
+}
+
+// evaluateRetest 寻找 RetestWindow 内发生的突破，当前K线为突破后首次回踩价位且收盘守住时发出信号
+func (s *SupportResistanceStrategy) evaluateRetest(data []models.Bar, supports, resistances []float64, atr float64) *models.TradeSignal {
+	last := len(data) - 1
+	tolerance := s.ToleranceATR * atr
+	buffer := s.BufferATR * atr
+
+	for b := last - 1; b >= 1 && b > last-s.RetestWindow; b-- {
+		prev, cur := data[b-1].Close, data[b].Close
+
+		if r, ok := nearestAbove(resistances, prev); ok && cur > r+buffer {
+			held := closesAll(data[b:], func(c float64) bool { return c > r-tolerance })
+			touched := func(bar models.Bar) bool { return bar.Low <= r+tolerance }
+			if held && touched(data[last]) && data[last].Close > r && !anyBar(data[b+1:last], touched) {
+				return s.levelSignal(models.ActionBuy, data[last].Close, r, atr,
+					fmt.Sprintf("突破阻力位 %.2f 后回踩（最低 %.2f）并守住，确认突破", r, data[last].Low))
+			}
+			return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: fmt.Sprintf("已突破阻力位 %.2f，等待回踩确认", r)}
+		}
+
+		if sup, ok := nearestBelow(supports, prev); ok && cur < sup-buffer {
+			held := closesAll(data[b:], func(c float64) bool { return c < sup+tolerance })
+			touched := func(bar models.Bar) bool { return bar.High >= sup-tolerance }
+			if held && touched(data[last]) && data[last].Close < sup && !anyBar(data[b+1:last], touched) {
+				return s.levelSignal(models.ActionSell, data[last].Close, sup, atr,
+					fmt.Sprintf("跌破支撑位 %.2f 后反抽（最高 %.2f）未能收复，确认跌破", sup, data[last].High))
+			}
+			return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: fmt.Sprintf("已跌破支撑位 %.2f，等待反抽确认", sup)}
+		}
+	}
+
+	return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "近期未突破支撑/阻力位"}
+}
+
+// evaluateBounce 在反弹/回落信号中以对侧最近的价位作为止盈价
+func (s *SupportResistanceStrategy) evaluateBounce(data []models.Bar, supports, resistances []float64, atr float64) *models.TradeSignal {
+	bar := data[len(data)-1]
+	tolerance := s.ToleranceATR * atr
+
+	if sup, ok := nearestLevel(supports, bar.Low); ok && math.Abs(bar.Low-sup) <= tolerance && bar.Close > sup && bar.Close > bar.Open {
+		signal := s.levelSignal(models.ActionBuy, bar.Close, sup, atr,
+			fmt.Sprintf("价格触及支撑位 %.2f 附近（最低 %.2f，容差 %.2f）后收阳反弹", sup, bar.Low, tolerance))
+		if r, ok := nearestAbove(resistances, bar.Close); ok {
+			signal.TakeProfit = r
+		}
+		return signal
+	}
+	if r, ok := nearestLevel(resistances, bar.High); ok && math.Abs(bar.High-r) <= tolerance && bar.Close < r && bar.Close < bar.Open {
+		signal := s.levelSignal(models.ActionSell, bar.Close, r, atr,
+			fmt.Sprintf("价格触及阻力位 %.2f 附近（最高 %.2f，容差 %.2f）后收阴回落", r, bar.High, tolerance))
+		if sup, ok := nearestBelow(supports, bar.Close); ok {
+			signal.TakeProfit = sup
+		}
+		return signal
+	}
+
+	return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "价格未靠近支撑/阻力位"}
+}
+
+// nearestAbove 返回不低于 price 的最近价位
+func nearestAbove(levels []float64, price float64) (float64, bool) {
+	best, found := 0.0, false
+	for _, l := range levels {
+		if l >= price && (!found || l < best) {
+			best, found = l, true
+		}
+	}
+	return best, found
+}
+
+// nearestBelow 返回不高于 price 的最近价位
+func nearestBelow(levels []float64, price float64) (float64, bool) {
+	best, found := 0.0, false
+	for _, l := range levels {
+		if l <= price && (!found || l > best) {
+			best, found = l, true
+		}
+	}
+	return best, found
+}
+
+// nearestLevel 返回距离 price 最近的价位
+func nearestLevel(levels []float64, price float64) (float64, bool) {
+	best, found := 0.0, false
+	for _, l := range levels {
+		if !found || math.Abs(l-price) < math.Abs(best-price) {
+			best, found = l, true
+		}
+	}
+	return best, found
+}
+
+func closesAll(bars []models.Bar, cond func(float64) bool) bool {
+	for _, bar := range bars {
+		if !cond(bar.Close) {
+			return false
+		}
+	}
+	return len(bars) > 0
+}
+
+func anyBar(bars []models.Bar, cond func(models.Bar) bool) bool {
+	for _, bar := range bars {
+		if cond(bar) {
+			return true
+		}
+	}
+	return false
+}
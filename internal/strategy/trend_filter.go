@@ -0,0 +1,98 @@
+package strategy
+
+import (
+	"fmt"
+
+	"github.com/qqqq/eth-trading-system/internal/config"
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// TrendFilterStrategy 仅在更高时间框架的趋势与信号方向一致时放行子策略的信号，
+// 例如只在 1Day 上升趋势中执行 1Hour MACD 的买入信号
+type TrendFilterStrategy struct {
+	BaseStrategy
+	child          Strategy
+	trendTimeframe string
+	filterSells    bool
+}
+
+func NewTrendFilterStrategy(child Strategy, trendTimeframe string, filterSells bool) *TrendFilterStrategy {
+	return &TrendFilterStrategy{
+		BaseStrategy:   BaseStrategy{name: "TrendFilter"},
+		child:          child,
+		trendTimeframe: trendTimeframe,
+		filterSells:    filterSells,
+	}
+}
+
+type trendFilterParams struct {
+	TrendTimeframe string `mapstructure:"trendTimeframe"`
+	// FilterSells 为 true 时卖出信号同样要求趋势向下，否则卖出信号总是放行
+	FilterSells bool `mapstructure:"filterSells"`
+}
+
+func newTrendFilterFromConfig(cfg config.StrategyConfig, registry *Registry) (Strategy, error) {
+	params := trendFilterParams{TrendTimeframe: "1Day"}
+	if err := decodeParams(cfg.Params, &params); err != nil {
+		return nil, err
+	}
+	if !models.IsValidTimeframe(params.TrendTimeframe) {
+		return nil, fmt.Errorf("不支持的趋势时间框架 %q", params.TrendTimeframe)
+	}
+
+	children, err := registry.BuildChildren(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(children) != 1 {
+		return nil, fmt.Errorf("趋势过滤策略需要且仅需要一个启用的子策略，当前为 %d 个", len(children))
+	}
+
+	return NewTrendFilterStrategy(children[0], params.TrendTimeframe, params.FilterSells), nil
+}
+
+func (s *TrendFilterStrategy) RequiredTimeframes() []string {
+	timeframes := []string{s.trendTimeframe}
+	if m, ok := s.child.(MultiTimeframeStrategy); ok {
+		timeframes = append(timeframes, m.RequiredTimeframes()...)
+	}
+	return timeframes
+}
+
+func (s *TrendFilterStrategy) Evaluate(data []models.Bar, analysisResult *models.AnalysisResult) *models.TradeSignal {
+	return s.EvaluateMulti(NewMultiTimeframeContext(s.Timeframe(), data, analysisResult))
+}
+
+func (s *TrendFilterStrategy) EvaluateMulti(ctx *MultiTimeframeContext) *models.TradeSignal {
+	signal := EvaluateInContext(s.child, ctx)
+	if signal.Action == models.ActionHold {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: signal.Reason}
+	}
+
+	trendData := ctx.Get(s.trendTimeframe)
+	if trendData == nil || trendData.Analysis == nil {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: fmt.Sprintf("%s 趋势数据不可用", s.trendTimeframe)}
+	}
+	trend, _ := trendData.Analysis.Indicators["Trend"].(string)
+
+	if signal.Action == models.ActionBuy && trend != "Uptrend" {
+		return &models.TradeSignal{
+			StrategyName: s.Name(),
+			Action:       models.ActionHold,
+			Reason:       fmt.Sprintf("买入信号被过滤：%s 趋势为 %s（%s）", s.trendTimeframe, trend, signal.Reason),
+		}
+	}
+	if signal.Action == models.ActionSell && s.filterSells && trend != "Downtrend" {
+		return &models.TradeSignal{
+			StrategyName: s.Name(),
+			Action:       models.ActionHold,
+			Reason:       fmt.Sprintf("卖出信号被过滤：%s 趋势为 %s（%s）", s.trendTimeframe, trend, signal.Reason),
+		}
+	}
+
+	// 放行时保留子策略给出的强度、数量、止损止盈和指标值
+	passed := *signal
+	passed.StrategyName = s.Name()
+	passed.Reason = fmt.Sprintf("%s，%s 趋势为 %s", signal.Reason, s.trendTimeframe, trend)
+	return &passed
+}
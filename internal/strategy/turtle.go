@@ -0,0 +1,249 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/qqqq/eth-trading-system/internal/analysis/indicators"
+	"github.com/qqqq/eth-trading-system/internal/config"
+	"github.com/qqqq/eth-trading-system/internal/models"
+)
+
+// TurtleStrategy 实现了海龟交易系统：
+//
+//   - 入场：收盘价突破前 EntryPeriod 根K线的唐奇安通道（系统一 20，系统二 55）
+//   - 离场：收盘价跌破/升破前 ExitPeriod 根K线的通道（系统一 10，系统二 20），或触及止损
+//   - 头寸单位：账户权益 × RiskPercent% / N，N 为 ATR
+//   - 加仓：价格每向有利方向移动 PyramidN × N 加一个单位，最多 MaxUnits 个，并整体上移止损
+//   - 止损：最近一次入场价 ∓ StopN × N
+//
+// 与 RSIStrategy 一样，持仓状态通过在历史K线上重放推导，信号中包含建议数量和止损价。
+type TurtleStrategy struct {
+	BaseStrategy
+	EntryPeriod   int
+	ExitPeriod    int
+	ATRPeriod     int
+	AccountEquity float64
+	RiskPercent   float64
+	MaxUnits      int
+	PyramidN      float64
+	StopN         float64
+	AllowShort    bool
+}
+
+func NewTurtleStrategy(entryPeriod, exitPeriod int) *TurtleStrategy {
+	return &TurtleStrategy{
+		BaseStrategy:  BaseStrategy{name: "Turtle"},
+		EntryPeriod:   entryPeriod,
+		ExitPeriod:    exitPeriod,
+		ATRPeriod:     20,
+		AccountEquity: 10000,
+		RiskPercent:   1,
+		MaxUnits:      4,
+		PyramidN:      0.5,
+		StopN:         2,
+	}
+}
+
+type turtleParams struct {
+	// System 1 对应 20/10，2 对应 55/20，可被 entryPeriod/exitPeriod 覆盖
+	System        int     `mapstructure:"system"`
+	EntryPeriod   int     `mapstructure:"entryPeriod"`
+	ExitPeriod    int     `mapstructure:"exitPeriod"`
+	ATRPeriod     int     `mapstructure:"atrPeriod"`
+	AccountEquity float64 `mapstructure:"accountEquity"`
+	RiskPercent   float64 `mapstructure:"riskPercent"`
+	MaxUnits      int     `mapstructure:"maxUnits"`
+	PyramidN      float64 `mapstructure:"pyramidN"`
+	StopN         float64 `mapstructure:"stopN"`
+	AllowShort    bool    `mapstructure:"allowShort"`
+}
+
+func newTurtleFromConfig(cfg config.StrategyConfig, _ *Registry) (Strategy, error) {
+	defaults := NewTurtleStrategy(20, 10)
+	params := turtleParams{
+		System:        1,
+		ATRPeriod:     defaults.ATRPeriod,
+		AccountEquity: defaults.AccountEquity,
+		RiskPercent:   defaults.RiskPercent,
+		MaxUnits:      defaults.MaxUnits,
+		PyramidN:      defaults.PyramidN,
+		StopN:         defaults.StopN,
+	}
+	if err := decodeParams(cfg.Params, &params); err != nil {
+		return nil, err
+	}
+
+	switch params.System {
+	case 1:
+		params.EntryPeriod, params.ExitPeriod = orDefault(params.EntryPeriod, 20), orDefault(params.ExitPeriod, 10)
+	case 2:
+		params.EntryPeriod, params.ExitPeriod = orDefault(params.EntryPeriod, 55), orDefault(params.ExitPeriod, 20)
+	default:
+		return nil, fmt.Errorf("system 必须为 1 或 2，当前为 %d", params.System)
+	}
+	if params.EntryPeriod <= 0 || params.ExitPeriod <= 0 || params.ATRPeriod <= 0 {
+		return nil, fmt.Errorf("entryPeriod、exitPeriod 和 atrPeriod 必须大于 0")
+	}
+	if params.AccountEquity <= 0 || params.RiskPercent <= 0 || params.RiskPercent > 100 {
+		return nil, fmt.Errorf("accountEquity 必须大于 0，riskPercent 必须在 (0, 100] 范围内")
+	}
+	if params.MaxUnits < 1 || params.PyramidN <= 0 || params.StopN <= 0 {
+		return nil, fmt.Errorf("maxUnits 必须不小于 1，pyramidN 和 stopN 必须大于 0")
+	}
+
+	s := NewTurtleStrategy(params.EntryPeriod, params.ExitPeriod)
+	s.ATRPeriod = params.ATRPeriod
+	s.AccountEquity = params.AccountEquity
+	s.RiskPercent = params.RiskPercent
+	s.MaxUnits = params.MaxUnits
+	s.PyramidN = params.PyramidN
+	s.StopN = params.StopN
+	s.AllowShort = params.AllowShort
+	return s, nil
+}
+
+func orDefault(v, def int) int {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+// turtlePosition 重放过程中的持仓状态
+type turtlePosition struct {
+	direction int // 1 多头，-1 空头，0 空仓
+	units     int
+	unitSize  float64
+	entryN    float64
+	lastEntry float64
+	stop      float64
+}
+
+func (s *TurtleStrategy) Evaluate(data []models.Bar, analysisResult *models.AnalysisResult) *models.TradeSignal {
+	start := s.EntryPeriod
+	if s.ExitPeriod > start {
+		start = s.ExitPeriod
+	}
+	if s.ATRPeriod > start {
+		start = s.ATRPeriod
+	}
+	if len(data) < start+2 {
+		return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: "不足够的数据"}
+	}
+
+	entryUpper, entryLower := indicators.NewDonchianChannel(s.EntryPeriod).Series(data)
+	exitUpper, exitLower := indicators.NewDonchianChannel(s.ExitPeriod).Series(data)
+	atr := indicators.NewAverageTrueRange(s.ATRPeriod).Series(data)
+
+	var pos turtlePosition
+	var signal *models.TradeSignal
+	for i := start + 1; i < len(data); i++ {
+		signal = s.step(&pos, data[i].Close, atr[i], entryUpper[i-1], entryLower[i-1], exitUpper[i-1], exitLower[i-1])
+	}
+
+	last := len(data) - 1
+	signal.Indicators = map[string]float64{
+		"N":          atr[last],
+		"EntryUpper": entryUpper[last-1],
+		"EntryLower": entryLower[last-1],
+		"ExitUpper":  exitUpper[last-1],
+		"ExitLower":  exitLower[last-1],
+	}
+	return signal
+}
+
+// step 在一根K线上更新持仓并返回信号，通道值取自前一根K线，避免包含当前K线
+func (s *TurtleStrategy) step(pos *turtlePosition, price, n, entryUpper, entryLower, exitUpper, exitLower float64) *models.TradeSignal {
+	switch pos.direction {
+	case 1:
+		if price <= pos.stop {
+			return s.exit(pos, models.ActionSell, price, fmt.Sprintf("收盘价 %.2f 触及 %.1fN 止损 %.2f，多头 %d 个单位全部离场", price, s.StopN, pos.stop, pos.units))
+		}
+		if price < exitLower {
+			return s.exit(pos, models.ActionSell, price, fmt.Sprintf("收盘价 %.2f 跌破 %d 根K线最低价 %.2f，多头 %d 个单位全部离场", price, s.ExitPeriod, exitLower, pos.units))
+		}
+		if pos.units < s.MaxUnits && price >= pos.lastEntry+s.PyramidN*pos.entryN {
+			pos.units++
+			pos.lastEntry = price
+			pos.stop = price - s.StopN*pos.entryN
+			return s.entry(pos, models.ActionBuy, price, 0.5, fmt.Sprintf("价格较上次入场上涨 %.1fN，加仓至第 %d 个单位，止损上移至 %.2f", s.PyramidN, pos.units, pos.stop))
+		}
+	case -1:
+		if price >= pos.stop {
+			return s.exit(pos, models.ActionBuy, price, fmt.Sprintf("收盘价 %.2f 触及 %.1fN 止损 %.2f，空头 %d 个单位全部离场", price, s.StopN, pos.stop, pos.units))
+		}
+		if price > exitUpper {
+			return s.exit(pos, models.ActionBuy, price, fmt.Sprintf("收盘价 %.2f 升破 %d 根K线最高价 %.2f，空头 %d 个单位全部离场", price, s.ExitPeriod, exitUpper, pos.units))
+		}
+		if pos.units < s.MaxUnits && price <= pos.lastEntry-s.PyramidN*pos.entryN {
+			pos.units++
+			pos.lastEntry = price
+			pos.stop = price + s.StopN*pos.entryN
+			return s.entry(pos, models.ActionSell, price, 0.5, fmt.Sprintf("价格较上次入场下跌 %.1fN，加仓至第 %d 个单位，止损下移至 %.2f", s.PyramidN, pos.units, pos.stop))
+		}
+	default:
+		if n <= 0 || math.IsNaN(n) {
+			break
+		}
+		if price > entryUpper {
+			*pos = s.open(1, price, n)
+			return s.entry(pos, models.ActionBuy, price, clampStrength(0.5+(price-entryUpper)/n), fmt.Sprintf("收盘价 %.2f 突破 %d 根K线最高价 %.2f，开多 1 个单位（N=%.2f），止损 %.2f", price, s.EntryPeriod, entryUpper, n, pos.stop))
+		}
+		if s.AllowShort && price < entryLower {
+			*pos = s.open(-1, price, n)
+			return s.entry(pos, models.ActionSell, price, clampStrength(0.5+(entryLower-price)/n), fmt.Sprintf("收盘价 %.2f 跌破 %d 根K线最低价 %.2f，开空 1 个单位（N=%.2f），止损 %.2f", price, s.EntryPeriod, entryLower, n, pos.stop))
+		}
+	}
+
+	return &models.TradeSignal{StrategyName: s.Name(), Action: models.ActionHold, Reason: s.holdReason(pos)}
+}
+
+func (s *TurtleStrategy) open(direction int, price, n float64) turtlePosition {
+	return turtlePosition{
+		direction: direction,
+		units:     1,
+		unitSize:  s.AccountEquity * s.RiskPercent / 100 / n,
+		entryN:    n,
+		lastEntry: price,
+		stop:      price - float64(direction)*s.StopN*n,
+	}
+}
+
+// entry 返回开仓或加仓信号，突破开仓的强度随突破幅度（N 的倍数）增加，加仓强度为 0.5
+func (s *TurtleStrategy) entry(pos *turtlePosition, action models.SignalAction, price, strength float64, reason string) *models.TradeSignal {
+	return &models.TradeSignal{
+		StrategyName: s.Name(),
+		Action:       action,
+		Price:        price,
+		Reason:       reason,
+		Strength:     strength,
+		Size:         pos.unitSize,
+		StopLoss:     pos.stop,
+	}
+}
+
+// exit 返回全部离场信号，强度为 1
+func (s *TurtleStrategy) exit(pos *turtlePosition, action models.SignalAction, price float64, reason string) *models.TradeSignal {
+	size := pos.unitSize * float64(pos.units)
+	*pos = turtlePosition{}
+	return &models.TradeSignal{
+		StrategyName: s.Name(),
+		Action:       action,
+		Price:        price,
+		Reason:       reason,
+		Strength:     1,
+		Size:         size,
+	}
+}
+
+func (s *TurtleStrategy) holdReason(pos *turtlePosition) string {
+	switch pos.direction {
+	case 1:
+		return fmt.Sprintf("持有多头 %d 个单位，止损 %.2f", pos.units, pos.stop)
+	case -1:
+		return fmt.Sprintf("持有空头 %d 个单位，止损 %.2f", pos.units, pos.stop)
+	default:
+		return "未突破唐奇安通道"
+	}
+}
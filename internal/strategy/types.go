@@ -8,13 +8,42 @@ import (
 type Strategy interface {
 	Evaluate(data []models.Bar, analysisResult *models.AnalysisResult) *models.TradeSignal
 	Name() string
+	// Symbol 返回策略交易的标的
+	Symbol() string
+	// Timeframe 返回策略绑定的时间框架，空字符串表示适用于任意时间框架
+	Timeframe() string
 }
 
+// DefaultSymbol 未在配置中指定标的时使用的默认值
+const DefaultSymbol = "ETH/USD"
+
 // BaseStrategy 提供了基础的策略功能
 type BaseStrategy struct {
-	name string
+	name      string
+	symbol    string
+	timeframe string
 }
 
 func (bs *BaseStrategy) Name() string {
 	return bs.name
 }
+
+func (bs *BaseStrategy) Symbol() string {
+	if bs.symbol == "" {
+		return DefaultSymbol
+	}
+	return bs.symbol
+}
+
+func (bs *BaseStrategy) Timeframe() string {
+	return bs.timeframe
+}
+
+func (bs *BaseStrategy) base() *BaseStrategy {
+	return bs
+}
+
+// configurable 由所有嵌入 BaseStrategy 的策略实现，供 Registry 设置名称等元信息
+type configurable interface {
+	base() *BaseStrategy
+}
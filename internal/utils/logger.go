@@ -45,3 +45,13 @@ func InitLogger(logDir string, logLevel string) error {
 
 	return nil
 }
+
+// SetLogLevel 在运行时调整日志级别
+func SetLogLevel(logLevel string) error {
+	level, err := logrus.ParseLevel(logLevel)
+	if err != nil {
+		return fmt.Errorf("invalid log level: %s", logLevel)
+	}
+	Log.SetLevel(level)
+	return nil
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redactedPlaceholder = "[REDACTED]"
+
+// RedactHook 在日志写出前，将消息和字段中出现的敏感值替换为占位符
+type RedactHook struct {
+	mu       sync.RWMutex
+	replacer *strings.Replacer
+}
+
+func NewRedactHook(secrets ...string) *RedactHook {
+	h := &RedactHook{}
+	h.SetSecrets(secrets...)
+	return h
+}
+
+// SetSecrets 替换需要脱敏的敏感值，配置热更新后密钥变化时调用
+func (h *RedactHook) SetSecrets(secrets ...string) {
+	var pairs []string
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		pairs = append(pairs, secret, redactedPlaceholder)
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	h.mu.Lock()
+	h.replacer = replacer
+	h.mu.Unlock()
+}
+
+func (h *RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *RedactHook) Fire(entry *logrus.Entry) error {
+	h.mu.RLock()
+	replacer := h.replacer
+	h.mu.RUnlock()
+
+	entry.Message = replacer.Replace(entry.Message)
+
+	for key, value := range entry.Data {
+		var text string
+		switch v := value.(type) {
+		case string:
+			text = v
+		case error:
+			text = v.Error()
+		default:
+			text = fmt.Sprint(v)
+		}
+
+		if redacted := replacer.Replace(text); redacted != text {
+			entry.Data[key] = redacted
+		}
+	}
+	return nil
+}